@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/xxxeh/todo-list/internal/api"
+	"github.com/xxxeh/todo-list/internal/backup"
+	"github.com/xxxeh/todo-list/internal/db"
+	"github.com/xxxeh/todo-list/internal/i18n"
+)
+
+const exportUsage string = `Использование: todo-list export [-format json|csv] [-o файл] <логин>
+
+Выгружает резервную копию задач пользователя в файл или, если файл не указан, в стандартный вывод.`
+
+const importUsage string = `Использование: todo-list import [-format json|csv] [-mode skip|overwrite|duplicate] [-dry-run] <логин> <файл>
+
+Загружает задачи пользователя из резервной копии. Параметр mode задаёт действие с задачами,
+которые уже есть у пользователя: skip - пропустить (по умолчанию), overwrite - заменить, duplicate - создать заново.
+Задача копии уже есть у пользователя, если у его задачи тот же идентификатор id и то же время создания created_at,
+а если время создания в копии не указано - тот же идентификатор и то же название. Остальные задачи создаются
+с новыми идентификаторами.
+С параметром dry-run задачи только проверяются, но не сохраняются.`
+
+// backupUser подключается к базе данных и возвращает пользователя с логином login.
+func backupUser(dbFile, login string) (*db.User, error) {
+	err := db.Init(dbFile)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := db.GetUserByLogin(login)
+	if err != nil {
+		return nil, fmt.Errorf("Пользователь %s не найден: %w", login, err)
+	}
+	return user, nil
+}
+
+// runExport выполняет подкоманду export с аргументами args.
+//
+// Параметры:
+//
+//	dbFile - путь до файла БД.
+//	args - аргументы командной строки после слова export.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func runExport(dbFile string, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", backup.FormatJSON, "")
+	output := flags.String("o", "", "")
+	if flags.Parse(args) != nil || flags.NArg() != 1 {
+		return fmt.Errorf("%s", exportUsage)
+	}
+
+	user, err := backupUser(dbFile, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if len(*output) == 0 {
+		return backup.Export(os.Stdout, user.ID, *format)
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	err = backup.Export(file, user.ID, *format)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// runImport выполняет подкоманду import с аргументами args.
+// Для каждой задачи, которую не удалось загрузить, выводится причина, в конце - количество задач по результатам загрузки.
+//
+// Параметры:
+//
+//	dbFile - путь до файла БД.
+//	args - аргументы командной строки после слова import.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func runImport(dbFile string, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", backup.FormatJSON, "")
+	mode := flags.String("mode", backup.ModeSkip, "")
+	dryRun := flags.Bool("dry-run", false, "")
+	if flags.Parse(args) != nil || flags.NArg() != 2 {
+		return fmt.Errorf("%s", importUsage)
+	}
+
+	data, err := os.ReadFile(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	user, err := backupUser(dbFile, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	lang := user.Lang
+	if !i18n.Supported(lang) {
+		lang = i18n.Default
+	}
+
+	res, err := backup.Import(user.ID, data, *format, *mode, *dryRun, lang, api.CheckTask)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range res.Items {
+		if len(item.Error) > 0 {
+			fmt.Printf("%d\t%s\t%s\t%s: %s\n", item.Index, item.UID, item.Title, item.Field, item.Error)
+		}
+	}
+
+	if res.DryRun {
+		fmt.Println("Пробный режим, задачи не сохранены")
+	}
+	fmt.Printf("Создано: %d, заменено: %d, пропущено: %d, с ошибками: %d\n", res.Created, res.Updated, res.Skipped, res.Failed)
+	return nil
+}
@@ -9,6 +9,39 @@ import (
 	"github.com/xxxeh/todo-list/internal/db"
 )
 
+// checkTask проверяет задачу перед сохранением: заголовок, время, приоритет и теги, условия окончания повторений,
+// и рассчитывает дату, на которую должна быть назначена задача.
+//
+// Параметры:
+//
+//	task - указатель на структуру Task, содержащую данные задачи.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка валидации одного из полей задачи.
+func checkTask(task *db.Task) error {
+	if task.Title == "" {
+		return db.Validation("title", "task.title_required")
+	}
+
+	err := checkTime(task)
+	if err != nil {
+		return err
+	}
+
+	err = checkLabels(task)
+	if err != nil {
+		return err
+	}
+
+	err = checkRepeatEnd(task)
+	if err != nil {
+		return err
+	}
+
+	return checkDate(task)
+}
+
 // addTaskHandler обрабатывает запросы на добавление новой задачи.
 func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var task db.Task
@@ -16,7 +49,7 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 
 	_, err := buf.ReadFrom(r.Body)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
+		writeError(w, r, badRequest(err))
 		return
 	}
 	defer r.Body.Close()
@@ -24,24 +57,19 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = json.Unmarshal(buf.Bytes(), &task)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
-		return
-	}
-
-	if task.Title == "" {
-		writeJson(w, map[string]string{"error": "Не указан заголовок задачи"}, http.StatusBadRequest)
+		writeError(w, r, badRequest(err))
 		return
 	}
 
-	err = checkDate(&task)
+	err = checkTask(&task)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	id, err := db.AddTask(&task)
+	id, err := db.AddTask(userID(r), &task)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
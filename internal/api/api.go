@@ -5,7 +5,6 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -15,8 +14,9 @@ import (
 )
 
 const (
-	dateFormat string = "20060102"
-	tasksLimit int    = 30
+	dateFormat    string = "20060102"
+	tasksLimit    int    = 30
+	tasksMaxLimit int    = 100
 )
 
 // Init инициализирует и настраивает HTTP-сервер с маршрутами для работы с задачами.
@@ -25,18 +25,67 @@ const (
 //
 //	*chi.Mux - маршрутизатор chi с зарегистрированными обработчиками маршрутов.
 func Init() *chi.Mux {
+	//Методы WebDAV, которые используют клиенты CalDAV.
+	chi.RegisterMethod("PROPFIND")
+	chi.RegisterMethod("REPORT")
+
 	r := chi.NewRouter()
 	r.Use(logger)
 
 	r.Handle("/*", http.FileServer(http.Dir("web")))
 	r.Get("/api/nextdate", nextDateHandler)
+	r.Get("/api/rrule", rruleHandler)
 	r.Get("/api/tasks", auth(tasksHandler))
+	r.Get("/api/archive", auth(archiveHandler))
+	r.Get("/api/occurrences", auth(occurrencesHandler))
 	r.Get("/api/task", auth(getTaskHandler))
 	r.Put("/api/task", auth(updateTaskHandler))
 	r.Post("/api/task", auth(addTaskHandler))
 	r.Post("/api/task/done", auth(completeTaskHandler))
+	r.Post("/api/task/undo", auth(undoCompleteTaskHandler))
+	r.Get("/api/task/history", auth(taskHistoryHandler))
+	r.Get("/api/task/items", auth(itemsHandler))
+	r.Put("/api/task/items", auth(reorderItemsHandler))
+	r.Post("/api/task/item", auth(addItemHandler))
+	r.Put("/api/task/item", auth(updateItemHandler))
+	r.Post("/api/task/item/toggle", auth(toggleItemHandler))
+	r.Delete("/api/task/item", auth(deleteItemHandler))
+	r.Post("/api/task/dependency", auth(addDependencyHandler))
+	r.Delete("/api/task/dependency", auth(deleteDependencyHandler))
+	r.Post("/api/signup", signupHandler)
 	r.Post("/api/signin", authHandler)
+	r.Post("/api/refresh", refreshHandler)
+	r.Post("/api/signout", auth(signoutHandler))
+	r.Get("/api/user", auth(getUserHandler))
+	r.Put("/api/user", auth(updateUserHandler))
+	r.Post("/api/user/feed", auth(feedTokenHandler))
+	r.Delete("/api/user/feed", auth(revokeFeedTokenHandler))
+	r.Get("/api/calendar.ics", calendarHandler)
+	r.HandleFunc("/.well-known/caldav", caldavRedirectHandler)
+	r.HandleFunc("/caldav", basicAuth(caldavHandler))
+	r.HandleFunc("/caldav/*", basicAuth(caldavHandler))
+	r.Post("/api/import/ics", auth(importICalHandler))
+	r.Get("/api/export", auth(exportHandler))
+	r.Post("/api/import", auth(importHandler))
 	r.Delete("/api/task", auth(deleteTaskHandler))
+	r.Get("/api/trash", auth(trashHandler))
+	r.Delete("/api/trash", auth(emptyTrashHandler))
+	r.Post("/api/trash/restore", auth(restoreTaskHandler))
+	r.Delete("/api/trash/task", auth(purgeTaskHandler))
+	r.Get("/api/tags", auth(tagsHandler))
+	r.Post("/api/tag", auth(addTagHandler))
+	r.Put("/api/tag", auth(updateTagHandler))
+	r.Delete("/api/tag", auth(deleteTagHandler))
+	r.Get("/api/projects", auth(projectsHandler))
+	r.Post("/api/project", auth(addProjectHandler))
+	r.Put("/api/project", auth(updateProjectHandler))
+	r.Delete("/api/project", auth(deleteProjectHandler))
+	r.Get("/api/views", auth(viewsHandler))
+	r.Get("/api/views/{id}/tasks", auth(viewTasksHandler))
+	r.Get("/api/view", auth(getViewHandler))
+	r.Post("/api/view", auth(addViewHandler))
+	r.Put("/api/view", auth(updateViewHandler))
+	r.Delete("/api/view", auth(deleteViewHandler))
 
 	return r
 }
@@ -60,10 +109,12 @@ func writeJson(w http.ResponseWriter, data any, status int) {
 	w.WriteHeader(status)
 	w.Write(resp)
 
-	log.Printf("Sending response with status %d - %s", status, string(resp))
+	//Тело ответа не записывается в журнал: в нём бывают токены доступа и токен календаря.
+	log.Printf("Sending response with status %d - %d bytes", status, len(resp))
 }
 
 // checkDate рассчитывает и сохраняет корректную дату, в которую должна быть назначена задача.
+// Текущая дата определяется в часовом поясе задачи.
 //
 // Параметры:
 //
@@ -71,9 +122,14 @@ func writeJson(w http.ResponseWriter, data any, status int) {
 //
 // Возвращаемые значения:
 //
-//	error - ошибка, которая могла возникнуть в ходе работы.
+//	error - ошибка валидации поля date или repeat.
 func checkDate(task *db.Task) error {
-	now := time.Now()
+	loc, err := taskLocation(task)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().In(loc)
 	if len(task.Date) == 0 {
 		//Если дата изначально не указана в задаче, записываем текущую и возвращаем nil в качестве ошибки.
 		task.Date = now.Format(dateFormat)
@@ -82,7 +138,7 @@ func checkDate(task *db.Task) error {
 
 	t, err := time.Parse(dateFormat, task.Date)
 	if err != nil {
-		return fmt.Errorf("Неверный формат даты")
+		return db.Validation("date", "task.date_invalid")
 	}
 
 	if after(now, t) {
@@ -91,14 +147,51 @@ func checkDate(task *db.Task) error {
 			task.Date = now.Format(dateFormat)
 		} else {
 			//Если текущая дата больше чем дата в задаче и есть условие повторения, то вычисляем и новую дату.
-			task.Date, err = NextDate(now, task.Date, task.Repeat)
+			task.Date, err = nextTaskDate(now, task)
+			if err == nil && len(task.Date) == 0 {
+				//Если повторения по правилу закончились, то записываем текущую дату.
+				task.Date = now.Format(dateFormat)
+			}
 		}
 	}
 	return err
 }
 
+// checkRepeatEnd проверяет условия окончания повторений задачи: дату окончания и количество повторений.
+// Если количество повторений не указано, а правило RRULE содержит COUNT, то количество повторений берётся из правила,
+// чтобы оно уменьшалось при каждом выполнении задачи.
+//
+// Параметры:
+//
+//	task - указатель на структуру Task, содержащую данные задачи.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка валидации поля repeat_until или repeat_count.
+func checkRepeatEnd(task *db.Task) error {
+	if len(task.RepeatUntil) > 0 {
+		_, err := time.Parse(dateFormat, task.RepeatUntil)
+		if err != nil {
+			return db.Validation("repeat_until", "task.until_invalid")
+		}
+	}
+
+	if task.RepeatCount < 0 {
+		return db.Validation("repeat_count", "task.count_invalid")
+	}
+
+	if task.RepeatCount == 0 && isRRule(task.Repeat) {
+		rule, err := parseRRule(task.Repeat)
+		if err != nil {
+			return err
+		}
+		task.RepeatCount = rule.count
+	}
+	return nil
+}
+
 // after проверяет, является ли первая дата (date1) более поздней, чем вторая дата (date2).
-// Даты сравниваются с усечением до начала суток, что позволяет игнорировать время и сравнивать только даты.
+// Сравниваются только календарные даты, каждая - в своём часовом поясе, время не учитывается.
 //
 // Параметры:
 //
@@ -109,5 +202,7 @@ func checkDate(task *db.Task) error {
 //
 //	bool - true, если date1 позже date2; false в противном случае.
 func after(date1, date2 time.Time) bool {
-	return date1.Truncate(24 * time.Hour).After(date2.Truncate(24 * time.Hour))
+	y1, m1, d1 := date1.Date()
+	y2, m2, d2 := date2.Date()
+	return time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC).After(time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC))
 }
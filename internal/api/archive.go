@@ -0,0 +1,71 @@
+package api
+
+//Файл содержит хендлер списка задач в архиве: выполненных задач, у которых нет следующего повторения.
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// archiveDate разбирает дату в формате 20060102 из параметра запроса name в часовом поясе loc.
+// Если параметр не указан, возвращается нулевое время.
+func archiveDate(params url.Values, name string, loc *time.Location) (time.Time, error) {
+	s := params.Get(name)
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+
+	date, err := time.ParseInLocation(dateFormat, s, loc)
+	if err != nil {
+		return date, db.Validation(name, "tasks.param_invalid", name)
+	}
+	return date, nil
+}
+
+// archiveHandler обрабатывает запрос на получение задач в архиве.
+// Принимает те же параметры, что и список задач, и период выполнения задач:
+//
+//	from - дата начала периода в формате 20060102 включительно;
+//	to - дата окончания периода в формате 20060102 включительно.
+//
+// Даты периода определяются в часовом поясе сервера.
+// По умолчанию задачи сортируются по времени выполнения, начиная с выполненных последними.
+func archiveHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q, err := tasksQuery(params)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if len(params.Get("sort")) == 0 && len(params.Get("cursor")) == 0 && q.Sort == db.SortDate {
+		q.Sort, q.Desc = db.SortCompleted, params.Get("order") != "asc"
+	}
+	q.Archived = db.ArchivedOnly
+
+	loc, err := serverLocation()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	q.CompletedFrom, err = archiveDate(params, "from", loc)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	to, err := archiveDate(params, "to", loc)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !to.IsZero() {
+		q.CompletedTo = to.AddDate(0, 0, 1)
+	}
+
+	writeTasks(w, r, q)
+}
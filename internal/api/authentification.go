@@ -1,124 +1,299 @@
 package api
 
-//Файл содержит хендлер обрабатывающий запросы на аутентификацию пользователя
-//и функцию auth проверки аутентификации.
+//Файл содержит хендлеры обрабатывающие запросы на регистрацию и аутентификацию пользователя
+//и функции auth и basicAuth проверки аутентификации.
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/xxxeh/todo-list/internal/db"
+	"github.com/xxxeh/todo-list/internal/i18n"
+	"golang.org/x/crypto/bcrypt"
 )
 
-// authHandler обрабатывает запросы на аутентификацию, проверяет правильность введённого пароля,
-// и, в случае успешной проверки, генерирует JWT-токен с использованием секретного ключа и возвращает его в ответе.
-// Ключ для подписания токена и пароль должны хранится в переменных окружения TODO_SECRET_KEY и TODO_PASSWORD соответственно.
-func authHandler(w http.ResponseWriter, r *http.Request) {
+const (
+	defaultLogin   string = "admin"
+	minPasswordLen int    = 8
+	maxLoginLen    int    = 64
+)
+
+type ctxKey int
+
+const (
+	userIDKey ctxKey = iota
+	sessionIDKey
+	langKey
+)
+
+type credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// readCredentials читает из тела запроса логин и пароль пользователя.
+func readCredentials(r *http.Request) (credentials, error) {
+	var cred credentials
 	var buf bytes.Buffer
 
-	todo_pass := os.Getenv("TODO_PASSWORD")
-	if len(todo_pass) == 0 {
-		writeJson(w, map[string]string{"error": "Не определена переменная окружения TODO_PASSWORD"}, http.StatusInternalServerError)
+	_, err := buf.ReadFrom(r.Body)
+	if err != nil {
+		return cred, err
+	}
+	defer r.Body.Close()
+
+	err = json.Unmarshal(buf.Bytes(), &cred)
+	return cred, err
+}
+
+// signupHandler обрабатывает запросы на регистрацию нового пользователя.
+// Пароль сохраняется в базе данных в виде bcrypt-хэша с солью.
+// Логин admin зарезервирован: этот пользователь создаётся при первом входе с паролем TODO_PASSWORD
+// и получает задачи, созданные до появления пользователей.
+func signupHandler(w http.ResponseWriter, r *http.Request) {
+	cred, err := readCredentials(r)
+	if err != nil {
+		writeError(w, r, badRequest(err))
 		return
 	}
 
-	secret := os.Getenv("TODO_SECRET_KEY")
-	if len(secret) == 0 {
-		writeJson(w, map[string]string{"error": "Не определена переменная окружения TODO_SECRET_KEY"}, http.StatusInternalServerError)
+	if len(cred.Login) == 0 || len(cred.Login) > maxLoginLen {
+		writeError(w, r, db.Validation("login", "user.login_invalid"))
 		return
 	}
 
-	_, err := buf.ReadFrom(r.Body)
+	if cred.Login == defaultLogin {
+		writeError(w, r, db.Validation("login", "user.login_reserved", defaultLogin))
+		return
+	}
+
+	if len(cred.Password) < minPasswordLen {
+		writeError(w, r, db.Validation("password", "user.password_short", minPasswordLen))
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(cred.Password), bcrypt.DefaultCost)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
-	defer r.Body.Close()
-	log.Printf("Requset body - %s", buf.String())
 
-	var data map[string]string
-	err = json.Unmarshal(buf.Bytes(), &data)
+	id, err := db.AddUser(cred.Login, string(hash))
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	pass := data["password"]
-	passHash := sha256.Sum256([]byte(pass))
-	if todo_pass != hex.EncodeToString(passHash[:]) {
-		writeJson(w, map[string]string{"error": "Неверный пароль"}, http.StatusBadRequest)
+	writeJson(w, map[string]int64{"id": id}, http.StatusCreated)
+}
+
+// authHandler обрабатывает запросы на аутентификацию, проверяет логин и пароль пользователя,
+// и, в случае успешной проверки, открывает новую сессию и возвращает в ответе короткоживущий access-токен и refresh-токен.
+// Ключ для подписания токена должен хранится в переменной окружения TODO_SECRET_KEY.
+// Если логин не указан, то используется пользователь admin.
+func authHandler(w http.ResponseWriter, r *http.Request) {
+	cred, err := readCredentials(r)
+	if err != nil {
+		writeError(w, r, badRequest(err))
 		return
 	}
+	log.Printf("Sign in attempt for user %q", cred.Login)
 
-	claims := jwt.MapClaims{
-		"hash": todo_pass,
+	if len(cred.Login) == 0 {
+		cred.Login = defaultLogin
 	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := jwtToken.SignedString([]byte(secret))
+	user, err := db.GetUserByLogin(cred.Login)
+	if errors.Is(err, db.ErrNotFound) && cred.Login == defaultLogin {
+		user, err = legacyUser(cred.Password)
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		err = errWrongCredentials
+	}
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	writeJson(w, map[string]string{"token": signedToken}, http.StatusOK)
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(cred.Password))
+	if err != nil {
+		writeError(w, r, errWrongCredentials)
+		return
+	}
+
+	//Заодно удаляем сессии с истёкшим сроком действия.
+	err = db.DeleteExpiredRefreshTokens()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	tokens, err := newSession(user.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, tokens, http.StatusOK)
 }
 
-// auth проверяет перед началом обработки запроса валидность JWT-токена в куках.
-// Если пользователь авторизован, то управление передается следующему обработчику.
+// legacyUser создаёт пользователя admin при первом входе с паролем из переменной окружения TODO_PASSWORD
+// (sha256-хэш пароля, использовавшийся до появления учётных записей) и передаёт ему все задачи без владельца.
+//
+// Параметры:
+//
+//	pass - пароль, указанный при входе.
+//
+// Возвращаемые значения:
+//
+//	*db.User - созданный пользователь.
+//	error - errWrongCredentials, если пароль не совпадает с TODO_PASSWORD, или ошибка работы с базой данных.
+func legacyUser(pass string) (*db.User, error) {
+	todo_pass := os.Getenv("TODO_PASSWORD")
+	passHash := sha256.Sum256([]byte(pass))
+	if len(todo_pass) == 0 || todo_pass != hex.EncodeToString(passHash[:]) {
+		return nil, errWrongCredentials
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := db.AddUser(defaultLogin, string(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.ClaimTasks(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetUser(id)
+}
+
+// auth проверяет перед началом обработки запроса валидность JWT-токена в куках:
+// подпись, срок действия и то, что сессия, в рамках которой выпущен токен, не отозвана.
+// Если пользователь авторизован, то его идентификатор, идентификатор сессии и выбранный язык сообщений
+// сохраняются в контексте запроса и управление передается следующему обработчику.
 func auth(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		todo_pass := os.Getenv("TODO_PASSWORD")
-		if len(todo_pass) == 0 {
-			http.Error(w, "Не определена переменная окружения TODO_SECRET_KEY", http.StatusInternalServerError)
+		secret, err := secretKey()
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		var signedToken string
 		cookie, err := r.Cookie("token")
 		if err != nil {
-			writeJson(w, map[string]string{"error": "Authentification required"}, http.StatusUnauthorized)
+			writeError(w, r, errAuthRequired)
 			return
 		}
 
-		signedToken = cookie.Value
+		claims := &tokenClaims{}
+		_, err = jwt.ParseWithClaims(cookie.Value, claims, func(t *jwt.Token) (interface{}, error) {
+			return secret, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
 
-		jwtToken, err := jwt.Parse(signedToken, func(t *jwt.Token) (interface{}, error) {
-			secret := os.Getenv("TODO_SECRET_KEY")
-			if len(secret) == 0 {
-				return nil, fmt.Errorf("Не определена переменная окружения TODO_SECRET_KEY")
+		if err != nil {
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				writeError(w, r, errTokenExpired)
+			} else {
+				writeError(w, r, errAuthRequired)
 			}
-			return []byte(secret), nil
-		})
+			return
+		}
 
+		session, err := db.GetRefreshToken(claims.SessionID)
+		if errors.Is(err, db.ErrNotFound) {
+			err = errAuthRequired
+		}
 		if err != nil {
-			writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+			writeError(w, r, err)
 			return
 		}
 
-		if !jwtToken.Valid {
-			writeJson(w, map[string]string{"error": "Authentification required"}, http.StatusUnauthorized)
+		if session.Revoked || session.UserID != claims.UserID {
+			writeError(w, r, errAuthRequired)
+			return
+		}
+
+		user, err := db.GetUser(claims.UserID)
+		if errors.Is(err, db.ErrNotFound) {
+			err = errAuthRequired
+		}
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		claims, ok := jwtToken.Claims.(jwt.MapClaims)
+		ctx := context.WithValue(r.Context(), userIDKey, user.ID)
+		ctx = context.WithValue(ctx, sessionIDKey, session.ID)
+		ctx = context.WithValue(ctx, langKey, user.Lang)
+		next(w, r.WithContext(ctx))
+	})
+}
+
+// basicAuth проверяет перед началом обработки запроса логин и пароль пользователя из заголовка Authorization
+// (схема Basic). Используется для клиентов CalDAV, которые не поддерживают вход с получением токена.
+// Если пользователь не авторизован, в ответе 401 передаётся заголовок WWW-Authenticate, по которому клиент запрашивает пароль.
+// Если пользователь авторизован, то его идентификатор и выбранный язык сообщений сохраняются в контексте запроса
+// и управление передается следующему обработчику.
+func basicAuth(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		login, pass, ok := r.BasicAuth()
 		if !ok {
-			writeJson(w, map[string]string{"error": "Authentification required"}, http.StatusUnauthorized)
+			w.Header().Set("WWW-Authenticate", `Basic realm="todo-list", charset="UTF-8"`)
+			writeError(w, r, errAuthRequired)
 			return
 		}
 
-		pass := claims["hash"]
-		if pass != todo_pass {
-			writeJson(w, map[string]string{"error": "Authentification required"}, http.StatusUnauthorized)
+		user, err := db.GetUserByLogin(login)
+		if errors.Is(err, db.ErrNotFound) {
+			err = errWrongCredentials
+		}
+		if err == nil && bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(pass)) != nil {
+			err = errWrongCredentials
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="todo-list", charset="UTF-8"`)
+			writeError(w, r, err)
 			return
 		}
 
-		next(w, r)
+		ctx := context.WithValue(r.Context(), userIDKey, user.ID)
+		ctx = context.WithValue(ctx, langKey, user.Lang)
+		next(w, r.WithContext(ctx))
 	})
 }
+
+// userID возвращает идентификатор авторизованного пользователя, сохранённый в контексте запроса функцией auth.
+func userID(r *http.Request) int64 {
+	id, _ := r.Context().Value(userIDKey).(int64)
+	return id
+}
+
+// sessionID возвращает идентификатор текущей сессии, сохранённый в контексте запроса функцией auth.
+func sessionID(r *http.Request) string {
+	id, _ := r.Context().Value(sessionIDKey).(string)
+	return id
+}
+
+// requestLang возвращает язык сообщений для запроса: язык, выбранный пользователем в профиле,
+// а если он не выбран или пользователь не авторизован - язык из заголовка Accept-Language.
+func requestLang(r *http.Request) string {
+	lang, _ := r.Context().Value(langKey).(string)
+	if i18n.Supported(lang) {
+		return lang
+	}
+	return i18n.Match(r.Header.Get("Accept-Language"))
+}
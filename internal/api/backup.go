@@ -0,0 +1,105 @@
+package api
+
+//Файл содержит хендлеры резервного копирования задач пользователя: выгрузки всех задач и загрузки задач из резервной копии.
+//Форматы копии и загрузка задач находятся в пакете backup.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/xxxeh/todo-list/internal/backup"
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// CheckTask проверяет задачу, которая сохраняется не через хендлеры API, например задачу резервной копии.
+// Задача проверяется так же, как при добавлении: дата рассчитывается функцией checkDate,
+// а правило повторения проверяется функцией NextDate, даже если дата задачи ещё не наступила.
+//
+// Параметры:
+//
+//	task - указатель на структуру Task, содержащую данные задачи.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка валидации одного из полей задачи.
+func CheckTask(task *db.Task) error {
+	err := checkTask(task)
+	if err != nil || len(task.Repeat) == 0 {
+		return err
+	}
+
+	_, err = NextDate(time.Now(), task.Date, task.Repeat)
+	return err
+}
+
+// exportHandler обрабатывает запрос на выгрузку резервной копии задач пользователя.
+// Параметр format задаёт формат копии: json (по умолчанию) или csv.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.FormValue("format")
+	if len(format) == 0 {
+		format = backup.FormatJSON
+	}
+
+	var buf bytes.Buffer
+	err := backup.Export(&buf, userID(r), format)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	contentType := "application/json; charset=utf-8"
+	if format == backup.FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks-%s.%s"`, time.Now().Format(dateFormat), format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// importHandler обрабатывает запрос на загрузку резервной копии задач пользователя.
+// Копия передаётся в поле file формы multipart/form-data или телом запроса. Параметры запроса:
+//
+//	format - формат копии: json (по умолчанию) или csv;
+//	mode - действие с задачами, которые уже есть у пользователя: skip (по умолчанию), overwrite или duplicate;
+//	dry_run - при значении true задачи только проверяются, но не сохраняются.
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := dryRunParam(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	params := r.URL.Query()
+	format, mode := params.Get("format"), params.Get("mode")
+	if len(format) == 0 {
+		format = backup.FormatJSON
+	}
+	if len(mode) == 0 {
+		mode = backup.ModeSkip
+	}
+
+	data, err := readImportFile(w, r)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+
+	lang := requestLang(r)
+	res, err := backup.Import(userID(r), data, format, mode, dryRun, lang, CheckTask)
+	var malformed *backup.MalformedError
+	if errors.As(err, &malformed) {
+		err = badRequest(malformed.Err)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Language", lang)
+	writeJson(w, res, http.StatusOK)
+}
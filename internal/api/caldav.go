@@ -0,0 +1,627 @@
+package api
+
+//Файл содержит доступ к задачам пользователя по протоколу CalDAV (RFC 4791): коллекцию задач VTODO,
+//которую клиенты календарей и задач синхронизируют в обе стороны. Клиент авторизуется логином и паролем (схема Basic).
+//Каждая задача - отдельный ресурс коллекции, ETag ресурса строится по номеру версии задачи.
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xxxeh/todo-list/internal/db"
+	"github.com/xxxeh/todo-list/internal/i18n"
+)
+
+// Пути ресурсов CalDAV.
+const (
+	caldavRoot  string = "/caldav/"       //Принципал пользователя, он же домашний каталог календарей.
+	caldavTasks string = "/caldav/tasks/" //Коллекция задач VTODO.
+)
+
+// Пространства имён XML WebDAV и CalDAV.
+const (
+	nsDAV    string = "DAV:"
+	nsCalDAV string = "urn:ietf:params:xml:ns:caldav"
+	nsCS     string = "http://calendarserver.org/ns/"
+)
+
+// davPrefixes сопоставляет пространствам имён XML префиксы, с которыми свойства записываются в ответе.
+var davPrefixes = map[string]string{nsDAV: "d", nsCalDAV: "c", nsCS: "cs"}
+
+// davAllProps - свойства, которые возвращаются на запрос PROPFIND без списка свойств.
+var davAllProps = []xml.Name{
+	{Space: nsDAV, Local: "resourcetype"},
+	{Space: nsDAV, Local: "displayname"},
+	{Space: nsDAV, Local: "getetag"},
+	{Space: nsDAV, Local: "getcontenttype"},
+	{Space: nsCS, Local: "getctag"},
+}
+
+// errResourceNotFound - ошибка для запроса к ресурсу CalDAV, которого нет.
+var errResourceNotFound = db.NotFound("caldav.not_found")
+
+// Виды ресурсов CalDAV.
+const (
+	davPrincipal  int = iota //Принципал пользователя.
+	davCollection            //Коллекция задач.
+	davTask                  //Задача.
+)
+
+// davResource - ресурс CalDAV, свойства которого возвращаются в ответах на запросы PROPFIND и REPORT.
+type davResource struct {
+	kind int
+	href string
+	name string   //Название принципала или коллекции.
+	ctag string   //Признак состояния коллекции, меняется при любом изменении задач.
+	task *db.Task //Задача ресурса вида davTask.
+	data string   //Задача в формате iCalendar.
+}
+
+// davRequest - разобранное тело запроса PROPFIND или REPORT.
+type davRequest struct {
+	root  xml.Name   //Корневой элемент, определяет вид запроса.
+	props []xml.Name //Запрошенные свойства, пустой список - свойства davAllProps.
+	hrefs []string   //Адреса ресурсов в запросе calendar-multiget.
+}
+
+// parseDAVRequest разбирает тело запроса PROPFIND или REPORT. Пустое тело соответствует запросу всех свойств.
+// Из запроса выбираются только корневой элемент, свойства, перечисленные в элементе prop, и адреса ресурсов,
+// условия фильтра calendar-query не учитываются.
+func parseDAVRequest(r io.Reader) (davRequest, error) {
+	var req davRequest
+	depth, propDepth := 0, 0
+
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return req, nil
+		}
+		if err != nil {
+			return req, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1:
+				req.root = t.Name
+			case propDepth > 0 && depth == propDepth+1:
+				req.props = append(req.props, t.Name)
+			case propDepth == 0 && t.Name == xml.Name{Space: nsDAV, Local: "prop"}:
+				propDepth = depth
+			case t.Name == xml.Name{Space: nsDAV, Local: "href"}:
+				var href string
+				err = dec.DecodeElement(&href, &t)
+				if err != nil {
+					return req, err
+				}
+				req.hrefs = append(req.hrefs, strings.TrimSpace(href))
+				depth--
+			}
+		case xml.EndElement:
+			if depth == propDepth {
+				propDepth = 0
+			}
+			depth--
+		}
+	}
+}
+
+// xmlText экранирует текст для записи в XML.
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// davElement возвращает элемент XML свойства name с содержимым value.
+// Свойства из пространств имён, неизвестных серверу, записываются с объявлением своего пространства имён.
+func davElement(name xml.Name, value string) string {
+	tag, open := name.Local, name.Local
+	if prefix, ok := davPrefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+		open = tag
+	} else if len(name.Space) > 0 {
+		tag = "x:" + name.Local
+		open = tag + ` xmlns:x="` + xmlText(name.Space) + `"`
+	}
+
+	if len(value) == 0 {
+		return "<" + open + "/>"
+	}
+	return "<" + open + ">" + value + "</" + tag + ">"
+}
+
+// prop возвращает содержимое свойства name ресурса в формате XML и признак того, что у ресурса есть это свойство.
+func (res *davResource) prop(name xml.Name) (string, bool) {
+	principal := "<d:href>" + caldavRoot + "</d:href>"
+
+	switch "{" + name.Space + "}" + name.Local {
+	case "{DAV:}resourcetype":
+		switch res.kind {
+		case davPrincipal:
+			return "<d:collection/><d:principal/>", true
+		case davCollection:
+			return "<d:collection/><c:calendar/>", true
+		}
+		return "", true
+	case "{DAV:}displayname":
+		return xmlText(res.name), res.kind != davTask
+	case "{DAV:}current-user-principal":
+		return principal, true
+	case "{DAV:}principal-URL", "{urn:ietf:params:xml:ns:caldav}calendar-home-set":
+		return principal, res.kind == davPrincipal
+	case "{DAV:}current-user-privilege-set":
+		return "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>", true
+	case "{DAV:}supported-report-set":
+		return "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>", res.kind == davCollection
+	case "{urn:ietf:params:xml:ns:caldav}supported-calendar-component-set":
+		return `<c:comp name="VTODO"/>`, res.kind == davCollection
+	case "{http://calendarserver.org/ns/}getctag":
+		return xmlText(res.ctag), res.kind == davCollection
+	case "{DAV:}getetag":
+		if res.kind == davTask {
+			return xmlText(taskETag(res.task)), true
+		}
+	case "{DAV:}getcontenttype":
+		return "text/calendar; charset=utf-8; component=VTODO", res.kind == davTask
+	case "{urn:ietf:params:xml:ns:caldav}calendar-data":
+		return xmlText(res.data), res.kind == davTask
+	}
+	return "", false
+}
+
+// multistatus формирует ответ 207 Multi-Status на запросы PROPFIND и REPORT.
+type multistatus struct {
+	b strings.Builder
+}
+
+// add записывает ответ со свойствами props ресурса res. Свойства, которых у ресурса нет, возвращаются со статусом 404.
+func (m *multistatus) add(res *davResource, props []xml.Name) {
+	if len(props) == 0 {
+		props = davAllProps
+	}
+
+	var found, missing strings.Builder
+	for _, name := range props {
+		if value, ok := res.prop(name); ok {
+			found.WriteString(davElement(name, value))
+		} else {
+			missing.WriteString(davElement(name, ""))
+		}
+	}
+
+	m.b.WriteString("<d:response><d:href>" + xmlText(res.href) + "</d:href>")
+	if found.Len() > 0 {
+		m.b.WriteString("<d:propstat><d:prop>" + found.String() + "</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>")
+	}
+	if missing.Len() > 0 {
+		m.b.WriteString("<d:propstat><d:prop>" + missing.String() + "</d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat>")
+	}
+	m.b.WriteString("</d:response>")
+}
+
+// notFound записывает ответ для ресурса href, которого нет.
+func (m *multistatus) notFound(href string) {
+	m.b.WriteString("<d:response><d:href>" + xmlText(href) + "</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>")
+}
+
+// write записывает ответ в ответ HTTP-сервера.
+func (m *multistatus) write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	w.Write([]byte(xml.Header + `<d:multistatus xmlns:d="` + nsDAV + `" xmlns:c="` + nsCalDAV + `" xmlns:cs="` + nsCS + `">` +
+		m.b.String() + "</d:multistatus>"))
+}
+
+// taskName возвращает имя ресурса задачи в коллекции.
+func taskName(task *db.Task) string {
+	if len(task.CalDAVName) > 0 {
+		return task.CalDAVName
+	}
+	return "task-" + task.ID + ".ics"
+}
+
+// generatedName проверяет, что имя ресурса имеет вид task-<число>.ics. Такие имена получают задачи, созданные на сервере,
+// поэтому задача клиента с таким именем совпала бы по имени с задачей, которая будет создана позже.
+func generatedName(name string) bool {
+	id, ok := strings.CutPrefix(name, "task-")
+	if !ok {
+		return false
+	}
+
+	id, ok = strings.CutSuffix(id, ".ics")
+	if !ok || len(id) == 0 {
+		return false
+	}
+
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// taskResource возвращает ресурс задачи с задачей в формате iCalendar.
+// DTSTAMP задачи - время её создания, чтобы содержимое ресурса менялось только вместе с ETag.
+func taskResource(task *db.Task) (*davResource, error) {
+	stamp := time.Now()
+	if created, err := time.Parse(time.RFC3339, task.CreatedAt); err == nil {
+		stamp = created
+	}
+
+	var cal icalWriter
+	cal.header()
+	err := cal.timezones([]*db.Task{task}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	err = cal.task(task, "VTODO", stamp.UTC().Format(icalDateTimeFormat)+"Z")
+	if err != nil {
+		return nil, err
+	}
+	cal.line("END", "VCALENDAR")
+
+	return &davResource{kind: davTask, href: caldavTasks + url.PathEscape(taskName(task)), task: task, data: cal.b.String()}, nil
+}
+
+// collectionResource возвращает ресурс коллекции задач пользователя, а если depth не равен 0, то и ресурсы всех задач,
+// кроме задач в архиве и корзине.
+func collectionResource(r *http.Request, depth string) ([]*davResource, error) {
+	ctag, err := db.CollectionTag(userID(r))
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []*davResource{{kind: davCollection, href: caldavTasks, name: i18n.T(requestLang(r), "caldav.tasks"), ctag: ctag}}
+	if depth == "0" {
+		return resources, nil
+	}
+
+	tasks, err := db.ScheduledTasks(userID(r), calendarDateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		res, err := taskResource(task)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, res)
+	}
+	return resources, nil
+}
+
+// caldavRedirectHandler перенаправляет клиента CalDAV с адреса /.well-known/caldav (RFC 6764) на принципал пользователя.
+func caldavRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, caldavRoot, http.StatusMovedPermanently)
+}
+
+// caldavHandler обрабатывает запросы CalDAV к принципалу пользователя /caldav/, коллекции задач /caldav/tasks/
+// и задачам коллекции /caldav/tasks/<имя>. Принципал одновременно является домашним каталогом календарей,
+// в котором находится единственная коллекция - задачи пользователя, кроме задач в архиве и корзине.
+// Поддерживаются методы OPTIONS, PROPFIND, REPORT (calendar-query и calendar-multiget), GET, PUT и DELETE.
+func caldavHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(caldavRoot, "/")), "/")
+	name, isTask := strings.CutPrefix(path, "tasks/")
+	switch {
+	case len(path) == 0:
+		caldavPrincipal(w, r)
+	case path == "tasks":
+		caldavCollection(w, r)
+	case isTask && !strings.Contains(name, "/"):
+		caldavTask(w, r, name)
+	default:
+		writeError(w, r, errResourceNotFound)
+	}
+}
+
+// caldavPrincipal обрабатывает запрос PROPFIND к принципалу пользователя.
+// Со значением заголовка Depth, отличным от 0, в ответ добавляется коллекция задач.
+func caldavPrincipal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PROPFIND" {
+		writeError(w, r, methodNotAllowed(r.Method))
+		return
+	}
+
+	req, err := parseDAVRequest(r.Body)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+
+	user, err := db.GetUser(userID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var m multistatus
+	m.add(&davResource{kind: davPrincipal, href: caldavRoot, name: user.Login}, req.props)
+	if r.Header.Get("Depth") != "0" {
+		resources, err := collectionResource(r, "0")
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		m.add(resources[0], req.props)
+	}
+	m.write(w)
+}
+
+// caldavCollection обрабатывает запросы PROPFIND и REPORT к коллекции задач.
+// Отчёт calendar-query возвращает все задачи коллекции, calendar-multiget - задачи с указанными адресами.
+func caldavCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PROPFIND" && r.Method != "REPORT" {
+		writeError(w, r, methodNotAllowed(r.Method))
+		return
+	}
+
+	req, err := parseDAVRequest(r.Body)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+
+	var m multistatus
+	switch {
+	case r.Method == "PROPFIND":
+		resources, err := collectionResource(r, r.Header.Get("Depth"))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		for _, res := range resources {
+			m.add(res, req.props)
+		}
+
+	case req.root == xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		resources, err := collectionResource(r, "1")
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		for _, res := range resources[1:] {
+			m.add(res, req.props)
+		}
+
+	case req.root == xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		for _, href := range req.hrefs {
+			u, err := url.Parse(href)
+			if err != nil {
+				m.notFound(href)
+				continue
+			}
+
+			name, ok := strings.CutPrefix(u.Path, caldavTasks)
+			if !ok || len(name) == 0 || strings.Contains(name, "/") {
+				m.notFound(href)
+				continue
+			}
+
+			task, err := db.GetTaskByName(userID(r), name)
+			if errors.Is(err, db.ErrTaskNotFound) {
+				m.notFound(href)
+				continue
+			}
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+
+			res, err := taskResource(task)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			m.add(res, req.props)
+		}
+
+	default:
+		writeError(w, r, db.Validation("report", "caldav.report_unsupported", req.root.Local))
+		return
+	}
+	m.write(w)
+}
+
+// caldavTask обрабатывает запросы к задаче коллекции с именем ресурса name.
+func caldavTask(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method == http.MethodPut {
+		caldavPut(w, r, name)
+		return
+	}
+
+	task, err := db.GetTaskByName(userID(r), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		res, err := taskResource(task)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("ETag", taskETag(task))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(res.data))
+
+	case "PROPFIND":
+		req, err := parseDAVRequest(r.Body)
+		if err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+
+		res, err := taskResource(task)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		var m multistatus
+		m.add(res, req.props)
+		m.write(w)
+
+	case http.MethodDelete:
+		err = checkETag(r, taskETag(task))
+		if err == nil {
+			err = db.DeleteTask(userID(r), task.ID, task.Revision)
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, r, methodNotAllowed(r.Method))
+	}
+}
+
+// caldavTodo возвращает из календаря единственную задачу VTODO. Изменённые повторения задачи (RECURRENCE-ID) не учитываются.
+func caldavTodo(data []byte) (*icalComponent, error) {
+	comps, err := parseICal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var todo *icalComponent
+	for _, c := range comps {
+		if _, override := c.props["RECURRENCE-ID"]; override && c.name == "VTODO" {
+			continue
+		}
+		if c.name != "VTODO" || todo != nil {
+			return nil, db.Validation("file", "caldav.todo_required")
+		}
+		todo = c
+	}
+
+	if todo == nil {
+		return nil, db.Validation("file", "caldav.todo_required")
+	}
+	return todo, nil
+}
+
+// caldavPut обрабатывает запрос PUT на создание или изменение задачи коллекции с именем ресурса name.
+// Задача создаётся, если задачи с таким именем нет, иначе задача заменяется данными из запроса.
+// Новая задача не создаётся под именем вида task-<число>.ics, которое зарезервировано для задач, созданных на сервере,
+// при этом проект задачи и не изменившееся правило повторения сохраняются. Условия If-Match и If-None-Match
+// проверяются по ETag задачи. Задача со статусом COMPLETED выполняется так же, как по запросу /api/task/done.
+// ETag в ответе не возвращается: сохранённая задача может отличаться от переданной, и клиент должен её перечитать.
+func caldavPut(w http.ResponseWriter, r *http.Request, name string) {
+	old, err := db.GetTaskByName(userID(r), name)
+	etag := ""
+	switch {
+	case err == nil:
+		etag = taskETag(old)
+	case errors.Is(err, db.ErrTaskNotFound):
+		err = nil
+	}
+	if err == nil {
+		err = checkETag(r, etag)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if len(name) > 255 {
+		writeError(w, r, db.Validation("name", "caldav.name_invalid"))
+		return
+	}
+
+	if old == nil && generatedName(name) {
+		writeError(w, r, db.Validation("name", "caldav.name_reserved"))
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, importMaxSize))
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+
+	todo, err := caldavTodo(data)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	task, err := icalTask(todo)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if old != nil {
+		task.ID, task.ProjectID, task.Revision = old.ID, old.ProjectID, old.Revision
+		//Правило повторения выдаётся клиенту в формате RRULE, поэтому правило, которое клиент не изменил,
+		//сохраняется в прежнем виде вместе с оставшимся количеством повторений.
+		if rule, err := icalRRule(old); err == nil && strings.EqualFold(rule, task.Repeat) {
+			task.Repeat, task.RepeatUntil, task.RepeatCount = old.Repeat, old.RepeatUntil, old.RepeatCount
+		}
+	} else {
+		task.CalDAVName = name
+	}
+
+	err = checkTask(task)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	status := http.StatusNoContent
+	if old != nil {
+		err = db.UpdateTask(userID(r), task)
+	} else {
+		var id int64
+		id, err = db.AddTask(userID(r), task)
+		task.ID, status = strconv.FormatInt(id, 10), http.StatusCreated
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if strings.EqualFold(strings.TrimSpace(todo.props["STATUS"].value), "COMPLETED") {
+		task, err = db.GetTask(userID(r), task.ID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		nextDate, err := nextTaskDate(time.Now(), task)
+		if err == nil {
+			err = db.CompleteTask(userID(r), task, nextDate, "")
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(status)
+}
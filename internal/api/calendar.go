@@ -0,0 +1,450 @@
+package api
+
+//Файл содержит выдачу задач пользователя в формате iCalendar (RFC 5545) для подписки из календарей,
+//а также хендлеры выпуска и отзыва токена календаря, по которому календарь доступен без авторизации.
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+const (
+	icalDateTimeFormat string = "20060102T150405"
+	icalLineLen        int    = 75         //Максимальная длина строки iCalendar в байтах без учёта перевода строки.
+	calendarDateLimit  string = "99991231" //Дата, не позже которой назначены все задачи.
+)
+
+// Виды компонентов, которыми задачи выдаются в календаре.
+const (
+	kindEvent string = "event" //VEVENT - события, их показывают все календари.
+	kindTodo  string = "todo"  //VTODO - задачи, их показывают календари с поддержкой задач.
+)
+
+// icalPriority сопоставляет приоритетам задач значения PRIORITY iCalendar: 1 - наивысший, 9 - наименьший.
+var icalPriority = map[int]int{1: 9, 2: 5, 3: 1}
+
+// icalEscape экранирует текстовое значение свойства iCalendar.
+var icalEscape = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icalWriter формирует календарь в формате iCalendar.
+type icalWriter struct {
+	b strings.Builder
+}
+
+// header записывает начало календаря и общие свойства календаря.
+func (w *icalWriter) header() {
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//xxxeh//todo-list//RU")
+	w.line("CALSCALE", "GREGORIAN")
+}
+
+// taskUID возвращает идентификатор компонента UID задачи: UID, под которым задачу создал клиент CalDAV
+// или с которым она импортирована из iCalendar, или идентификатор, построенный по идентификатору задачи.
+func taskUID(task *db.Task) string {
+	if len(task.ICalUID) > 0 {
+		return task.ICalUID
+	}
+	return "task-" + task.ID + "@todo-list"
+}
+
+// line записывает свойство name со значением value.
+// Строки длиннее icalLineLen байт переносятся по границам символов UTF-8, продолжение строки начинается с пробела.
+func (w *icalWriter) line(name, value string) {
+	s := name + ":" + value
+	limit := icalLineLen
+	for len(s) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+		w.b.WriteString(s[:i])
+		w.b.WriteString("\r\n ")
+		s = s[i:]
+		limit = icalLineLen - 1
+	}
+	w.b.WriteString(s)
+	w.b.WriteString("\r\n")
+}
+
+// icalOffset возвращает смещение часового пояса offset в секундах в формате свойств TZOFFSETFROM и TZOFFSETTO.
+func icalOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+
+	s := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		s += fmt.Sprintf("%02d", offset%60)
+	}
+	return s
+}
+
+// zoneTransitions возвращает моменты смены смещения часового пояса loc в году year.
+func zoneTransitions(loc *time.Location, year int) []time.Time {
+	var res []time.Time
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, loc).Unix()
+	to := time.Date(year+1, 1, 1, 0, 0, 0, 0, loc).Unix()
+	offset := func(sec int64) int {
+		_, off := time.Unix(sec, 0).In(loc).Zone()
+		return off
+	}
+
+	const day int64 = 24 * 60 * 60
+	for t := from; t < to; t += day {
+		if offset(t) == offset(t+day) {
+			continue
+		}
+
+		//Смещение меняется в течение суток: момент смены ищется делением отрезка пополам.
+		lo, hi := t, t+day
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if offset(mid) == offset(lo) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		res = append(res, time.Unix(hi, 0).In(loc))
+	}
+	return res
+}
+
+// timezone записывает компонент VTIMEZONE часового пояса name, на который ссылаются параметры TZID задач.
+// Переходы на летнее время и обратно записываются ежегодными правилами по переходам года now,
+// часовой пояс без двух переходов в году - постоянным смещением, действующим в момент now.
+func (w *icalWriter) timezone(name string, now time.Time) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", icalEscape.Replace(name))
+
+	transitions := zoneTransitions(loc, now.Year())
+	if len(transitions) != 2 {
+		abbr, offset := now.In(loc).Zone()
+		w.line("BEGIN", "STANDARD")
+		w.line("DTSTART", "19700101T000000")
+		w.line("TZOFFSETFROM", icalOffset(offset))
+		w.line("TZOFFSETTO", icalOffset(offset))
+		w.line("TZNAME", icalEscape.Replace(abbr))
+		w.line("END", "STANDARD")
+		w.line("END", "VTIMEZONE")
+		return nil
+	}
+
+	for _, t := range transitions {
+		_, before := t.Add(-time.Second).Zone()
+		abbr, after := t.Zone()
+		kind := "STANDARD"
+		if after > before {
+			kind = "DAYLIGHT"
+		}
+
+		//Начало правила записывается в местном времени до перехода, как того требует RFC 5545.
+		//Переход в последнюю неделю месяца записывается как последний день недели месяца.
+		local := t.In(time.FixedZone("", before))
+		rule := nthWeekday{(local.Day()-1)/7 + 1, local.Weekday()}
+		if daysInMonth(local)-local.Day() < 7 {
+			rule.n = -1
+		}
+		start := time.Date(1970, local.Month(), 1, local.Hour(), local.Minute(), local.Second(), 0, time.UTC)
+		for !rule.match(start) {
+			start = start.AddDate(0, 0, 1)
+		}
+
+		w.line("BEGIN", kind)
+		w.line("DTSTART", start.Format(icalDateTimeFormat))
+		w.line("TZOFFSETFROM", icalOffset(before))
+		w.line("TZOFFSETTO", icalOffset(after))
+		w.line("TZNAME", icalEscape.Replace(abbr))
+		w.line("RRULE", fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", local.Month(), rule.n, rruleWeekdays[rule.weekday]))
+		w.line("END", kind)
+	}
+
+	w.line("END", "VTIMEZONE")
+	return nil
+}
+
+// timezones записывает компоненты VTIMEZONE всех часовых поясов, в которых назначены задачи tasks.
+func (w *icalWriter) timezones(tasks []*db.Task, now time.Time) error {
+	written := map[string]bool{}
+	for _, task := range tasks {
+		if len(task.Time) == 0 || len(task.Timezone) == 0 || written[task.Timezone] {
+			continue
+		}
+
+		err := w.timezone(task.Timezone, now)
+		if err != nil {
+			return err
+		}
+		written[task.Timezone] = true
+	}
+	return nil
+}
+
+// icalUntil возвращает дату окончания повторений задачи в том же виде, что и начало задачи:
+// дату для задачи на весь день, местное время для задачи без часового пояса и время UTC для задачи с часовым поясом.
+func icalUntil(task *db.Task) (string, error) {
+	if len(task.Time) == 0 {
+		return task.RepeatUntil, nil
+	}
+
+	loc := time.UTC
+	if len(task.Timezone) > 0 {
+		var err error
+		loc, err = taskLocation(task)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	until, err := time.ParseInLocation(dateFormat, task.RepeatUntil, loc)
+	if err != nil {
+		return "", err
+	}
+
+	until = until.AddDate(0, 0, 1).Add(-time.Second)
+	if len(task.Timezone) > 0 {
+		return until.UTC().Format(icalDateTimeFormat) + "Z", nil
+	}
+	return until.Format(icalDateTimeFormat), nil
+}
+
+// icalRRule возвращает правило повторения задачи в формате RRULE с ограничением количества или даты повторений задачи.
+// Если правило нельзя записать в формате RRULE, возвращается пустая строка, и задача выдаётся без повторений.
+func icalRRule(task *db.Task) (string, error) {
+	if len(task.Repeat) == 0 {
+		return "", nil
+	}
+
+	rule, err := toRRule(task.Repeat)
+	if err != nil {
+		return "", nil
+	}
+
+	//COUNT из правила при каждом выполнении задачи уменьшается в repeat_count, поэтому ограничение берётся из задачи.
+	limited := task.RepeatCount > 0 || len(task.RepeatUntil) > 0
+	var parts []string
+	for _, part := range strings.Split(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:"), ";") {
+		name, _, _ := strings.Cut(part, "=")
+		if len(part) == 0 || limited && (name == "COUNT" || name == "UNTIL") {
+			continue
+		}
+		parts = append(parts, part)
+	}
+
+	//COUNT и UNTIL не могут быть указаны в RRULE одновременно.
+	switch {
+	case task.RepeatCount > 0:
+		parts = append(parts, "COUNT="+strconv.Itoa(task.RepeatCount))
+	case len(task.RepeatUntil) > 0:
+		until, err := icalUntil(task)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "UNTIL="+until)
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// task записывает задачу компонентом component: VEVENT или VTODO.
+// Идентификатор компонента UID постоянен для задачи, поэтому календарь обновляет задачу, а не создаёт новую.
+// Задача без времени выдаётся на весь день, задача без часового пояса - в местном времени календаря,
+// часовой пояс TZID задачи должен быть записан в календарь методом timezones.
+// Срок DUE задачи VTODO должен наступать позже её начала DTSTART, поэтому задача VTODO без продолжительности
+// записывается только сроком DUE, а повторяющаяся - только началом DTSTART, от которого отсчитываются повторения.
+func (w *icalWriter) task(task *db.Task, component, stamp string) error {
+	date, err := time.Parse(dateFormat, task.Date)
+	if err != nil {
+		return err
+	}
+
+	rule, err := icalRRule(task)
+	if err != nil {
+		return err
+	}
+
+	w.line("BEGIN", component)
+	w.line("UID", icalEscape.Replace(taskUID(task)))
+	w.line("DTSTAMP", stamp)
+	if created, err := time.Parse(time.RFC3339, task.CreatedAt); err == nil {
+		w.line("CREATED", created.UTC().Format(icalDateTimeFormat)+"Z")
+	}
+	w.line("SUMMARY", icalEscape.Replace(task.Title))
+	if len(task.Comment) > 0 {
+		w.line("DESCRIPTION", icalEscape.Replace(task.Comment))
+	}
+
+	end := "DTEND"
+	if component == "VTODO" {
+		end = "DUE"
+	}
+
+	//Задача VTODO без продолжительности записывается одним свойством: сроком или, если она повторяется, началом.
+	single := "DTSTART"
+	if component == "VTODO" && len(rule) == 0 {
+		single = "DUE"
+	}
+
+	if len(task.Time) == 0 {
+		if component == "VTODO" {
+			w.line(single+";VALUE=DATE", date.Format(dateFormat))
+		} else {
+			w.line("DTSTART;VALUE=DATE", date.Format(dateFormat))
+			w.line("DTEND;VALUE=DATE", date.AddDate(0, 0, 1).Format(dateFormat))
+		}
+	} else {
+		clock, err := time.Parse(timeFormat, task.Time)
+		if err != nil {
+			return err
+		}
+
+		param := ""
+		if len(task.Timezone) > 0 {
+			param = ";TZID=" + task.Timezone
+		}
+
+		start := date.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute)
+		if task.Duration > 0 {
+			w.line("DTSTART"+param, start.Format(icalDateTimeFormat))
+			w.line(end+param, start.Add(time.Duration(task.Duration)*time.Minute).Format(icalDateTimeFormat))
+		} else {
+			w.line(single+param, start.Format(icalDateTimeFormat))
+		}
+	}
+
+	if len(rule) > 0 {
+		w.line("RRULE", rule)
+	}
+
+	if len(task.Tags) > 0 {
+		tags := make([]string, 0, len(task.Tags))
+		for _, tag := range task.Tags {
+			tags = append(tags, icalEscape.Replace(tag))
+		}
+		w.line("CATEGORIES", strings.Join(tags, ","))
+	}
+
+	if p, ok := icalPriority[task.Priority]; ok {
+		w.line("PRIORITY", strconv.Itoa(p))
+	}
+
+	if component == "VTODO" {
+		w.line("STATUS", "NEEDS-ACTION")
+	}
+
+	w.line("END", component)
+	return nil
+}
+
+// calendarHandler обрабатывает запрос на получение задач пользователя в формате iCalendar.
+// Пользователь определяется по токену календаря из параметра token, авторизация не требуется,
+// чтобы на календарь можно было подписаться из приложений календаря.
+// Параметр kind задаёт вид компонентов: event (по умолчанию) или todo.
+// В календарь попадают все задачи пользователя, кроме задач в архиве и корзине.
+func calendarHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	if len(token) == 0 {
+		writeError(w, r, errInvalidFeedToken)
+		return
+	}
+
+	user, err := db.GetUserByFeedToken(hashToken(token))
+	if errors.Is(err, db.ErrNotFound) {
+		err = errInvalidFeedToken
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var component string
+	switch r.FormValue("kind") {
+	case "", kindEvent:
+		component = "VEVENT"
+	case kindTodo:
+		component = "VTODO"
+	default:
+		writeError(w, r, db.Validation("kind", "tasks.param_invalid", "kind"))
+		return
+	}
+
+	tasks, err := db.ScheduledTasks(user.ID, calendarDateLimit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var cal icalWriter
+	cal.header()
+	cal.line("X-WR-CALNAME", icalEscape.Replace(user.Login))
+
+	now := time.Now()
+	err = cal.timezones(tasks, now)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	stamp := now.UTC().Format(icalDateTimeFormat) + "Z"
+	for _, task := range tasks {
+		err = cal.task(task, component, stamp)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	cal.line("END", "VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(cal.b.String()))
+}
+
+type feedResp struct {
+	Token string `json:"token"`
+	URL   string `json:"url"` //Путь календаря с токеном относительно адреса сервера.
+}
+
+// feedTokenHandler обрабатывает запрос на выпуск токена календаря пользователя.
+// Прежний токен календаря перестаёт действовать. В базе данных хранится только хэш токена,
+// а ответ с токеном не сохраняется в кэше и не записывается в журнал.
+func feedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := randomString(32)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.SetFeedToken(userID(r), hashToken(token))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJson(w, feedResp{Token: token, URL: "/api/calendar.ics?token=" + token}, http.StatusOK)
+}
+
+// revokeFeedTokenHandler обрабатывает запрос на отзыв токена календаря пользователя.
+func revokeFeedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	err := db.SetFeedToken(userID(r), "")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
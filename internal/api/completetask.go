@@ -8,39 +8,80 @@ import (
 )
 
 // completeTaskHandler обрабатывает запрос на завершение задачи.
-// В зависимости от наличия условия повторения задачи, задача либо удаляется, либо обновляется с новой датой.
+// В зависимости от наличия условия повторения задачи, задача либо переносится в архив, либо обновляется с новой датой.
+// Задача переносится в архив и после выполнения последнего повторения: по количеству повторений или по дате окончания.
+// Выполнение записывается в историю задачи вместе с необязательной заметкой из параметра note.
+// Задача с невыполненными обязательными пунктами или зависящая от невыполненных задач не выполняется (ответ 409).
+// Клиент передаёт версию задачи в заголовке If-Match или параметре version (без версии - ответ 428).
+// Если задача с тех пор изменилась, задача не выполняется (ответ 412).
 func completeTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 	if len(id) == 0 {
-		writeJson(w, map[string]string{"error": "Не указан идентификатор"}, http.StatusBadRequest)
+		writeError(w, r, errNoID)
 		return
 	}
 
-	task, err := db.GetTask(id)
+	revision, err := requestRevision(r, id, 0)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	if len(task.Repeat) == 0 {
-		err := db.DeleteTask(task.ID)
-		if err != nil {
-			writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
-		} else {
-			writeJson(w, struct{}{}, http.StatusOK)
-		}
+	task, err := db.GetTask(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if revision > 0 && revision != task.Revision {
+		writeError(w, r, db.ErrTaskModified)
+		return
+	}
+
+	nextDate, err := nextTaskDate(time.Now(), task)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	task.Date, err = NextDate(time.Now(), task.Date, task.Repeat)
+	err = db.CompleteTask(userID(r), task, nextDate, r.FormValue("note"))
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
+
+// taskHistoryHandler обрабатывает запрос на получение истории выполнения задачи по идентификатору.
+func taskHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if len(id) == 0 {
+		writeError(w, r, errNoID)
+		return
+	}
+
+	completions, err := db.Completions(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string][]*db.Completion{"completions": completions}, http.StatusOK)
+}
+
+// undoCompleteTaskHandler обрабатывает запрос на отмену последнего выполнения задачи.
+// Задаче возвращается прежняя дата, а перенесённая при выполнении в архив задача возвращается из архива.
+func undoCompleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if len(id) == 0 {
+		writeError(w, r, errNoID)
 		return
 	}
 
-	err = db.UpdateDate(task.Date, task.ID)
+	err := db.UndoCompletion(userID(r), id)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -7,16 +7,24 @@ import (
 )
 
 // deleteTaskHandler обрабатывает запрос на удаление задачи по идентификатору.
+// Клиент передаёт версию задачи в заголовке If-Match или параметре version (без версии - ответ 428).
+// Если задача с тех пор изменилась, задача не удаляется (ответ 412).
 func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 	if len(id) == 0 {
-		writeJson(w, map[string]string{"error": "Не указан идентификатор"}, http.StatusBadRequest)
+		writeError(w, r, errNoID)
 		return
 	}
 
-	err := db.DeleteTask(id)
+	revision, err := requestRevision(r, id, 0)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.DeleteTask(userID(r), id, revision)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,57 @@
+package api
+
+//Файл содержит хендлеры для добавления и удаления зависимостей между задачами.
+
+import (
+	"net/http"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// dependencyParams возвращает идентификаторы зависимой задачи из параметра id
+// и задачи, от которой она зависит, из параметра depends_on.
+func dependencyParams(r *http.Request) (string, string, error) {
+	id, dependsOn := r.FormValue("id"), r.FormValue("depends_on")
+	if len(id) == 0 {
+		return id, dependsOn, errNoID
+	}
+	if len(dependsOn) == 0 {
+		return id, dependsOn, db.Validation("depends_on", "task.id_required")
+	}
+	return id, dependsOn, nil
+}
+
+// addDependencyHandler обрабатывает запрос на добавление зависимости задачи id от задачи depends_on.
+// Зависимость, которая образует цикл, не добавляется.
+func addDependencyHandler(w http.ResponseWriter, r *http.Request) {
+	id, dependsOn, err := dependencyParams(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.AddDependency(userID(r), id, dependsOn)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
+
+// deleteDependencyHandler обрабатывает запрос на удаление зависимости задачи id от задачи depends_on.
+func deleteDependencyHandler(w http.ResponseWriter, r *http.Request) {
+	id, dependsOn, err := dependencyParams(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.DeleteDependency(userID(r), id, dependsOn)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
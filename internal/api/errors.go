@@ -0,0 +1,119 @@
+package api
+
+//Файл содержит формат ответа с ошибкой и функции сопоставления ошибок кодам ответа HTTP-сервера.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/xxxeh/todo-list/internal/db"
+	"github.com/xxxeh/todo-list/internal/i18n"
+)
+
+// Машиночитаемые коды ошибок, возвращаемые в поле code.
+const (
+	codeBadRequest         string = "bad_request"
+	codeValidation         string = "validation_failed"
+	codeUnauthorized       string = "unauthorized"
+	codeTokenExpired       string = "token_expired"
+	codeInvalidCredentials string = "invalid_credentials"
+	codeNotFound           string = "not_found"
+	codeConflict           string = "conflict"
+	codePreconditionFailed string = "precondition_failed"
+	codePreconditionNeeded string = "precondition_required"
+	codeMethodNotAllowed   string = "method_not_allowed"
+	codeInternal           string = "internal_error"
+)
+
+// errorResp - тело ответа с ошибкой.
+type errorResp struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+	Field string `json:"field,omitempty"`
+}
+
+// httpError - ошибка уровня API с заранее известными кодом ответа, кодом ошибки и ключом сообщения в каталоге i18n.
+type httpError struct {
+	status int
+	code   string
+	key    string
+	args   []any
+}
+
+func (e *httpError) Error() string {
+	return i18n.T(i18n.Default, e.key, e.args...)
+}
+
+var (
+	errAuthRequired        = &httpError{status: http.StatusUnauthorized, code: codeUnauthorized, key: "auth.required"}
+	errTokenExpired        = &httpError{status: http.StatusUnauthorized, code: codeTokenExpired, key: "auth.token_expired"}
+	errWrongCredentials    = &httpError{status: http.StatusUnauthorized, code: codeInvalidCredentials, key: "auth.invalid_credentials"}
+	errInvalidRefreshToken = &httpError{status: http.StatusUnauthorized, code: codeUnauthorized, key: "auth.invalid_refresh_token"}
+	errInvalidFeedToken    = &httpError{status: http.StatusUnauthorized, code: codeUnauthorized, key: "auth.invalid_feed_token"}
+	errPreconditionFailed  = &httpError{status: http.StatusPreconditionFailed, code: codePreconditionFailed, key: "request.precondition_failed"}
+	errPreconditionNeeded  = &httpError{status: http.StatusPreconditionRequired, code: codePreconditionNeeded, key: "request.precondition_required"}
+)
+
+// badRequest возвращает ошибку с кодом ответа 400 для запроса, который не удалось разобрать.
+// Ошибка валидации, которую вернул разбор значения поля, возвращается без изменений.
+func badRequest(err error) error {
+	var dbErr *db.Error
+	if errors.As(err, &dbErr) {
+		return err
+	}
+	return &httpError{status: http.StatusBadRequest, code: codeBadRequest, key: "request.malformed", args: []any{err.Error()}}
+}
+
+// methodNotAllowed возвращает ошибку с кодом ответа 405 для запроса с методом method, который ресурс не поддерживает.
+func methodNotAllowed(method string) error {
+	return &httpError{status: http.StatusMethodNotAllowed, code: codeMethodNotAllowed, key: "request.method_not_allowed", args: []any{method}}
+}
+
+// errNoID - ошибка валидации для запроса без идентификатора задачи.
+var errNoID = db.Validation("id", "task.id_required")
+
+// writeError записывает в ответ HTTP-сервера ошибку в формате errorResp.
+// Код ответа определяется видом ошибки: ошибки валидации - 422, объект не найден - 404, конфликт - 409,
+// объект изменён другим запросом - 412.
+// Прочие ошибки считаются внутренними: они записываются в журнал, а клиенту возвращается код 500 без подробностей.
+// Сообщение об ошибке переводится на язык клиента, определённый функцией requestLang.
+//
+// Параметры:
+//
+//	w - http.ResponseWriter, используемый для записи ответа клиенту.
+//	r - *http.Request, запрос клиента.
+//	err - ошибка.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var he *httpError
+	var de *db.Error
+
+	lang := requestLang(r)
+	w.Header().Set("Content-Language", lang)
+
+	switch {
+	case errors.As(err, &he):
+		writeJson(w, errorResp{Error: i18n.T(lang, he.key, he.args...), Code: he.code}, he.status)
+
+	case errors.As(err, &de):
+		resp := errorResp{Error: de.Localize(lang), Field: de.Field}
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(de, db.ErrValidation):
+			resp.Code, status = codeValidation, http.StatusUnprocessableEntity
+		case errors.Is(de, db.ErrNotFound):
+			resp.Code, status = codeNotFound, http.StatusNotFound
+		case errors.Is(de, db.ErrConflict):
+			resp.Code, status = codeConflict, http.StatusConflict
+		case errors.Is(de, db.ErrModified):
+			resp.Code, status = codePreconditionFailed, http.StatusPreconditionFailed
+		default:
+			resp.Code = codeInternal
+		}
+		writeJson(w, resp, status)
+
+	default:
+		log.Printf("Internal error: %v", err)
+		writeJson(w, errorResp{Error: i18n.T(lang, "internal"), Code: codeInternal}, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+//Файл содержит ETag задач и проверку условий запросов If-Match и If-None-Match,
+//которые защищают задачу от одновременного изменения несколькими клиентами.
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// taskETag возвращает ETag задачи. ETag меняется при каждом изменении задачи вместе с номером её версии.
+func taskETag(task *db.Task) string {
+	return `"` + task.ID + "-" + strconv.FormatInt(task.Revision, 10) + `"`
+}
+
+// etagMatch проверяет, что список ETag из заголовка If-Match или If-None-Match содержит etag или равен "*".
+// Пустой etag означает, что ресурса нет, такому ресурсу не соответствует ни один список.
+func etagMatch(header, etag string) bool {
+	if len(etag) == 0 {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkETag проверяет условия If-Match и If-None-Match запроса на изменение ресурса с ETag etag.
+// Пустой etag означает, что ресурса нет.
+//
+// Параметры:
+//
+//	r - *http.Request, запрос клиента.
+//	etag - текущий ETag ресурса.
+//
+// Возвращаемые значения:
+//
+//	error - errPreconditionFailed, если условие не выполняется.
+func checkETag(r *http.Request, etag string) error {
+	if match := r.Header.Get("If-Match"); len(match) > 0 && !etagMatch(match, etag) {
+		return errPreconditionFailed
+	}
+
+	if none := r.Header.Get("If-None-Match"); len(none) > 0 && etagMatch(none, etag) {
+		return errPreconditionFailed
+	}
+	return nil
+}
+
+// requestRevision возвращает версию задачи id, которую изменяет клиент: из ETag задачи в заголовке If-Match,
+// а если заголовка нет - номер версии version из тела запроса или из параметра version.
+// Заголовок "If-Match: *" означает, что клиент явно согласен изменить любую версию задачи, тогда возвращается 0,
+// и задача изменяется без проверки. Запрос без версии не выполняется, чтобы клиент,
+// не знающий о версиях, не затёр чужое изменение.
+//
+// Параметры:
+//
+//	r - *http.Request, запрос клиента.
+//	id - идентификатор задачи.
+//	version - номер версии из тела запроса, 0 - не указан.
+//
+// Возвращаемые значения:
+//
+//	int64 - версия задачи, которую изменяет клиент.
+//	error - db.ErrTaskModified, если ETag не относится к задаче id, errPreconditionNeeded, если версия не указана,
+//	или ошибка валидации параметра version.
+func requestRevision(r *http.Request, id string, version int64) (int64, error) {
+	match := strings.TrimSpace(r.Header.Get("If-Match"))
+	if match == "*" {
+		return 0, nil
+	}
+	if len(match) > 0 {
+		taskID, rev, ok := strings.Cut(strings.Trim(match, `"`), "-")
+		n, err := strconv.ParseInt(rev, 10, 64)
+		if !ok || taskID != id || err != nil || n <= 0 {
+			return 0, db.ErrTaskModified
+		}
+		return n, nil
+	}
+
+	if param := r.FormValue("version"); version == 0 && len(param) > 0 {
+		n, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, db.Validation("version", "tasks.param_invalid", "version")
+		}
+		return n, nil
+	}
+
+	if version <= 0 {
+		return 0, errPreconditionNeeded
+	}
+	return version, nil
+}
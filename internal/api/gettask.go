@@ -7,20 +7,25 @@ import (
 )
 
 // getTaskHandler обрабатывает запрос на получение задачи по идентификатору.
+// Версия задачи возвращается в поле version и в заголовке ETag. Если ETag из заголовка If-None-Match совпадает
+// с текущим, задача не изменилась и возвращается ответ 304 без тела.
 func getTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 	if len(id) == 0 {
-		writeJson(w, map[string]string{"error": "Не указан идентификатор"}, http.StatusBadRequest)
+		writeError(w, r, errNoID)
 		return
 	}
 
-	task, err := db.GetTask(id)
+	task, err := db.GetTask(userID(r), id)
 	if err != nil {
-		if task == nil {
-			writeJson(w, map[string]string{"error": "Задача не найдена"}, http.StatusNotFound)
-		} else {
-			writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
+		return
+	}
+
+	etag := taskETag(task)
+	w.Header().Set("ETag", etag)
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
@@ -0,0 +1,405 @@
+package api
+
+//Файл содержит импорт задач из файла в формате iCalendar (RFC 5545): разбор компонентов VTODO и VEVENT,
+//преобразование их в задачи и хендлер импорта с пробным режимом, в котором задачи не сохраняются.
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+const importMaxSize int64 = 5 << 20 //Максимальный размер импортируемого файла в байтах.
+
+// icalUnescape восстанавливает экранированное текстовое значение свойства iCalendar.
+var icalUnescape = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// icalProp - свойство компонента iCalendar.
+type icalProp struct {
+	params map[string]string //Параметры свойства, названия в верхнем регистре.
+	value  string
+}
+
+// icalComponent - компонент календаря VTODO или VEVENT.
+// Для каждого свойства хранится только первое значение.
+type icalComponent struct {
+	name  string
+	props map[string]icalProp
+}
+
+// splitICal делит строку s по разделителю sep, не учитывая разделители внутри кавычек.
+// Если limit больше нуля, строка делится не более чем на limit частей.
+func splitICal(s string, sep byte, limit int) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s) && (limit <= 0 || len(parts) < limit-1); i++ {
+		switch {
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseICalLine разбирает строку календаря вида NAME;PARAM=VALUE:value.
+// Возвращает название свойства в верхнем регистре и свойство или false, если строка не содержит двоеточия.
+func parseICalLine(line string) (string, icalProp, bool) {
+	//Двоеточие может встречаться в значении и в параметрах в кавычках, поэтому ищется первое двоеточие вне кавычек.
+	parts := splitICal(line, ':', 2)
+	if len(parts) < 2 {
+		return "", icalProp{}, false
+	}
+
+	head := splitICal(parts[0], ';', 0)
+	prop := icalProp{params: map[string]string{}, value: parts[1]}
+	for _, param := range head[1:] {
+		name, val, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(name)] = strings.Trim(val, `"`)
+	}
+	return strings.ToUpper(head[0]), prop, true
+}
+
+// parseICal разбирает календарь и возвращает его компоненты VTODO и VEVENT в порядке следования в файле.
+// Вложенные компоненты, например VALARM, и прочие компоненты календаря пропускаются.
+//
+// Параметры:
+//
+//	data - содержимое файла календаря.
+//
+// Возвращаемые значения:
+//
+//	[]*icalComponent - компоненты календаря.
+//	error - ошибка валидации поля file, если файл не является календарём.
+func parseICal(data []byte) ([]*icalComponent, error) {
+	//Продолжение длинной строки начинается с пробела или табуляции, перевод строки перед ним удаляется.
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.NewReplacer("\n ", "", "\n\t", "").Replace(text)
+
+	var comps []*icalComponent
+	var cur *icalComponent
+	calendar := false
+	depth := 0 //Глубина вложенности компонентов внутри текущего компонента.
+
+	for _, line := range strings.Split(text, "\n") {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		name, prop, ok := parseICalLine(line)
+		if !ok {
+			return nil, db.Validation("file", "import.line_invalid", line)
+		}
+
+		value := strings.ToUpper(strings.TrimSpace(prop.value))
+		switch {
+		case name == "BEGIN" && value == "VCALENDAR":
+			calendar = true
+		case name == "BEGIN" && cur != nil:
+			depth++
+		case name == "BEGIN" && (value == "VTODO" || value == "VEVENT"):
+			cur = &icalComponent{name: value, props: map[string]icalProp{}}
+		case name == "END" && cur != nil && depth > 0:
+			depth--
+		case name == "END" && cur != nil && value == cur.name:
+			comps = append(comps, cur)
+			cur = nil
+		case cur != nil && depth == 0:
+			if _, exists := cur.props[name]; !exists {
+				cur.props[name] = prop
+			}
+		}
+	}
+
+	if !calendar {
+		return nil, db.Validation("file", "import.calendar_invalid")
+	}
+	return comps, nil
+}
+
+// icalTime - дата или дата и время из свойства календаря.
+type icalTime struct {
+	t        time.Time
+	allDay   bool   //Указана только дата.
+	timezone string //Часовой пояс IANA, пустая строка - местное время.
+}
+
+// parseICalTime разбирает значение свойства DTSTART, DTEND или DUE.
+// Время UTC переводится в часовой пояс сервера. Время с часовым поясом TZID, неизвестным серверу,
+// считается местным временем.
+func parseICalTime(prop icalProp) (icalTime, error) {
+	value := strings.TrimSpace(prop.value)
+	if prop.params["VALUE"] == "DATE" || len(value) == len(dateFormat) {
+		t, err := time.Parse(dateFormat, value)
+		return icalTime{t: t, allDay: true}, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icalDateTimeFormat, strings.TrimSuffix(value, "Z"))
+		if err != nil {
+			return icalTime{}, err
+		}
+
+		loc, err := serverLocation()
+		if err != nil {
+			return icalTime{}, err
+		}
+		return icalTime{t: t.In(loc)}, nil
+	}
+
+	loc := time.UTC
+	tz := prop.params["TZID"]
+	if l, err := time.LoadLocation(tz); len(tz) > 0 && tz != "Local" && err == nil {
+		loc = l
+	} else {
+		tz = ""
+	}
+
+	t, err := time.ParseInLocation(icalDateTimeFormat, value, loc)
+	return icalTime{t: t, timezone: tz}, err
+}
+
+// parseICalDuration разбирает продолжительность в формате DURATION, например PT1H30M или P1D.
+// Продолжительность с точностью до недель, дней, часов, минут и секунд, отрицательная продолжительность не допускается.
+func parseICalDuration(s string) (time.Duration, bool) {
+	s, ok := strings.CutPrefix(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "+"), "P")
+	if !ok || len(s) == 0 {
+		return 0, false
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var d time.Duration
+	num := ""
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			num += string(c)
+		case c == 'T' && len(num) == 0:
+			//T отделяет время от дней.
+		default:
+			n, err := strconv.Atoi(num)
+			if err != nil || units[c] == 0 {
+				return 0, false
+			}
+			d += time.Duration(n) * units[c]
+			num = ""
+		}
+	}
+	return d, len(num) == 0
+}
+
+// icalUID возвращает идентификатор компонента календаря UID.
+func icalUID(c *icalComponent) string {
+	return strings.TrimSpace(icalUnescape.Replace(c.props["UID"].value))
+}
+
+// icalTask преобразует компонент календаря в задачу.
+// UID сохраняется в задаче, SUMMARY становится заголовком задачи, DESCRIPTION - комментарием, RRULE - правилом повторения,
+// CATEGORIES - тегами, PRIORITY - приоритетом. Дата задачи берётся из DUE, а для событий и задач без срока - из DTSTART.
+// Продолжительность события рассчитывается по DTEND или DURATION, продолжительность задачи VTODO - по DTSTART и DUE,
+// если срок задачи наступает не позже чем через db.MaxDuration минут после её начала: тогда дата и время берутся из DTSTART.
+//
+// Параметры:
+//
+//	c - компонент календаря.
+//
+// Возвращаемые значения:
+//
+//	*db.Task - задача, не прошедшая проверку checkTask.
+//	error - ошибка валидации поля задачи.
+func icalTask(c *icalComponent) (*db.Task, error) {
+	task := &db.Task{
+		ICalUID: icalUID(c),
+		Title:   strings.TrimSpace(icalUnescape.Replace(c.props["SUMMARY"].value)),
+		Comment: icalUnescape.Replace(c.props["DESCRIPTION"].value),
+		Repeat:  strings.TrimSpace(c.props["RRULE"].value),
+	}
+
+	prop, ok := c.props["DUE"]
+	if !ok || c.name == "VEVENT" {
+		prop, ok = c.props["DTSTART"]
+	}
+
+	if ok {
+		start, err := parseICalTime(prop)
+		if err != nil {
+			return task, db.Validation("date", "task.date_invalid")
+		}
+
+		//Срок задачи VTODO, наступающий не позже чем через db.MaxDuration минут после её начала, задаёт продолжительность задачи.
+		if begin, ok := c.props["DTSTART"]; ok && c.name == "VTODO" && prop.value != begin.value && !start.allDay {
+			t, err := parseICalTime(begin)
+			if d := start.t.Sub(t.t); err == nil && !t.allDay && t.timezone == start.timezone &&
+				d >= 0 && d <= time.Duration(db.MaxDuration)*time.Minute {
+				start, task.Duration = t, int(d.Minutes())
+			}
+		}
+
+		task.Date = start.t.Format(dateFormat)
+		if !start.allDay {
+			task.Time = start.t.Format(timeFormat)
+			task.Timezone = start.timezone
+		}
+
+		if c.name == "VEVENT" && !start.allDay {
+			var d time.Duration
+			if end, ok := c.props["DTEND"]; ok {
+				t, err := parseICalTime(end)
+				if err == nil && !t.allDay {
+					d = t.t.Sub(start.t)
+				}
+			} else if dur, ok := parseICalDuration(c.props["DURATION"].value); ok {
+				d = dur
+			}
+			task.Duration = int(d.Minutes())
+		}
+	}
+
+	if categories, ok := c.props["CATEGORIES"]; ok {
+		for _, tag := range splitICal(strings.ReplaceAll(categories.value, `\,`, "\x00"), ',', 0) {
+			tag = strings.TrimSpace(icalUnescape.Replace(strings.ReplaceAll(tag, "\x00", `\,`)))
+			if len(tag) > 0 {
+				task.Tags = append(task.Tags, tag)
+			}
+		}
+	}
+
+	//PRIORITY: 1-4 - высокий, 5 - средний, 6-9 - низкий, 0 - не указан.
+	if p, err := strconv.Atoi(strings.TrimSpace(c.props["PRIORITY"].value)); err == nil {
+		switch {
+		case p >= 1 && p <= 4:
+			task.Priority = 3
+		case p == 5:
+			task.Priority = 2
+		case p >= 6 && p <= 9:
+			task.Priority = 1
+		}
+	}
+	return task, nil
+}
+
+// dryRunParam разбирает параметр запроса dry_run: при значении true задачи не сохраняются.
+func dryRunParam(r *http.Request) (bool, error) {
+	s := r.URL.Query().Get("dry_run")
+	if len(s) == 0 {
+		return false, nil
+	}
+
+	dryRun, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, db.Validation("dry_run", "tasks.param_invalid", "dry_run")
+	}
+	return dryRun, nil
+}
+
+// readImportFile читает импортируемый файл из поля file формы multipart/form-data или из тела запроса.
+func readImportFile(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxSize)
+	defer r.Body.Close()
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return io.ReadAll(r.Body)
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// importICalHandler обрабатывает запрос на импорт задач из файла iCalendar.
+// Файл передаётся в поле file формы multipart/form-data или телом запроса.
+// Каждый компонент VTODO и VEVENT импортируется отдельно: задачи с ошибками, например с правилом повторения,
+// которое не поддерживается, не создаются, а ошибка возвращается в результате импорта компонента.
+// Выполненные и отменённые задачи, изменённые повторения (RECURRENCE-ID) и компоненты, UID которых уже есть у задачи
+// пользователя или у компонента выше в файле, пропускаются, поэтому повторный импорт файла не создаёт копии задач.
+// С параметром dry_run=true задачи только проверяются и возвращаются в результате, но не сохраняются.
+func importICalHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := dryRunParam(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	data, err := readImportFile(w, r)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+
+	comps, err := parseICal(data)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	lang := requestLang(r)
+	res := &db.ImportResult{DryRun: dryRun, Items: []db.ImportItem{}}
+	imported := map[string]string{} //Идентификаторы задач, созданных из компонентов файла, по UID.
+	for i, c := range comps {
+		uid := icalUID(c)
+		item := db.ImportItem{Index: i + 1, UID: uid, Status: db.ImportCreated}
+
+		//existing - идентификатор задачи, у которой уже есть UID компонента.
+		existing, found := imported[uid]
+		if !found && len(uid) > 0 {
+			existing, err = db.TaskIDByUID(userID(r), uid)
+			found = err == nil
+			if errors.Is(err, db.ErrTaskNotFound) {
+				err = nil
+			}
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+
+		task, err := icalTask(c)
+		if err == nil {
+			err = checkTask(task)
+		}
+		item.Title = task.Title
+
+		status := strings.ToUpper(strings.TrimSpace(c.props["STATUS"].value))
+		_, override := c.props["RECURRENCE-ID"]
+		switch {
+		case status == "COMPLETED" || status == "CANCELLED" || override:
+			item.Status, err = db.ImportSkipped, nil
+		case found:
+			item.Status, item.ID, err = db.ImportSkipped, existing, nil
+		case err != nil:
+			//Ошибка валидации записывается в результат задачи.
+		case dryRun:
+			item.Task = task
+		default:
+			var id int64
+			id, err = db.AddTask(userID(r), task)
+			if err == nil {
+				item.ID = strconv.FormatInt(id, 10)
+			}
+		}
+
+		if err == nil && item.Status == db.ImportCreated && len(uid) > 0 {
+			imported[uid] = item.ID
+		}
+
+		err = res.Add(item, err, lang)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Language", lang)
+	writeJson(w, res, http.StatusOK)
+}
@@ -0,0 +1,169 @@
+package api
+
+//Файл содержит хендлеры для работы с пунктами задачи (чек-листом).
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+type itemsOrder struct {
+	TaskID string  `json:"task_id"`
+	IDs    []int64 `json:"ids"`
+}
+
+// readBody читает тело запроса в формате JSON в v.
+func readBody(r *http.Request, v any) error {
+	var buf bytes.Buffer
+
+	_, err := buf.ReadFrom(r.Body)
+	if err != nil {
+		return badRequest(err)
+	}
+	defer r.Body.Close()
+
+	err = json.Unmarshal(buf.Bytes(), v)
+	if err != nil {
+		return badRequest(err)
+	}
+	return nil
+}
+
+// readItem читает пункт задачи из тела запроса и проверяет его название.
+// Пробелы в начале и в конце названия удаляются.
+func readItem(r *http.Request) (*db.Item, error) {
+	var item db.Item
+
+	err := readBody(r, &item)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Title = strings.TrimSpace(item.Title)
+	if n := utf8.RuneCountInString(item.Title); n == 0 || n > db.MaxItemLen {
+		return nil, db.Validation("title", "item.title_invalid", db.MaxItemLen)
+	}
+
+	return &item, nil
+}
+
+// itemsHandler обрабатывает запрос на получение пунктов задачи по идентификатору задачи.
+func itemsHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if len(id) == 0 {
+		writeError(w, r, errNoID)
+		return
+	}
+
+	items, err := db.Items(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string][]*db.Item{"items": items}, http.StatusOK)
+}
+
+// addItemHandler обрабатывает запрос на добавление пункта в конец списка пунктов задачи.
+func addItemHandler(w http.ResponseWriter, r *http.Request) {
+	item, err := readItem(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if len(item.TaskID) == 0 {
+		writeError(w, r, db.Validation("task_id", "task.id_required"))
+		return
+	}
+
+	id, err := db.AddItem(userID(r), item)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string]int64{"id": id}, http.StatusCreated)
+}
+
+// updateItemHandler обрабатывает запрос на изменение пункта задачи.
+func updateItemHandler(w http.ResponseWriter, r *http.Request) {
+	item, err := readItem(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.UpdateItem(userID(r), item)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
+
+// toggleItemHandler обрабатывает запрос на переключение отметки о выполнении пункта задачи.
+// В ответе возвращается новое значение отметки.
+func toggleItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, db.ErrItemNotFound)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	done, err := db.ToggleItem(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string]bool{"done": done}, http.StatusOK)
+}
+
+// reorderItemsHandler обрабатывает запрос на изменение порядка пунктов задачи.
+// В запросе передаются идентификаторы всех пунктов задачи в новом порядке.
+func reorderItemsHandler(w http.ResponseWriter, r *http.Request) {
+	var order itemsOrder
+
+	err := readBody(r, &order)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if len(order.TaskID) == 0 {
+		writeError(w, r, db.Validation("task_id", "task.id_required"))
+		return
+	}
+
+	err = db.ReorderItems(userID(r), order.TaskID, order.IDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
+
+// deleteItemHandler обрабатывает запрос на удаление пункта задачи по идентификатору.
+func deleteItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, db.ErrItemNotFound)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.DeleteItem(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
@@ -1,24 +1,44 @@
 package api
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// Максимальные интервалы повторения задачи.
+const (
+	maxDays   int = 400
+	maxWeeks  int = 52
+	maxMonths int = 60
+	maxYears  int = 100
+
+	//maxSearchYears - на сколько лет вперёд ищется следующая дата повторения.
+	//29 февраля, выпадающее на нужный день недели, встречается не реже чем раз в 40 лет.
+	maxSearchYears int = 50
 )
 
 // nextDateHandler обрабатывает запрос вычисление следующей даты повторения задачи.
+// Если текущая дата now не указана, то она определяется в часовом поясе tz или в часовом поясе сервера.
 func nextDateHandler(w http.ResponseWriter, r *http.Request) {
+	loc, err := taskLocation(&db.Task{Timezone: r.FormValue("tz")})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
 	now, err := time.Parse(dateFormat, r.FormValue("now"))
 	if err != nil {
-		now = time.Now()
+		now = time.Now().In(loc)
 	}
 	dstart := r.FormValue("date")
 	repeat := r.FormValue("repeat")
 	date, err := NextDate(now, dstart, repeat)
 	if err != nil {
-		writeJson(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -37,69 +57,134 @@ func nextDateHandler(w http.ResponseWriter, r *http.Request) {
 //
 // Возвращаемые значения:
 //
-//	string - рассчитанная относительно правила, следующая дата повторения задачи.
+//	string - рассчитанная относительно правила, следующая дата повторения задачи,
+//	пустая строка, если повторения по правилу закончились.
 //
-//	error - ошибка, которая могла возникнуть в ходе работы.
+//	error - ошибка валидации поля date или repeat.
 func NextDate(now time.Time, dstart string, repeat string) (string, error) {
 	date, err := time.Parse(dateFormat, dstart)
+	if err != nil {
+		return "", db.Validation("date", "task.date_invalid")
+	}
+
+	step, err := repeatStep(repeat)
 	if err != nil {
 		return "", err
 	}
 
+	date, err = step(date, now)
+	if err != nil || date.IsZero() {
+		return "", err
+	}
+
+	return date.Format(dateFormat), nil
+}
+
+// stepFunc вычисляет по правилу повторения первую после date дату повторения задачи, которая позже now.
+// Нулевая дата означает, что повторения по правилу закончились.
+type stepFunc func(date, now time.Time) (time.Time, error)
+
+// repeatStep разбирает правило повторения задачи и возвращает функцию вычисления следующей даты по нему.
+//
+// Параметры:
+//
+//	repeat - правило повторения задачи.
+//
+// Возвращаемые значения:
+//
+//	stepFunc - функция вычисления следующей даты повторения.
+//	error - ошибка валидации поля repeat.
+func repeatStep(repeat string) (stepFunc, error) {
+	if isRRule(repeat) {
+		r, err := parseRRule(repeat)
+		if err != nil {
+			return nil, err
+		}
+		return r.step, nil
+	}
+
 	params := strings.Split(repeat, " ")
 	datepart := params[0]
 
+	var next func(date, now time.Time, params []string) (time.Time, error)
 	switch datepart {
 	case "y":
-		date, err = nextYear(date, now, params)
-		if err != nil {
-			return "", err
-		}
-
+		next = nextYear
 	case "d":
-		date, err = nextDay(date, now, params)
-		if err != nil {
-			return "", err
-		}
-
+		next = nextDay
 	case "w":
-		date, err = nextDayOfWeek(date, now, params)
-		if err != nil {
-			return "", err
-		}
-
+		next = nextDayOfWeek
 	case "m":
-		date, err = nextDayOfMonth(date, now, params)
-		if err != nil {
-			return "", err
-		}
-
+		next = nextDayOfMonth
 	default:
-		return "", fmt.Errorf("Недопустимый символ %s", datepart)
+		return nil, db.Validation("repeat", "repeat.rule_invalid", datepart)
 	}
 
-	return date.Format(dateFormat), nil
+	return func(date, now time.Time) (time.Time, error) {
+		return next(date, now, params)
+	}, nil
 }
 
-// nextYear рассчитывает следющую дату, если задача выполняется ежегодно.
+// nextTaskDate вычисляет дату следующего повторения задачи с учётом условий окончания повторений:
+// оставшегося количества повторений и даты, после которой задача не повторяется.
+// Текущая дата определяется в часовом поясе задачи.
+//
+// Параметры:
+//
+//	now - текущая дата.
+//	task - задача.
+//
+// Возвращаемые значения:
+//
+//	string - следующая дата повторения задачи или пустая строка, если задача больше не повторяется.
+//	error - ошибка валидации поля date или repeat.
+func nextTaskDate(now time.Time, task *db.Task) (string, error) {
+	//Если осталось одно повторение, то это текущее.
+	if len(task.Repeat) == 0 || task.RepeatCount == 1 {
+		return "", nil
+	}
+
+	loc, err := taskLocation(task)
+	if err != nil {
+		return "", err
+	}
+
+	next, err := NextDate(now.In(loc), task.Date, task.Repeat)
+	if err != nil {
+		return "", err
+	}
+
+	//Даты в формате 20060102 можно сравнивать как строки.
+	if len(task.RepeatUntil) > 0 && next > task.RepeatUntil {
+		return "", nil
+	}
+	return next, nil
+}
+
+// nextYear рассчитывает следющую дату, если задача выполняется ежегодно или раз в несколько лет.
 //
 // Параметры:
 //
 //	date - дата выполнения задачи.
 //	now - текущая дата.
-//	params - интервал повторения задачи.
+//	params - интервал повторения задачи в годах (необязательный, по умолчанию 1).
 //
 // Возвращаемые значения:
 //
 //	time.Time - следующая дата повторения задачи.
 //	error - ошибка, которая могла возникнуть в ходе работы.
 func nextYear(date, now time.Time, params []string) (time.Time, error) {
+	years := 1
 	if len(params) > 1 {
-		return date, fmt.Errorf("Недопустимый интервал")
+		var err error
+		years, err = parseInterval(params[1], maxYears)
+		if err != nil {
+			return date, err
+		}
 	}
 
 	for {
-		date = date.AddDate(1, 0, 0)
+		date = date.AddDate(years, 0, 0)
 		if after(date, now) {
 			break
 		}
@@ -121,18 +206,14 @@ func nextYear(date, now time.Time, params []string) (time.Time, error) {
 //	error - ошибка, которая могла возникнуть в ходе работы.
 func nextDay(date, now time.Time, params []string) (time.Time, error) {
 	if len(params) == 1 {
-		return date, fmt.Errorf("Не указан интервал")
+		return date, db.Validation("repeat", "repeat.interval_missing")
 	}
 
-	days, err := strconv.Atoi(params[1])
+	days, err := parseInterval(params[1], maxDays)
 	if err != nil {
 		return date, err
 	}
 
-	if days > 400 {
-		return date, fmt.Errorf("Превышен максимально допустимый интервал %d (%d)", 400, days)
-	}
-
 	for {
 		date = date.AddDate(0, 0, days)
 		if after(date, now) {
@@ -143,13 +224,14 @@ func nextDay(date, now time.Time, params []string) (time.Time, error) {
 	return date, nil
 }
 
-// nextDay рассчитывает следющую дату, если задача выполняется с интервалом указанным в днях недели.
+// nextDayOfWeek рассчитывает следющую дату, если задача выполняется с интервалом указанным в днях недели.
+// Если указан интервал в неделях (/N), то задача повторяется раз в N недель, считая от недели, на которую назначена задача.
 //
 // Параметры:
 //
 //	date - дата выполнения задачи.
 //	now - текущая дата.
-//	params - интервал повторения задачи.
+//	params - дни недели и, необязательно, интервал в неделях.
 //
 // Возвращаемые значения:
 //
@@ -157,37 +239,47 @@ func nextDay(date, now time.Time, params []string) (time.Time, error) {
 //	error - ошибка, которая могла возникнуть в ходе работы.
 func nextDayOfWeek(date, now time.Time, params []string) (time.Time, error) {
 	if len(params) == 1 {
-		return date, fmt.Errorf("Не указан интервал")
+		return date, db.Validation("repeat", "repeat.interval_missing")
 	}
 
 	var day [7]bool
 	//Парсим переданный интервал и отмечаем "true" дни недели в массиве day.
 	for _, val := range strings.Split(params[1], ",") {
-		weekday, err := strconv.Atoi(val)
+		weekday, err := parseWeekday(val)
 		if err != nil {
 			return date, err
 		}
+		day[weekday] = true
+	}
 
-		if weekday < 1 || weekday > 7 {
-			return date, fmt.Errorf("Недопустимое значение дня недели %d", weekday)
+	weeks := 1
+	if len(params) > 2 {
+		val, ok := strings.CutPrefix(params[2], "/")
+		if !ok {
+			return date, db.Validation("repeat", "repeat.interval_invalid", params[2])
 		}
 
-		if weekday != 7 {
-			day[weekday] = true
-		} else {
-			//Если weekday = 7, то отмечаем нулевой элемент массива day.
-			//Связано с особенностями нумерации дней недели (в date.Weekday() 0 - воскресенье 1 - понедельник,
-			// 												в params 	     7 - воскресенье 1 - понедельник).
-			day[0] = true
+		var err error
+		weeks, err = parseInterval(val, maxWeeks)
+		if err != nil {
+			return date, err
 		}
-
 	}
 
+	start := startOfWeek(date)
+	limit := searchLimit(date, now)
 	for {
 		//Двигаемся с шагом в один день.
 		date = date.AddDate(0, 0, 1)
-		if after(date, now) && day[date.Weekday()] {
-			//Если день недели имеет значение true и дата стала позже текущей, то значит следующая дата найдена, выходим из цикла.
+		if date.After(limit) {
+			return date, db.Validation("repeat", "repeat.no_dates")
+		}
+
+		//Номер недели считаем от недели, на которую была назначена задача.
+		week := int(startOfWeek(date).Sub(start).Hours()/24) / 7
+		if after(date, now) && day[date.Weekday()] && week%weeks == 0 {
+			//Если день недели имеет значение true, неделя подходит под интервал и дата стала позже текущей,
+			//то значит следующая дата найдена, выходим из цикла.
 			break
 		}
 	}
@@ -195,14 +287,35 @@ func nextDayOfWeek(date, now time.Time, params []string) (time.Time, error) {
 	return date, nil
 }
 
-// nextDay рассчитывает следющую дату, если задача выполняется с интервалом указанным в днях месяца.
-// Интервал может быть указан как в виде дней месяца, так и с указанием конкретных месяцев.
+// nthWeekday - день недели с порядковым номером в месяце, например первый понедельник (1, time.Monday)
+// или последняя пятница (-1, time.Friday).
+type nthWeekday struct {
+	n       int
+	weekday time.Weekday
+}
+
+// match проверяет, что дата date является днём недели с нужным порядковым номером в своём месяце.
+func (d nthWeekday) match(date time.Time) bool {
+	if date.Weekday() != d.weekday {
+		return false
+	}
+	if d.n > 0 {
+		return (date.Day()-1)/7+1 == d.n
+	}
+	return (daysInMonth(date)-date.Day())/7+1 == -d.n
+}
+
+// nextDayOfMonth рассчитывает следющую дату, если задача выполняется с интервалом указанным в днях месяца.
+// Дни могут быть указаны числами (-1 и -2 - последний и предпоследний день месяца)
+// или днями недели с порядковым номером в виде NwD, например 1w1 - первый понедельник, -1w5 - последняя пятница.
+// После дней могут быть указаны конкретные месяцы или интервал в месяцах (/N), считая от месяца, на который назначена задача.
+// Если дни не указаны, а указан только интервал, то задача повторяется в тот же день месяца, на который она назначена.
 //
 // Параметры:
 //
 //	date - дата выполнения задачи.
 //	now - текущая дата.
-//	params - интервал повторения задачи.
+//	params - дни месяца и, необязательно, месяцы или интервал в месяцах.
 //
 // Возвращаемые значения:
 //
@@ -210,54 +323,84 @@ func nextDayOfWeek(date, now time.Time, params []string) (time.Time, error) {
 //	error - ошибка, которая могла возникнуть в ходе работы.
 func nextDayOfMonth(date, now time.Time, params []string) (time.Time, error) {
 	if len(params) == 1 {
-		return date, fmt.Errorf("Не указан интервал")
+		return date, db.Validation("repeat", "repeat.interval_missing")
 	}
 
 	var day [32]bool
 	var month [13]bool
-	lastDay := 0
-	penultDay := 0
-	currentMonth := date.Month()
-	lastDayOfMonth := time.Date(date.Year(), currentMonth+1, 1, 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
-
-	//Парсим переданный интервал и отмечаем дни "true" в массиве day.
-	for _, val := range strings.Split(params[1], ",") {
-		d, err := strconv.Atoi(val)
-		if err != nil {
-			return date, err
-		}
-		if d < -2 || d > 31 || d == 0 {
-			return date, fmt.Errorf("Недопустимое значение дня %d", d)
+	var weekdays []nthWeekday
+	lastDay := false
+	penultDay := false
+	months := 1
+
+	//Если дни не указаны, то правило состоит только из интервала в месяцах: "m /N".
+	if strings.HasPrefix(params[1], "/") {
+		if len(params) > 2 {
+			return date, db.Validation("repeat", "repeat.month_invalid", params[2])
 		}
+		day[date.Day()] = true
+		params = []string{params[0], "", params[1]}
+	} else {
+		//Парсим переданный интервал и отмечаем дни "true" в массиве day.
+		for _, val := range strings.Split(params[1], ",") {
+			//Дни недели с порядковым номером записываются в виде NwD.
+			if n, wd, ok := strings.Cut(val, "w"); ok {
+				nth, err := strconv.Atoi(n)
+				if err != nil || nth < -5 || nth > 5 || nth == 0 {
+					return date, db.Validation("repeat", "repeat.day_invalid", val)
+				}
+				weekday, err := parseWeekday(wd)
+				if err != nil {
+					return date, err
+				}
+				weekdays = append(weekdays, nthWeekday{nth, weekday})
+				continue
+			}
 
-		//Если в интервале указаны дни в виде -1, -2 - рассчитываем последний и предпоследний день текущего месяца.
-		if d == -1 {
-			lastDay = lastDayOfMonth.Day()
-			day[lastDay] = true
-			continue
-		}
+			d, err := strconv.Atoi(val)
+			if err != nil {
+				return date, db.Validation("repeat", "repeat.day_invalid", val)
+			}
+			if d < -2 || d > 31 || d == 0 {
+				return date, db.Validation("repeat", "repeat.day_invalid", val)
+			}
 
-		if d == -2 {
-			penultDay = lastDayOfMonth.Day() - 1
-			day[penultDay] = true
-			continue
+			//Последний и предпоследний день зависят от месяца, поэтому проверяются отдельно.
+			switch d {
+			case -1:
+				lastDay = true
+			case -2:
+				penultDay = true
+			default:
+				day[d] = true
+			}
 		}
-
-		day[d] = true
 	}
 
-	//Если в интервале указаны и конкретные месяцы повторения задачи, то отмечаем их "true" в массиве month.
 	if len(params) > 2 {
-		for _, val := range strings.Split(params[2], ",") {
-			m, err := strconv.Atoi(val)
+		if val, ok := strings.CutPrefix(params[2], "/"); ok {
+			//Если указан интервал в месяцах, то подходят все месяцы, а интервал проверяется отдельно.
+			var err error
+			months, err = parseInterval(val, maxMonths)
 			if err != nil {
 				return date, err
 			}
-			if m < 1 || m > 12 {
-				return date, fmt.Errorf("Недопустимое значение месяца %d", m)
+			for i := range month {
+				month[i] = true
+			}
+		} else {
+			//Если в интервале указаны конкретные месяцы повторения задачи, то отмечаем их "true" в массиве month.
+			for _, val := range strings.Split(params[2], ",") {
+				m, err := strconv.Atoi(val)
+				if err != nil {
+					return date, db.Validation("repeat", "repeat.month_invalid", val)
+				}
+				if m < 1 || m > 12 {
+					return date, db.Validation("repeat", "repeat.month_invalid", val)
+				}
+
+				month[m] = true
 			}
-
-			month[m] = true
 		}
 	} else {
 		//Если в интервале не указаны конкретные месяцы, то отметим как подходящие все месяцы.
@@ -266,27 +409,92 @@ func nextDayOfMonth(date, now time.Time, params []string) (time.Time, error) {
 		}
 	}
 
+	startMonth := date.Year()*12 + int(date.Month())
+	limit := searchLimit(date, now)
 	for {
-		if lastDay != 0 || penultDay != 0 {
-			if date.Month() != currentMonth {
-				//Если задача должна повторятся в последний и/или предпоследний день месяца и вычисление уже перешло на следующий месяц, то
-				//Пересчитываем последний и/или предпоследний день и обновляем значения массива day.
-				currentMonth = date.Month()
-				day[lastDay] = false
-				day[penultDay] = false
-				lastDayOfMonth = time.Date(date.Year(), currentMonth+1, 1, 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
-				lastDay = lastDayOfMonth.Day()
-				penultDay = lastDayOfMonth.Day() - 1
-				day[lastDay] = true
-				day[penultDay] = true
-			}
-		}
 		//Двигаемся с шагом в один день.
 		date = date.AddDate(0, 0, 1)
-		if after(date, now) && day[date.Day()] && month[date.Month()] {
-			//Если день и месяц имеют значение true и дата стала позже текущей, то значит следующая дата найдена, выходим из цикла.
+		if date.After(limit) {
+			return date, db.Validation("repeat", "repeat.no_dates")
+		}
+
+		if !after(date, now) || !month[date.Month()] || (date.Year()*12+int(date.Month())-startMonth)%months != 0 {
+			continue
+		}
+
+		last := daysInMonth(date)
+		match := day[date.Day()] || (lastDay && date.Day() == last) || (penultDay && date.Day() == last-1)
+		for _, wd := range weekdays {
+			match = match || wd.match(date)
+		}
+
+		if match {
+			//Если день и месяц подходят под правило и дата стала позже текущей, то значит следующая дата найдена, выходим из цикла.
 			break
 		}
 	}
 	return date, nil
 }
+
+// parseInterval разбирает интервал повторения задачи.
+//
+// Параметры:
+//
+//	val - интервал.
+//	max - максимально допустимое значение интервала.
+//
+// Возвращаемые значения:
+//
+//	int - интервал.
+//	error - ошибка валидации, если интервал не является положительным числом или превышает max.
+func parseInterval(val string, max int) (int, error) {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 1 {
+		return 0, db.Validation("repeat", "repeat.interval_invalid", val)
+	}
+
+	if n > max {
+		return 0, db.Validation("repeat", "repeat.interval_too_large", max, n)
+	}
+	return n, nil
+}
+
+// parseWeekday разбирает номер дня недели: 1 - понедельник, 7 - воскресенье.
+//
+// Параметры:
+//
+//	val - номер дня недели.
+//
+// Возвращаемые значения:
+//
+//	time.Weekday - день недели.
+//	error - ошибка валидации, если номер дня недели недопустим.
+func parseWeekday(val string) (time.Weekday, error) {
+	weekday, err := strconv.Atoi(val)
+	if err != nil || weekday < 1 || weekday > 7 {
+		return 0, db.Validation("repeat", "repeat.weekday_invalid", val)
+	}
+
+	//В time.Weekday 0 - воскресенье, 1 - понедельник, в правилах повторения 7 - воскресенье, 1 - понедельник.
+	return time.Weekday(weekday % 7), nil
+}
+
+// startOfWeek возвращает понедельник недели, в которую входит дата date.
+func startOfWeek(date time.Time) time.Time {
+	offset := (int(date.Weekday()) + 6) % 7
+	return date.AddDate(0, 0, -offset)
+}
+
+// daysInMonth возвращает количество дней в месяце, в который входит дата date.
+func daysInMonth(date time.Time) int {
+	return time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// searchLimit возвращает дату, после которой поиск следующей даты повторения прекращается.
+// Нужен для правил, которые никогда не дают подходящей даты, например "m 31 2".
+func searchLimit(date, now time.Time) time.Time {
+	if now.After(date) {
+		date = now
+	}
+	return date.AddDate(maxSearchYears, 0, 0)
+}
@@ -0,0 +1,209 @@
+package api
+
+//Файл содержит итератор дат повторений задачи и хендлер, раскрывающий повторения задач пользователя на заданный период.
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+const (
+	occurrencesDays     int = 7    //Длина периода по умолчанию.
+	occurrencesMaxDays  int = 366  //Максимальная длина периода.
+	occurrencesMaxCount int = 1000 //Максимальное количество повторений в ответе.
+	occurrencesMaxSteps int = 5000 //Максимальное количество шагов итератора для одной задачи.
+)
+
+// occurrenceIter перебирает по порядку даты повторений задачи, начиная с даты, на которую она назначена,
+// с учётом условий окончания повторений. Каждая следующая дата вычисляется так же, как при выполнении задачи.
+type occurrenceIter struct {
+	step      stepFunc
+	date      time.Time
+	until     string
+	remaining int
+	started   bool
+}
+
+// newOccurrenceIter создаёт итератор дат повторений задачи.
+//
+// Параметры:
+//
+//	task - задача.
+//
+// Возвращаемые значения:
+//
+//	*occurrenceIter - итератор.
+//	error - ошибка валидации поля date или repeat.
+func newOccurrenceIter(task *db.Task) (*occurrenceIter, error) {
+	date, err := time.Parse(dateFormat, task.Date)
+	if err != nil {
+		return nil, db.Validation("date", "task.date_invalid")
+	}
+
+	it := &occurrenceIter{date: date, until: task.RepeatUntil, remaining: task.RepeatCount}
+	if len(task.Repeat) == 0 {
+		//Разовая задача повторяется один раз.
+		it.remaining = 1
+		return it, nil
+	}
+
+	it.step, err = repeatStep(task.Repeat)
+	return it, err
+}
+
+// skip пропускает повторения раньше даты from.
+// Если количество повторений не ограничено, то первая подходящая дата вычисляется за один шаг.
+func (it *occurrenceIter) skip(from time.Time) error {
+	if it.remaining != 0 || !it.date.Before(from) {
+		return nil
+	}
+
+	next, err := it.step(it.date, from.AddDate(0, 0, -1))
+	if err != nil {
+		return err
+	}
+
+	//Первой датой итератора становится найденная дата.
+	it.date = next
+	if next.IsZero() || (len(it.until) > 0 && next.Format(dateFormat) > it.until) {
+		it.remaining = 1
+		it.started = true
+	}
+	return nil
+}
+
+// next возвращает следующую дату повторения задачи или false, если повторений больше нет.
+func (it *occurrenceIter) next() (time.Time, bool, error) {
+	if !it.started {
+		it.started = true
+		return it.date, true, nil
+	}
+
+	//Оставшееся количество повторений включает текущее.
+	if it.step == nil || it.remaining == 1 {
+		return time.Time{}, false, nil
+	}
+
+	next, err := it.step(it.date, it.date)
+	if err != nil || next.IsZero() {
+		return time.Time{}, false, err
+	}
+
+	if len(it.until) > 0 && next.Format(dateFormat) > it.until {
+		return time.Time{}, false, nil
+	}
+
+	if it.remaining > 0 {
+		it.remaining--
+	}
+	it.date = next
+	return next, true, nil
+}
+
+type occurrence struct {
+	Date string   `json:"date"`
+	Task *db.Task `json:"task"`
+}
+
+type occurrencesResp struct {
+	Occurrences []occurrence `json:"occurrences"`
+	Truncated   bool         `json:"truncated,omitempty"`
+}
+
+// occurrencesRange разбирает период запроса повторений.
+//
+// Параметры запроса:
+//
+//	from - первая дата периода в формате 20060102, по умолчанию текущая дата в часовом поясе сервера.
+//	to - последняя дата периода, по умолчанию через occurrencesDays дней после from.
+func occurrencesRange(r *http.Request) (time.Time, time.Time, error) {
+	loc, err := serverLocation()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	today := time.Now().In(loc).Format(dateFormat)
+	from, err := time.Parse(dateFormat, today)
+	if val := r.FormValue("from"); len(val) > 0 {
+		from, err = time.Parse(dateFormat, val)
+	}
+	if err != nil {
+		return from, from, db.Validation("from", "task.date_invalid")
+	}
+
+	to := from.AddDate(0, 0, occurrencesDays-1)
+	if val := r.FormValue("to"); len(val) > 0 {
+		to, err = time.Parse(dateFormat, val)
+		if err != nil {
+			return from, to, db.Validation("to", "task.date_invalid")
+		}
+	}
+
+	if to.Before(from) {
+		return from, to, db.Validation("to", "occurrences.range_invalid")
+	}
+
+	if to.Sub(from).Hours()/24 >= float64(occurrencesMaxDays) {
+		return from, to, db.Validation("to", "occurrences.range_too_large", occurrencesMaxDays)
+	}
+	return from, to, nil
+}
+
+// occurrencesHandler обрабатывает запрос на получение всех дат повторений задач пользователя за период.
+// Повторения возвращаются по порядку дат. Если их больше occurrencesMaxCount,
+// то возвращаются первые occurrencesMaxCount повторений и признак truncated.
+func occurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := occurrencesRange(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	tasks, err := db.ScheduledTasks(userID(r), to.Format(dateFormat))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp := occurrencesResp{Occurrences: []occurrence{}}
+	for _, task := range tasks {
+		it, err := newOccurrenceIter(task)
+		if err == nil {
+			err = it.skip(from)
+		}
+		if err != nil {
+			//Задачи с недопустимым правилом повторения показываем только в день, на который они назначены.
+			it = &occurrenceIter{remaining: 1}
+			it.date, err = time.Parse(dateFormat, task.Date)
+			if err != nil {
+				continue
+			}
+		}
+
+		for i := 0; i < occurrencesMaxSteps; i++ {
+			date, ok, err := it.next()
+			if err != nil || !ok || date.After(to) {
+				break
+			}
+			if !date.Before(from) {
+				resp.Occurrences = append(resp.Occurrences, occurrence{date.Format(dateFormat), task})
+			}
+		}
+	}
+
+	//Задачи выбраны в порядке идентификаторов, поэтому повторения одного дня остаются в том же порядке.
+	slices.SortStableFunc(resp.Occurrences, func(a, b occurrence) int {
+		return strings.Compare(a.Date, b.Date)
+	})
+
+	if len(resp.Occurrences) > occurrencesMaxCount {
+		resp.Occurrences = resp.Occurrences[:occurrencesMaxCount]
+		resp.Truncated = true
+	}
+
+	writeJson(w, resp, http.StatusOK)
+}
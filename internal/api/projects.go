@@ -0,0 +1,101 @@
+package api
+
+//Файл содержит хендлеры для работы с проектами пользователя.
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// readProject читает проект из тела запроса и проверяет его название.
+// Пробелы в начале и в конце названия удаляются.
+func readProject(r *http.Request) (*db.Project, error) {
+	var project db.Project
+	var buf bytes.Buffer
+
+	_, err := buf.ReadFrom(r.Body)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	defer r.Body.Close()
+
+	err = json.Unmarshal(buf.Bytes(), &project)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	project.Name = strings.TrimSpace(project.Name)
+	if n := utf8.RuneCountInString(project.Name); n == 0 || n > db.MaxProjectLen {
+		return nil, db.Validation("name", "project.name_invalid")
+	}
+
+	return &project, nil
+}
+
+// projectsHandler обрабатывает запрос на получение списка проектов пользователя.
+func projectsHandler(w http.ResponseWriter, r *http.Request) {
+	projects, err := db.Projects(userID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string][]*db.Project{"projects": projects}, http.StatusOK)
+}
+
+// addProjectHandler обрабатывает запрос на создание проекта.
+func addProjectHandler(w http.ResponseWriter, r *http.Request) {
+	project, err := readProject(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	id, err := db.AddProject(userID(r), project.Name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string]int64{"id": id}, http.StatusCreated)
+}
+
+// updateProjectHandler обрабатывает запрос на переименование проекта.
+func updateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	project, err := readProject(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.UpdateProject(userID(r), project)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
+
+// deleteProjectHandler обрабатывает запрос на удаление проекта по идентификатору.
+// Задачи проекта не удаляются, а остаются без проекта.
+func deleteProjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, db.ErrProjectNotFound)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.DeleteProject(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
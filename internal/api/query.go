@@ -0,0 +1,235 @@
+package api
+
+//Файл содержит разбор языка запросов списка задач.
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// queryOps - операторы условий языка запросов в порядке проверки: двухсимвольные раньше односимвольных.
+var queryOps = []string{db.OpLe, db.OpGe, db.OpNe, db.OpLt, db.OpGt, db.OpEq}
+
+// queryDateFormats - форматы дат в условиях языка запросов.
+var queryDateFormats = []string{"2006-01-02", "02.01.2006", dateFormat}
+
+// splitQuery разбивает запрос на слова по пробелам. Пробелы внутри двойных кавычек не разделяют слова.
+func splitQuery(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	quoted := false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			word.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+
+	if quoted {
+		return nil, db.Validation("search", "query.quote_unclosed")
+	}
+
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// relativeDate возвращает дату условия, отстоящую на days дней от текущей даты в часовом поясе задачи.
+func relativeDate(days int) db.DateFunc {
+	return func(today time.Time) string {
+		return today.AddDate(0, 0, days).Format(dateFormat)
+	}
+}
+
+// weekDate возвращает дату условия: день текущей недели в часовом поясе задачи, 0 - понедельник.
+func weekDate(day int) db.DateFunc {
+	return func(today time.Time) string {
+		return startOfWeek(today).AddDate(0, 0, day).Format(dateFormat)
+	}
+}
+
+// queryDate разбирает дату условия: today, tomorrow, yesterday или дату в одном из форматов queryDateFormats.
+// Относительные даты вычисляются по текущей дате в часовом поясе каждой задачи.
+func queryDate(s string) (any, bool) {
+	switch s {
+	case "today":
+		return relativeDate(0), true
+	case "tomorrow":
+		return relativeDate(1), true
+	case "yesterday":
+		return relativeDate(-1), true
+	}
+
+	for _, layout := range queryDateFormats {
+		date, err := time.Parse(layout, s)
+		if err == nil {
+			return date.Format(dateFormat), true
+		}
+	}
+	return "", false
+}
+
+// queryValue разбирает значение условия поля field.
+func queryValue(field, s string) (any, bool) {
+	switch field {
+	case db.FieldDue:
+		return queryDate(s)
+	case db.FieldPriority:
+		n, err := strconv.Atoi(s)
+		return n, err == nil && n >= 0 && n <= db.MaxPriority
+	case db.FieldTag:
+		return s, validTag(s)
+	case db.FieldProject:
+		return s, len(s) > 0
+	case db.FieldStatus:
+		return s, s == db.StatusBlocked || s == db.StatusReady
+	}
+	return nil, false
+}
+
+// queryKeyword возвращает условие для ключевого слова: today, overdue или this-week.
+// Текущая дата и неделя определяются в часовом поясе каждой задачи.
+func queryKeyword(word string) (db.Condition, bool) {
+	switch word {
+	case "today":
+		return db.Condition{Field: db.FieldDue, Op: db.OpEq, Value: relativeDate(0)}, true
+	case "overdue":
+		return db.Condition{Field: db.FieldDue, Op: db.OpLt, Value: relativeDate(0)}, true
+	case "this-week":
+		return db.Condition{Field: db.FieldDue, Op: db.OpBetween, Value: weekDate(0), To: weekDate(6)}, true
+	}
+	return db.Condition{}, false
+}
+
+// queryCondition разбирает условие вида field:value для известного поля field, где value может начинаться с оператора
+// сравнения или быть диапазоном from..to, в котором одна из границ может быть опущена.
+func queryCondition(field, value string) (db.Condition, error) {
+	c := db.Condition{Field: field, Op: db.OpEq}
+	for _, op := range queryOps {
+		if strings.HasPrefix(value, op) {
+			c.Op, value = op, value[len(op):]
+			break
+		}
+	}
+
+	if !db.ValidOp(field, c.Op) {
+		return c, db.Validation("search", "query.operator_invalid", c.Op, field)
+	}
+
+	if len(value) > 1 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = value[1 : len(value)-1]
+	}
+
+	errValue := db.Validation("search", "query.value_invalid", value, field)
+
+	if field == db.FieldRepeat {
+		switch value {
+		case "none":
+		case "any":
+			c.Op = map[string]string{db.OpEq: db.OpNe, db.OpNe: db.OpEq}[c.Op]
+		default:
+			return c, errValue
+		}
+		c.Value = ""
+	} else if from, to, ok := strings.Cut(value, ".."); ok && c.Op == db.OpEq {
+		var okFrom, okTo bool
+		c.Value, okFrom = queryValue(field, from)
+		c.To, okTo = queryValue(field, to)
+		switch {
+		case okFrom && okTo:
+			c.Op = db.OpBetween
+		case okFrom && len(to) == 0:
+			c.Op = db.OpGe
+		case okTo && len(from) == 0:
+			c.Op, c.Value, c.To = db.OpLe, c.To, nil
+		default:
+			return c, errValue
+		}
+	} else {
+		c.Value, ok = queryValue(field, value)
+		if !ok {
+			return c, errValue
+		}
+	}
+
+	//Диапазон допустим не для всех полей.
+	if !db.ValidOp(field, c.Op) {
+		return c, db.Validation("search", "query.operator_invalid", "..", field)
+	}
+	return c, nil
+}
+
+// parseSearch разбирает язык запросов списка задач.
+// Запрос состоит из слов, разделённых пробелами. Слово вида field:value задаёт условие на поле задачи:
+//
+//	due - дата задачи: due:2026-11-01, due:<2026-11-01, due:>=today, due:2026-11-01..2026-11-30, due:..tomorrow;
+//	tag - тег задачи: tag:work;
+//	project - название проекта: project:"Мой дом";
+//	priority - приоритет задачи: priority:>=2;
+//	repeat - наличие правила повторения: repeat:none или repeat:any;
+//	status - состояние по зависимостям: status:blocked или status:ready.
+//
+// Ключевые слова today, overdue и this-week выбирают задачи на сегодня, просроченные задачи и задачи на текущую неделю.
+// Относительные даты (today, tomorrow, yesterday и ключевые слова) определяются в часовом поясе задачи,
+// а для задачи без часового пояса - в часовом поясе сервера.
+// Минус перед условием или ключевым словом означает отрицание. Остальные слова, в том числе слова с двоеточием
+// после неизвестного названия поля, образуют полнотекстовый запрос.
+//
+// Параметры:
+//
+//	search - запрос.
+//
+// Возвращаемые значения:
+//
+//	string - полнотекстовый запрос, пустая строка, если в запросе только условия.
+//	[]db.Condition - условия фильтра.
+//	error - ошибка валидации поля search с описанием ошибки в запросе.
+func parseSearch(search string) (string, []db.Condition, error) {
+	words, err := splitQuery(search)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var text []string
+	var conditions []db.Condition
+	for _, word := range words {
+		not, body := false, word
+		if len(word) > 1 && strings.HasPrefix(word, "-") {
+			not, body = true, word[1:]
+		}
+
+		c, ok := queryKeyword(body)
+		if !ok {
+			field, value, found := strings.Cut(body, ":")
+			//Слово с двоеточием, которое не начинается с названия поля, например адрес ссылки, ищется как текст.
+			if !found || !db.ValidOp(field, db.OpEq) {
+				text = append(text, word)
+				continue
+			}
+
+			c, err = queryCondition(field, value)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+
+		c.Not = not
+		conditions = append(conditions, c)
+	}
+
+	return strings.Join(text, " "), conditions, nil
+}
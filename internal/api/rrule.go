@@ -0,0 +1,486 @@
+package api
+
+//Файл содержит разбор и вычисление правил повторения в формате RRULE (RFC 5545),
+//а также преобразование правил повторения в формате d/w/m/y в RRULE.
+//Поддерживаются частоты DAILY, WEEKLY, MONTHLY и YEARLY и параметры INTERVAL, BYDAY, BYMONTHDAY, BYMONTH,
+//BYSETPOS, COUNT, UNTIL и WKST. Время в правилах не учитывается: задачи назначаются на даты.
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// Частоты повторения RRULE.
+const (
+	freqDaily   string = "DAILY"
+	freqWeekly  string = "WEEKLY"
+	freqMonthly string = "MONTHLY"
+	freqYearly  string = "YEARLY"
+)
+
+// rruleWeekdays - коды дней недели RRULE в порядке time.Weekday.
+var rruleWeekdays = [7]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// rrule - разобранное правило повторения RRULE.
+type rrule struct {
+	freq       string
+	interval   int
+	byDay      []nthWeekday
+	byMonthDay []int
+	byMonth    []int
+	bySetPos   []int
+	count      int
+	until      time.Time
+	wkst       time.Weekday
+}
+
+// isRRule проверяет, записано ли правило повторения в формате RRULE.
+func isRRule(repeat string) bool {
+	repeat = strings.ToUpper(repeat)
+	return strings.HasPrefix(repeat, "RRULE:") || strings.Contains(repeat, "FREQ=")
+}
+
+// parseRRule разбирает правило повторения в формате RRULE, например "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU".
+// Префикс "RRULE:" необязателен.
+//
+// Параметры:
+//
+//	s - правило повторения.
+//
+// Возвращаемые значения:
+//
+//	*rrule - разобранное правило.
+//	error - ошибка валидации поля repeat.
+func parseRRule(s string) (*rrule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	r := &rrule{interval: 1, wkst: time.Monday}
+
+	for _, part := range strings.Split(s, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		if !ok || len(val) == 0 {
+			return nil, db.Validation("repeat", "rrule.part_invalid", part)
+		}
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch val {
+			case freqDaily, freqWeekly, freqMonthly, freqYearly:
+				r.freq = val
+			default:
+				return nil, db.Validation("repeat", "rrule.freq_invalid", val)
+			}
+
+		case "INTERVAL":
+			r.interval, err = parseInterval(val, maxDays)
+
+		case "COUNT":
+			r.count, err = strconv.Atoi(val)
+			if err != nil || r.count < 1 {
+				err = db.Validation("repeat", "rrule.part_invalid", part)
+			}
+
+		case "UNTIL":
+			//Время в UNTIL отбрасывается, учитывается только дата.
+			r.until, err = time.Parse(dateFormat, val[:min(len(val), len(dateFormat))])
+			if err != nil {
+				err = db.Validation("repeat", "rrule.part_invalid", part)
+			}
+
+		case "WKST":
+			r.wkst, err = parseRRuleWeekday(val)
+			if err != nil {
+				err = db.Validation("repeat", "rrule.part_invalid", part)
+			}
+
+		case "BYDAY":
+			for _, v := range strings.Split(val, ",") {
+				wd, err := parseRRuleByDay(v)
+				if err != nil {
+					return nil, db.Validation("repeat", "rrule.part_invalid", part)
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseRRuleList(val, -31, 31)
+
+		case "BYMONTH":
+			r.byMonth, err = parseRRuleList(val, 1, 12)
+
+		case "BYSETPOS":
+			r.bySetPos, err = parseRRuleList(val, -366, 366)
+
+		default:
+			return nil, db.Validation("repeat", "rrule.param_unsupported", name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(r.freq) == 0 {
+		return nil, db.Validation("repeat", "rrule.freq_invalid", "")
+	}
+
+	//По RFC 5545 COUNT и UNTIL не могут использоваться вместе.
+	if r.count > 0 && !r.until.IsZero() {
+		return nil, db.Validation("repeat", "rrule.count_until")
+	}
+
+	//Порядковые номера в BYDAY допустимы только для ежемесячных и ежегодных правил.
+	for _, wd := range r.byDay {
+		if wd.n != 0 && r.freq != freqMonthly && r.freq != freqYearly {
+			return nil, db.Validation("repeat", "rrule.part_invalid", "BYDAY")
+		}
+	}
+
+	//BYMONTHDAY не имеет смысла для еженедельных правил.
+	if len(r.byMonthDay) > 0 && r.freq == freqWeekly {
+		return nil, db.Validation("repeat", "rrule.part_invalid", "BYMONTHDAY")
+	}
+
+	return r, nil
+}
+
+// parseRRuleList разбирает список целых чисел из диапазона [lo, hi], не равных нулю.
+func parseRRuleList(val string, lo, hi int) ([]int, error) {
+	var list []int
+	for _, v := range strings.Split(val, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < lo || n > hi || n == 0 {
+			return nil, db.Validation("repeat", "rrule.part_invalid", val)
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+// parseRRuleWeekday разбирает код дня недели RRULE: MO, TU, WE, TH, FR, SA или SU.
+func parseRRuleWeekday(val string) (time.Weekday, error) {
+	i := slices.Index(rruleWeekdays[:], val)
+	if i < 0 {
+		return 0, db.Validation("repeat", "repeat.weekday_invalid", val)
+	}
+	return time.Weekday(i), nil
+}
+
+// parseRRuleByDay разбирает элемент BYDAY: день недели с необязательным порядковым номером, например MO, 1MO или -1FR.
+func parseRRuleByDay(val string) (nthWeekday, error) {
+	if len(val) < 2 {
+		return nthWeekday{}, db.Validation("repeat", "repeat.weekday_invalid", val)
+	}
+
+	wd, err := parseRRuleWeekday(val[len(val)-2:])
+	if err != nil {
+		return nthWeekday{}, err
+	}
+
+	n := 0
+	if num := val[:len(val)-2]; len(num) > 0 {
+		n, err = strconv.Atoi(num)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return nthWeekday{}, db.Validation("repeat", "repeat.weekday_invalid", val)
+		}
+	}
+	return nthWeekday{n, wd}, nil
+}
+
+// step вычисляет дату следующего повторения задачи по правилу RRULE.
+// Дата выполнения задачи считается первым повторением (DTSTART), COUNT учитывает и её.
+//
+// Параметры:
+//
+//	date - дата выполнения задачи.
+//	now - текущая дата.
+//
+// Возвращаемые значения:
+//
+//	time.Time - следующая дата повторения задачи или нулевая дата, если повторения закончились (COUNT или UNTIL).
+//	error - ошибка валидации поля repeat.
+func (r *rrule) step(date, now time.Time) (time.Time, error) {
+	it := r.occurrences(date)
+	limit := searchLimit(date, now)
+	for {
+		next, ok := it.next(limit)
+		if !ok {
+			if it.ended {
+				return time.Time{}, nil
+			}
+			return time.Time{}, db.Validation("repeat", "repeat.no_dates")
+		}
+
+		if next.After(date) && after(next, now) {
+			return next, nil
+		}
+	}
+}
+
+// rruleIter перебирает даты повторений правила RRULE по порядку.
+type rruleIter struct {
+	rule    *rrule
+	start   time.Time
+	period  int
+	pending []time.Time
+	emitted int
+	//ended - повторения закончились по условию COUNT или UNTIL.
+	ended bool
+}
+
+// occurrences возвращает итератор дат повторений правила, начиная с даты start.
+func (r *rrule) occurrences(start time.Time) *rruleIter {
+	return &rruleIter{rule: r, start: start, pending: []time.Time{start}}
+}
+
+// next возвращает следующую дату повторения.
+// Если повторения закончились или следующая дата позже limit, то возвращает false.
+func (it *rruleIter) next(limit time.Time) (time.Time, bool) {
+	r := it.rule
+	for len(it.pending) == 0 {
+		if it.ended {
+			return time.Time{}, false
+		}
+
+		from, to := r.period(it.start, it.period)
+		if from.After(limit) {
+			return time.Time{}, false
+		}
+		if !r.until.IsZero() && from.After(r.until) {
+			it.ended = true
+			return time.Time{}, false
+		}
+		it.period++
+
+		for _, d := range r.expand(it.start, from, to) {
+			//Первой датой всегда считается дата start, поэтому более ранние даты и её саму пропускаем.
+			if d.After(it.start) {
+				it.pending = append(it.pending, d)
+			}
+		}
+	}
+
+	d := it.pending[0]
+	it.pending = it.pending[1:]
+
+	if (!r.until.IsZero() && d.After(r.until)) || (r.count > 0 && it.emitted >= r.count) {
+		it.ended = true
+		it.pending = nil
+		return time.Time{}, false
+	}
+
+	it.emitted++
+	return d, true
+}
+
+// period возвращает первый и последний день периода повторения с номером n, считая от периода, в который входит дата start.
+func (r *rrule) period(start time.Time, n int) (time.Time, time.Time) {
+	step := n * r.interval
+	switch r.freq {
+	case freqWeekly:
+		offset := (int(start.Weekday()) - int(r.wkst) + 7) % 7
+		from := start.AddDate(0, 0, step*7-offset)
+		return from, from.AddDate(0, 0, 6)
+	case freqMonthly:
+		from := time.Date(start.Year(), start.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 1, -1)
+	case freqYearly:
+		from := time.Date(start.Year()+step, 1, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, -1)
+	default:
+		from := start.AddDate(0, 0, step)
+		return from, from
+	}
+}
+
+// expand возвращает подходящие под правило даты периода [from, to] по порядку с учётом BYSETPOS.
+func (r *rrule) expand(start, from, to time.Time) []time.Time {
+	var dates []time.Time
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if r.match(start, d) {
+			dates = append(dates, d)
+		}
+	}
+
+	if len(r.bySetPos) == 0 {
+		return dates
+	}
+
+	var set []time.Time
+	for _, pos := range r.bySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(dates) + pos
+		}
+		if i >= 0 && i < len(dates) && !slices.ContainsFunc(set, dates[i].Equal) {
+			set = append(set, dates[i])
+		}
+	}
+	slices.SortFunc(set, func(a, b time.Time) int { return a.Compare(b) })
+	return set
+}
+
+// match проверяет, подходит ли дата d под правило с первой датой start.
+func (r *rrule) match(start, d time.Time) bool {
+	if len(r.byMonth) > 0 && !slices.Contains(r.byMonth, int(d.Month())) {
+		return false
+	}
+
+	if len(r.byMonthDay) > 0 {
+		last := daysInMonth(d)
+		if !slices.ContainsFunc(r.byMonthDay, func(n int) bool {
+			return n == d.Day() || last+n+1 == d.Day()
+		}) {
+			return false
+		}
+	}
+
+	if len(r.byDay) > 0 {
+		return slices.ContainsFunc(r.byDay, func(wd nthWeekday) bool {
+			switch {
+			case wd.n == 0:
+				return d.Weekday() == wd.weekday
+			case r.freq == freqYearly && len(r.byMonth) == 0:
+				return wd.matchYear(d)
+			default:
+				return wd.match(d)
+			}
+		})
+	}
+
+	if len(r.byMonthDay) > 0 {
+		return true
+	}
+
+	//Если дни не указаны, то они берутся из первой даты.
+	switch r.freq {
+	case freqWeekly:
+		return d.Weekday() == start.Weekday()
+	case freqMonthly:
+		return d.Day() == start.Day()
+	case freqYearly:
+		return d.Day() == start.Day() && (len(r.byMonth) > 0 || d.Month() == start.Month())
+	default:
+		return true
+	}
+}
+
+// matchYear проверяет, что дата date является днём недели с нужным порядковым номером в своём году.
+func (d nthWeekday) matchYear(date time.Time) bool {
+	if date.Weekday() != d.weekday {
+		return false
+	}
+	if d.n > 0 {
+		return (date.YearDay()-1)/7+1 == d.n
+	}
+	days := time.Date(date.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	return (days-date.YearDay())/7+1 == -d.n
+}
+
+// toRRule преобразует правило повторения в формате d/w/m/y в формат RRULE.
+// Правило "m", в котором одновременно указаны числа и дни недели с порядковым номером, не может быть записано одним RRULE.
+//
+// Параметры:
+//
+//	repeat - правило повторения в формате d/w/m/y.
+//
+// Возвращаемые значения:
+//
+//	string - правило повторения в формате RRULE.
+//	error - ошибка валидации поля repeat.
+func toRRule(repeat string) (string, error) {
+	//Проверяем правило, вычисляя по нему следующую дату.
+	now := time.Now().UTC()
+	_, err := NextDate(now, now.Format(dateFormat), repeat)
+	if err != nil {
+		return "", err
+	}
+
+	if isRRule(repeat) {
+		return repeat, nil
+	}
+
+	params := strings.Split(repeat, " ")
+	var parts []string
+	switch params[0] {
+	case "d":
+		parts = append(parts, "FREQ=DAILY", "INTERVAL="+params[1])
+
+	case "y":
+		parts = append(parts, "FREQ=YEARLY")
+		if len(params) > 1 {
+			parts = append(parts, "INTERVAL="+params[1])
+		}
+
+	case "w":
+		var days []string
+		for _, val := range strings.Split(params[1], ",") {
+			wd, _ := parseWeekday(val)
+			days = append(days, rruleWeekdays[wd])
+		}
+		parts = append(parts, "FREQ=WEEKLY")
+		if len(params) > 2 {
+			parts = append(parts, "INTERVAL="+strings.TrimPrefix(params[2], "/"))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+
+	case "m":
+		parts = append(parts, "FREQ=MONTHLY")
+		if len(params) > 2 && strings.HasPrefix(params[2], "/") {
+			parts = append(parts, "INTERVAL="+params[2][1:])
+		} else if strings.HasPrefix(params[1], "/") {
+			parts = append(parts, "INTERVAL="+params[1][1:])
+		}
+
+		var days, weekdays []string
+		if !strings.HasPrefix(params[1], "/") {
+			for _, val := range strings.Split(params[1], ",") {
+				n, wd, ok := strings.Cut(val, "w")
+				if !ok {
+					d, _ := strconv.Atoi(val)
+					days = append(days, strconv.Itoa(d))
+					continue
+				}
+				nth, _ := strconv.Atoi(n)
+				weekday, _ := parseWeekday(wd)
+				weekdays = append(weekdays, strconv.Itoa(nth)+rruleWeekdays[weekday])
+			}
+		}
+
+		if len(days) > 0 && len(weekdays) > 0 {
+			return "", db.Validation("repeat", "rrule.not_convertible", repeat)
+		}
+		if len(days) > 0 {
+			parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+		}
+		if len(weekdays) > 0 {
+			parts = append(parts, "BYDAY="+strings.Join(weekdays, ","))
+		}
+		if len(params) > 2 && !strings.HasPrefix(params[2], "/") {
+			var months []string
+			for _, val := range strings.Split(params[2], ",") {
+				m, _ := strconv.Atoi(val)
+				months = append(months, strconv.Itoa(m))
+			}
+			parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+		}
+	}
+
+	return strings.Join(parts, ";"), nil
+}
+
+// rruleHandler обрабатывает запрос на преобразование правила повторения в формате d/w/m/y в формат RRULE.
+func rruleHandler(w http.ResponseWriter, r *http.Request) {
+	rule, err := toRRule(r.FormValue("repeat"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string]string{"rrule": rule}, http.StatusOK)
+}
@@ -0,0 +1,161 @@
+package api
+
+//Файл содержит хендлеры для работы с тегами пользователя и проверку приоритета и тегов задачи.
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// maxTagLen - максимальная длина названия тега в символах.
+const maxTagLen = 64
+
+// validTag проверяет название тега: от 1 до maxTagLen символов без пробелов и запятых.
+func validTag(name string) bool {
+	n := utf8.RuneCountInString(name)
+	if n == 0 || n > maxTagLen {
+		return false
+	}
+	return !strings.ContainsFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+}
+
+// checkLabels проверяет приоритет и теги задачи. Повторяющиеся теги удаляются.
+//
+// Параметры:
+//
+//	task - указатель на структуру Task, содержащую данные задачи.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка валидации поля priority или tags.
+func checkLabels(task *db.Task) error {
+	if task.Priority < 0 || task.Priority > db.MaxPriority {
+		return db.Validation("priority", "task.priority_invalid", db.MaxPriority)
+	}
+
+	if task.ProjectID < 0 {
+		return db.Validation("project_id", "project.not_found")
+	}
+
+	tags := make([]string, 0, len(task.Tags))
+	seen := make(map[string]bool, len(task.Tags))
+	for _, tag := range task.Tags {
+		if !validTag(tag) {
+			return db.Validation("tags", "task.tag_invalid", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	task.Tags = tags
+
+	return nil
+}
+
+// parseID разбирает числовой идентификатор тега, проекта, пункта задачи или представления из параметра запроса id.
+// Если идентификатор не указан, возвращается errNoID, если указан неверно - ошибка notFound.
+func parseID(r *http.Request, notFound error) (int64, error) {
+	s := r.FormValue("id")
+	if len(s) == 0 {
+		return 0, errNoID
+	}
+
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, notFound
+	}
+	return id, nil
+}
+
+// readTag читает тег из тела запроса и проверяет его название.
+func readTag(r *http.Request) (*db.Tag, error) {
+	var tag db.Tag
+	var buf bytes.Buffer
+
+	_, err := buf.ReadFrom(r.Body)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	defer r.Body.Close()
+
+	err = json.Unmarshal(buf.Bytes(), &tag)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	if !validTag(tag.Name) {
+		return nil, db.Validation("name", "tag.name_invalid")
+	}
+
+	return &tag, nil
+}
+
+// tagsHandler обрабатывает запрос на получение списка тегов пользователя.
+func tagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := db.Tags(userID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string][]*db.Tag{"tags": tags}, http.StatusOK)
+}
+
+// addTagHandler обрабатывает запрос на создание тега.
+func addTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := readTag(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	id, err := db.AddTag(userID(r), tag.Name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string]int64{"id": id}, http.StatusCreated)
+}
+
+// updateTagHandler обрабатывает запрос на переименование тега. Новое название тега получают все задачи с этим тегом.
+func updateTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := readTag(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.UpdateTag(userID(r), tag)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
+
+// deleteTagHandler обрабатывает запрос на удаление тега по идентификатору. Тег снимается со всех задач.
+func deleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, db.ErrTagNotFound)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.DeleteTag(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
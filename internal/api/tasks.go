@@ -1,25 +1,202 @@
 package api
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/xxxeh/todo-list/internal/db"
 )
 
 type tasksResp struct {
 	Tasks []*db.Task `json:"tasks"`
+	Next  string     `json:"next,omitempty"`
+}
+
+// cursor - содержимое курсора следующей страницы списка задач.
+// Клиент получает курсор в виде непрозрачной строки и передаёт его без изменений в параметре cursor.
+type cursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d,omitempty"`
+	Key  string `json:"k"`
+	ID   int64  `json:"i"`
+}
+
+// encodeCursor формирует курсор страницы, следующей за задачей last.
+func encodeCursor(q db.TasksQuery, last *db.Task) (string, error) {
+	id, err := strconv.ParseInt(last.ID, 10, 64)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(cursor{Sort: q.Sort, Desc: q.Desc, Key: last.SortKey(q.Sort), ID: id})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor разбирает курсор и записывает сортировку и начало страницы в параметры выборки q.
+func decodeCursor(s string, q *db.TasksQuery) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return db.Validation("cursor", "tasks.cursor_invalid")
+	}
+
+	var c cursor
+	err = json.Unmarshal(data, &c)
+	if err != nil || c.ID <= 0 {
+		return db.Validation("cursor", "tasks.cursor_invalid")
+	}
+
+	q.Sort, q.Desc, q.AfterKey, q.AfterID = c.Sort, c.Desc, c.Key, c.ID
+	return nil
+}
+
+// tasksQuery разбирает параметры запроса списка задач или сохранённого представления.
+//
+// Параметры запроса:
+//
+//	search - фильтр по дате или запрос на языке запросов parseSearch: условия на поля задачи и полнотекстовый запрос.
+//	sort - поле сортировки: date, title, id, created, completed (время выполнения задач в архиве)
+//	или rank (релевантность, только для полнотекстового поиска).
+//	По умолчанию результаты полнотекстового поиска сортируются по релевантности, остальные задачи - по дате.
+//	order - направление сортировки: asc (по умолчанию) или desc.
+//	limit - размер страницы, не больше tasksMaxLimit (по умолчанию tasksLimit).
+//	cursor - курсор следующей страницы из ответа на предыдущий запрос, задаёт также сортировку.
+//	tag - фильтр по названию тега.
+//	project - фильтр по идентификатору проекта.
+//	priority - фильтр по приоритету: задачи с приоритетом не ниже указанного.
+//	status - фильтр по зависимостям: blocked - заблокированные задачи, ready - задачи, готовые к выполнению.
+//	archived - выборка задач в архиве: include - вместе с остальными задачами, only - только задачи в архиве.
+//	По умолчанию задачи в архиве не выбираются.
+func tasksQuery(params url.Values) (db.TasksQuery, error) {
+	q := db.TasksQuery{
+		Search:   params.Get("search"),
+		Sort:     params.Get("sort"),
+		Limit:    tasksLimit,
+		Tag:      params.Get("tag"),
+		Status:   params.Get("status"),
+		Archived: params.Get("archived"),
+	}
+
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, db.Validation("order", "tasks.order_invalid", params.Get("order"))
+	}
+
+	if _, ok := db.SearchDate(q.Search); len(q.Search) > 0 && !ok {
+		//Относительные даты запроса для задач без часового пояса определяются в часовом поясе сервера.
+		loc, err := serverLocation()
+		if err != nil {
+			return q, err
+		}
+
+		q.Location = loc
+		q.Search, q.Conditions, err = parseSearch(q.Search)
+		if err != nil {
+			return q, err
+		}
+	}
+
+	if len(q.Sort) == 0 {
+		q.Sort = db.SortDate
+		if _, ok := db.SearchDate(q.Search); len(q.Search) > 0 && !ok {
+			q.Sort = db.SortRank
+		}
+	}
+
+	switch q.Sort {
+	case db.SortDate, db.SortTitle, db.SortID, db.SortCreated, db.SortRank, db.SortCompleted:
+	default:
+		return q, db.Validation("sort", "tasks.sort_invalid", q.Sort)
+	}
+
+	if limit := params.Get("limit"); len(limit) > 0 {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > tasksMaxLimit {
+			return q, db.Validation("limit", "tasks.limit_invalid", tasksMaxLimit)
+		}
+		q.Limit = n
+	}
+
+	if project := params.Get("project"); len(project) > 0 {
+		id, err := strconv.ParseInt(project, 10, 64)
+		if err != nil || id < 1 {
+			return q, db.Validation("project", "tasks.param_invalid", "project")
+		}
+		q.ProjectID = id
+	}
+
+	if priority := params.Get("priority"); len(priority) > 0 {
+		n, err := strconv.Atoi(priority)
+		if err != nil || n < 0 || n > db.MaxPriority {
+			return q, db.Validation("priority", "task.priority_invalid", db.MaxPriority)
+		}
+		q.MinPriority = n
+	}
+
+	switch q.Status {
+	case "", db.StatusBlocked, db.StatusReady:
+	default:
+		return q, db.Validation("status", "tasks.param_invalid", "status")
+	}
+
+	switch q.Archived {
+	case "", db.ArchivedInclude, db.ArchivedOnly:
+	default:
+		return q, db.Validation("archived", "tasks.param_invalid", "archived")
+	}
+
+	if c := params.Get("cursor"); len(c) > 0 {
+		err := decodeCursor(c, &q)
+		if err != nil {
+			return q, err
+		}
+	}
+
+	return q, nil
 }
 
 // tasksHandler обрабатывает запросы на получение списка ближайших задач.
-// Список может быть отфильтрован по дате или части названия/комментария задачи, если в запросе передан параметр search.
+// Список может быть отфильтрован по дате или полнотекстовым запросом по названию и комментарию задачи, если в запросе передан параметр search.
+// Список выдаётся постранично: если есть следующая страница, то в ответе возвращается её курсор next.
 func tasksHandler(w http.ResponseWriter, r *http.Request) {
-	search := r.FormValue("search")
+	q, err := tasksQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeTasks(w, r, q)
+}
 
-	tasks, err := db.Tasks(search, tasksLimit)
+// writeTasks выполняет выборку страницы списка задач с параметрами q и записывает её в ответ.
+func writeTasks(w http.ResponseWriter, r *http.Request, q db.TasksQuery) {
+	//Запрашиваем на одну задачу больше, чтобы узнать, есть ли следующая страница.
+	limit := q.Limit
+	q.Limit++
+	tasks, err := db.Tasks(userID(r), q)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	writeJson(w, tasksResp{Tasks: tasks}, http.StatusOK)
+	resp := tasksResp{Tasks: tasks}
+	if len(tasks) > limit {
+		resp.Tasks = tasks[:limit]
+		resp.Next, err = encodeCursor(q, resp.Tasks[limit-1])
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	writeJson(w, resp, http.StatusOK)
 }
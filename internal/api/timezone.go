@@ -0,0 +1,72 @@
+package api
+
+//Файл содержит функции определения часового пояса задачи и проверки времени, продолжительности и часового пояса задачи.
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+const timeFormat string = "15:04"
+
+// serverLocation возвращает часовой пояс сервера из переменной окружения TODO_TIMEZONE
+// или местный часовой пояс, если переменная не задана.
+func serverLocation() (*time.Location, error) {
+	name := os.Getenv("TODO_TIMEZONE")
+	if len(name) == 0 {
+		return time.Local, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("Недопустимое значение переменной окружения TODO_TIMEZONE")
+	}
+	return loc, nil
+}
+
+// taskLocation возвращает часовой пояс задачи или часовой пояс сервера, если у задачи он не указан.
+// В этом часовом поясе определяется текущая дата при вычислении следующей даты повторения задачи.
+func taskLocation(task *db.Task) (*time.Location, error) {
+	if len(task.Timezone) == 0 {
+		return serverLocation()
+	}
+
+	loc, err := time.LoadLocation(task.Timezone)
+	if err != nil {
+		return nil, db.Validation("timezone", "task.timezone_invalid", task.Timezone)
+	}
+	return loc, nil
+}
+
+// checkTime проверяет время начала, продолжительность и часовой пояс задачи.
+//
+// Параметры:
+//
+//	task - указатель на структуру Task, содержащую данные задачи.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка валидации поля time, duration или timezone.
+func checkTime(task *db.Task) error {
+	if len(task.Time) > 0 {
+		_, err := time.Parse(timeFormat, task.Time)
+		if err != nil {
+			return db.Validation("time", "task.time_invalid")
+		}
+	}
+
+	if task.Duration < 0 || task.Duration > db.MaxDuration {
+		return db.Validation("duration", "task.duration_invalid", db.MaxDuration)
+	}
+
+	//Имя "Local" допустимо для time.LoadLocation, но зависит от настроек сервера.
+	if task.Timezone == "Local" {
+		return db.Validation("timezone", "task.timezone_invalid", task.Timezone)
+	}
+
+	_, err := taskLocation(task)
+	return err
+}
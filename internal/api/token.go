@@ -0,0 +1,261 @@
+package api
+
+//Файл содержит функции выпуска access- и refresh-токенов,
+//а также хендлеры обновления токенов и завершения сессии.
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+const (
+	defaultAccessTTL  time.Duration = 15 * time.Minute
+	defaultRefreshTTL time.Duration = 30 * 24 * time.Hour
+)
+
+// tokenClaims - содержимое access-токена.
+type tokenClaims struct {
+	UserID    int64  `json:"uid"`
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+type tokensResp struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// secretKey возвращает ключ для подписания токенов из переменной окружения TODO_SECRET_KEY.
+func secretKey() ([]byte, error) {
+	secret := os.Getenv("TODO_SECRET_KEY")
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("Не определена переменная окружения TODO_SECRET_KEY")
+	}
+	return []byte(secret), nil
+}
+
+// ttl возвращает срок действия токена из переменной окружения name или значение def, если переменная не задана.
+func ttl(name string, def time.Duration) (time.Duration, error) {
+	val := os.Getenv(name)
+	if len(val) == 0 {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("Недопустимое значение переменной окружения %s", name)
+	}
+	return d, nil
+}
+
+// randomString возвращает случайную строку из n байт в кодировке base64url.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken возвращает sha256-хэш секретной части refresh-токена или токена календаря для хранения в базе данных.
+func hashToken(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// signAccessToken выпускает подписанный access-токен пользователя в рамках сессии sessionID.
+//
+// Возвращаемые значения:
+//
+//	string - подписанный токен.
+//	time.Duration - срок действия токена.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func signAccessToken(userID int64, sessionID string) (string, time.Duration, error) {
+	secret, err := secretKey()
+	if err != nil {
+		return "", 0, err
+	}
+
+	accessTTL, err := ttl("TODO_ACCESS_TTL", defaultAccessTTL)
+	if err != nil {
+		return "", 0, err
+	}
+
+	jti, err := randomString(16)
+	if err != nil {
+		return "", 0, err
+	}
+
+	now := time.Now()
+	claims := tokenClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTTL)),
+		},
+	}
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := jwtToken.SignedString(secret)
+	return signedToken, accessTTL, err
+}
+
+// newSession создаёт новую сессию пользователя и выпускает для неё пару токенов.
+// Refresh-токен имеет вид <идентификатор сессии>.<секрет>, в базе данных хранится только хэш секрета.
+func newSession(userID int64) (tokensResp, error) {
+	var resp tokensResp
+
+	//Проверяем ключ заранее, чтобы не сохранять сессию, для которой нельзя выпустить access-токен.
+	_, err := secretKey()
+	if err != nil {
+		return resp, err
+	}
+
+	refreshTTL, err := ttl("TODO_REFRESH_TTL", defaultRefreshTTL)
+	if err != nil {
+		return resp, err
+	}
+
+	sessionID, err := randomString(16)
+	if err != nil {
+		return resp, err
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		return resp, err
+	}
+
+	err = db.AddRefreshToken(&db.RefreshToken{
+		ID:        sessionID,
+		UserID:    userID,
+		TokenHash: hashToken(secret),
+		ExpiresAt: time.Now().Add(refreshTTL),
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	access, accessTTL, err := signAccessToken(userID, sessionID)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.Token = access
+	resp.RefreshToken = sessionID + "." + secret
+	resp.ExpiresIn = int64(accessTTL.Seconds())
+	return resp, nil
+}
+
+// refreshHandler обрабатывает запросы на обновление токенов.
+// Предъявленный refresh-токен заменяется новым, поэтому каждый refresh-токен можно использовать только один раз.
+// Повторное предъявление уже заменённого токена считается признаком его утечки, и сессия отзывается.
+func refreshHandler(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	var data map[string]string
+
+	_, err := buf.ReadFrom(r.Body)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	defer r.Body.Close()
+
+	err = json.Unmarshal(buf.Bytes(), &data)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+
+	sessionID, secret, ok := strings.Cut(data["refresh_token"], ".")
+	if !ok {
+		writeError(w, r, errInvalidRefreshToken)
+		return
+	}
+
+	token, err := db.GetRefreshToken(sessionID)
+	if errors.Is(err, db.ErrNotFound) {
+		err = errInvalidRefreshToken
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if token.Revoked || time.Now().After(token.ExpiresAt) {
+		writeError(w, r, errInvalidRefreshToken)
+		return
+	}
+
+	oldHash := hashToken(secret)
+	if oldHash != token.TokenHash {
+		//Токен уже был заменён: отзываем сессию, чтобы украденный токен стал бесполезен.
+		err = db.RevokeRefreshToken(sessionID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeError(w, r, errInvalidRefreshToken)
+		return
+	}
+
+	refreshTTL, err := ttl("TODO_REFRESH_TTL", defaultRefreshTTL)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	newSecret, err := randomString(32)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.RotateRefreshToken(sessionID, oldHash, hashToken(newSecret), time.Now().Add(refreshTTL))
+	if err != nil {
+		writeError(w, r, errInvalidRefreshToken)
+		return
+	}
+
+	access, accessTTL, err := signAccessToken(token.UserID, sessionID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, tokensResp{
+		Token:        access,
+		RefreshToken: sessionID + "." + newSecret,
+		ExpiresIn:    int64(accessTTL.Seconds()),
+	}, http.StatusOK)
+}
+
+// signoutHandler обрабатывает запрос на завершение текущей сессии.
+// Сессия отзывается, а кука с токеном удаляется.
+func signoutHandler(w http.ResponseWriter, r *http.Request) {
+	err := db.RevokeRefreshToken(sessionID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: "token", Value: "", Path: "/", MaxAge: -1})
+	writeJson(w, struct{}{}, http.StatusOK)
+}
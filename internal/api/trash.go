@@ -0,0 +1,116 @@
+package api
+
+//Файл содержит хендлеры корзины: просмотр, восстановление и окончательное удаление задач,
+//а также фоновую очистку корзины от задач, срок хранения которых истёк.
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+const (
+	defaultTrashTTL    time.Duration = 30 * 24 * time.Hour
+	trashPurgeInterval time.Duration = time.Hour
+)
+
+// StartTrashPurge запускает фоновую очистку корзины: при запуске и затем каждый час окончательно удаляются задачи,
+// которые находятся в корзине дольше срока из переменной окружения TODO_TRASH_TTL.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, если значение переменной окружения TODO_TRASH_TTL недопустимо.
+func StartTrashPurge() error {
+	trashTTL, err := ttl("TODO_TRASH_TTL", defaultTrashTTL)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for ; ; <-ticker.C {
+			purgeTrash(trashTTL)
+		}
+	}()
+	return nil
+}
+
+// purgeTrash окончательно удаляет задачи, которые находятся в корзине дольше trashTTL.
+func purgeTrash(trashTTL time.Duration) {
+	count, err := db.PurgeTrash(trashTTL)
+	if err != nil {
+		log.Printf("Trash purge failed: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Purged %d tasks from trash", count)
+	}
+}
+
+// trashHandler обрабатывает запрос на получение задач в корзине.
+// Задачи, срок хранения которых истёк с момента последней фоновой очистки, не выдаются: их удалит фоновая очистка.
+func trashHandler(w http.ResponseWriter, r *http.Request) {
+	trashTTL, err := ttl("TODO_TRASH_TTL", defaultTrashTTL)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	tasks, err := db.Trash(userID(r), trashTTL)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, tasksResp{Tasks: tasks}, http.StatusOK)
+}
+
+// restoreTaskHandler обрабатывает запрос на восстановление задачи id из корзины.
+func restoreTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if len(id) == 0 {
+		writeError(w, r, errNoID)
+		return
+	}
+
+	err := db.RestoreTask(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
+
+// purgeTaskHandler обрабатывает запрос на окончательное удаление задачи id из корзины.
+func purgeTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if len(id) == 0 {
+		writeError(w, r, errNoID)
+		return
+	}
+
+	err := db.PurgeTask(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
+
+// emptyTrashHandler обрабатывает запрос на окончательное удаление всех задач из корзины.
+// В ответе возвращается количество удалённых задач.
+func emptyTrashHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := db.EmptyTrash(userID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string]int64{"purged": count}, http.StatusOK)
+}
@@ -10,13 +10,15 @@ import (
 )
 
 // tasksHandler обрабатывает запросы на изменение задачи.
+// Клиент передаёт версию задачи в заголовке If-Match или в поле version (без версии - ответ 428).
+// Если задача с тех пор изменилась, задача не изменяется (ответ 412). Новая версия задачи возвращается в заголовке ETag.
 func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var task db.Task
 	var buf bytes.Buffer
 
 	_, err := buf.ReadFrom(r.Body)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
+		writeError(w, r, badRequest(err))
 		return
 	}
 	defer r.Body.Close()
@@ -24,27 +26,29 @@ func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = json.Unmarshal(buf.Bytes(), &task)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
+		writeError(w, r, badRequest(err))
 		return
 	}
 
-	if task.Title == "" {
-		writeJson(w, map[string]string{"error": "Не указан заголовок задачи"}, http.StatusBadRequest)
+	task.Revision, err = requestRevision(r, task.ID, task.Revision)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	err = checkDate(&task)
+	err = checkTask(&task)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	err = db.UpdateTask(&task)
+	err = db.UpdateTask(userID(r), &task)
 
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", taskETag(&task))
 	writeJson(w, struct{}{}, http.StatusOK)
 }
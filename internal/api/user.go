@@ -0,0 +1,67 @@
+package api
+
+//Файл содержит хендлеры получения и изменения настроек профиля пользователя.
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/xxxeh/todo-list/internal/db"
+	"github.com/xxxeh/todo-list/internal/i18n"
+)
+
+type userSettings struct {
+	Lang string `json:"lang"`
+}
+
+// getUserHandler обрабатывает запрос на получение профиля авторизованного пользователя.
+func getUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := db.GetUser(userID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, user, http.StatusOK)
+}
+
+// updateUserHandler обрабатывает запрос на изменение настроек пользователя.
+// Сейчас настраивается только язык сообщений API: ru, en или пустая строка,
+// если язык нужно определять по заголовку Accept-Language.
+func updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	var settings userSettings
+	var buf bytes.Buffer
+
+	_, err := buf.ReadFrom(r.Body)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	defer r.Body.Close()
+
+	err = json.Unmarshal(buf.Bytes(), &settings)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+
+	if len(settings.Lang) > 0 && !i18n.Supported(settings.Lang) {
+		writeError(w, r, db.Validation("lang", "user.lang_invalid", settings.Lang))
+		return
+	}
+
+	err = db.SetUserLang(userID(r), settings.Lang)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := db.GetUser(userID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, user, http.StatusOK)
+}
@@ -0,0 +1,177 @@
+package api
+
+//Файл содержит хендлеры для работы с сохранёнными представлениями списка задач.
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// maxViewLen - максимальная длина названия представления в символах.
+const maxViewLen = 128
+
+// viewParams возвращает параметры запроса списка задач, сохранённые в представлении.
+func viewParams(view *db.View) url.Values {
+	params := url.Values{}
+	for name, value := range map[string]string{
+		"search":   view.Search,
+		"tag":      view.Tag,
+		"status":   view.Status,
+		"sort":     view.Sort,
+		"order":    view.Order,
+		"archived": view.Archived,
+	} {
+		if len(value) > 0 {
+			params.Set(name, value)
+		}
+	}
+
+	if view.ProjectID > 0 {
+		params.Set("project", strconv.FormatInt(view.ProjectID, 10))
+	}
+	if view.Priority > 0 {
+		params.Set("priority", strconv.Itoa(view.Priority))
+	}
+	return params
+}
+
+// readView читает представление из тела запроса и проверяет его название и параметры.
+// Параметры проверяются так же, как параметры запроса списка задач.
+func readView(r *http.Request) (*db.View, error) {
+	var view db.View
+
+	err := readBody(r, &view)
+	if err != nil {
+		return nil, err
+	}
+
+	view.Name = strings.TrimSpace(view.Name)
+	if n := utf8.RuneCountInString(view.Name); n == 0 || n > maxViewLen {
+		return nil, db.Validation("name", "view.name_invalid")
+	}
+
+	_, err = tasksQuery(viewParams(&view))
+	if err != nil {
+		return nil, err
+	}
+
+	return &view, nil
+}
+
+// viewsHandler обрабатывает запрос на получение списка сохранённых представлений пользователя.
+func viewsHandler(w http.ResponseWriter, r *http.Request) {
+	views, err := db.Views(userID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string][]*db.View{"views": views}, http.StatusOK)
+}
+
+// getViewHandler обрабатывает запрос на получение сохранённого представления по идентификатору.
+func getViewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, db.ErrViewNotFound)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	view, err := db.GetView(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, view, http.StatusOK)
+}
+
+// addViewHandler обрабатывает запрос на сохранение нового представления.
+func addViewHandler(w http.ResponseWriter, r *http.Request) {
+	view, err := readView(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	id, err := db.AddView(userID(r), view)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, map[string]int64{"id": id}, http.StatusCreated)
+}
+
+// updateViewHandler обрабатывает запрос на изменение сохранённого представления.
+func updateViewHandler(w http.ResponseWriter, r *http.Request) {
+	view, err := readView(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.UpdateView(userID(r), view)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
+
+// deleteViewHandler обрабатывает запрос на удаление сохранённого представления по идентификатору.
+func deleteViewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, db.ErrViewNotFound)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = db.DeleteView(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJson(w, struct{}{}, http.StatusOK)
+}
+
+// viewTasksHandler обрабатывает запрос на получение списка задач сохранённого представления.
+// Параметры представления разбираются и выполняются так же, как параметры запроса GET /api/tasks,
+// а размер страницы и курсор передаются в параметрах limit и cursor.
+// Относительные даты в запросе представления, например today, вычисляются в момент выполнения.
+func viewTasksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, r, db.ErrViewNotFound)
+		return
+	}
+
+	view, err := db.GetView(userID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	params := viewParams(view)
+	for _, name := range []string{"limit", "cursor"} {
+		if value := r.URL.Query().Get(name); len(value) > 0 {
+			params.Set(name, value)
+		}
+	}
+
+	q, err := tasksQuery(params)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeTasks(w, r, q)
+}
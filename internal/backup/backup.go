@@ -0,0 +1,458 @@
+// Пакет backup содержит резервное копирование задач пользователя: выгрузку всех задач в форматах JSON и CSV
+// и загрузку задач из резервной копии с выбором действия для задач, которые уже есть у пользователя.
+// Пакет используется хендлерами API и подкомандами export и import командной строки.
+package backup
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+// backupVersion - версия формата резервной копии JSON. Копии с большей версией не загружаются.
+const backupVersion = 1
+
+// MalformedError - ошибка разбора файла резервной копии: файл не является документом JSON или CSV.
+type MalformedError struct {
+	Err error
+}
+
+func (e *MalformedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MalformedError) Unwrap() error {
+	return e.Err
+}
+
+// CheckFunc проверяет задачу копии так же, как задачу, добавляемую через API, и рассчитывает её дату.
+type CheckFunc func(task *db.Task) error
+
+// Форматы резервной копии.
+const (
+	FormatJSON string = "json"
+	FormatCSV  string = "csv"
+)
+
+// Действия с задачами резервной копии, которые уже есть у пользователя.
+const (
+	ModeSkip      string = "skip"      //Задача пропускается.
+	ModeOverwrite string = "overwrite" //Задача заменяется задачей из копии.
+	ModeDuplicate string = "duplicate" //Из копии создаётся новая задача.
+)
+
+// backupColumns - столбцы резервной копии CSV. Теги и зависимости перечисляются через запятую, пункты задач не выгружаются.
+var backupColumns = []string{"id", "date", "title", "comment", "repeat", "repeat_until", "repeat_count", "time", "duration",
+	"timezone", "priority", "project", "tags", "depends_on", "created_at", "completed_at"}
+
+// backupItem - пункт задачи в резервной копии.
+type backupItem struct {
+	Title    string `json:"title"`
+	Done     bool   `json:"done,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// backupTask - задача в резервной копии. Проект указывается названием, зависимости - идентификаторами задач копии.
+type backupTask struct {
+	ID          string       `json:"id"`
+	Date        string       `json:"date"`
+	Title       string       `json:"title"`
+	Comment     string       `json:"comment,omitempty"`
+	Repeat      string       `json:"repeat,omitempty"`
+	RepeatUntil string       `json:"repeat_until,omitempty"`
+	RepeatCount int          `json:"repeat_count,omitempty"`
+	Time        string       `json:"time,omitempty"`
+	Duration    int          `json:"duration,omitempty"`
+	Timezone    string       `json:"timezone,omitempty"`
+	Priority    int          `json:"priority,omitempty"`
+	Project     string       `json:"project,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	DependsOn   []string     `json:"depends_on,omitempty"`
+	Items       []backupItem `json:"items,omitempty"`
+	CreatedAt   string       `json:"created_at,omitempty"`
+	CompletedAt string       `json:"completed_at,omitempty"` //Время выполнения задачи в архиве.
+
+	err error //Ошибка разбора строки CSV.
+}
+
+// backupFile - резервная копия в формате JSON. Неизвестные поля при загрузке не учитываются,
+// поэтому копии, дополненные новыми данными, загружаются прежними версиями сервиса.
+type backupFile struct {
+	Version    int          `json:"version"`
+	ExportedAt string       `json:"exported_at"`
+	Tasks      []backupTask `json:"tasks"`
+}
+
+// backupTasks возвращает все задачи пользователя, кроме задач в корзине, в виде задач резервной копии.
+func backupTasks(userID int64) ([]backupTask, error) {
+	tasks, err := db.ExportTasks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := db.ExportItems(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := db.Projects(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int64]string, len(projects))
+	for _, p := range projects {
+		names[p.ID] = p.Name
+	}
+
+	backup := make([]backupTask, 0, len(tasks))
+	for _, t := range tasks {
+		b := backupTask{ID: t.ID, Date: t.Date, Title: t.Title, Comment: t.Comment, Repeat: t.Repeat,
+			RepeatUntil: t.RepeatUntil, RepeatCount: t.RepeatCount, Time: t.Time, Duration: t.Duration,
+			Timezone: t.Timezone, Priority: t.Priority, Project: names[t.ProjectID], Tags: t.Tags,
+			DependsOn: t.DependsOn, CreatedAt: t.CreatedAt, CompletedAt: t.CompletedAt}
+		for _, item := range items[t.ID] {
+			b.Items = append(b.Items, backupItem{Title: item.Title, Done: item.Done, Optional: item.Optional})
+		}
+		backup = append(backup, b)
+	}
+	return backup, nil
+}
+
+// Export записывает в w резервную копию всех задач пользователя, кроме задач в корзине.
+// В копию входят задачи в архиве, теги, проекты, зависимости и, в формате JSON, пункты задач.
+//
+// Параметры:
+//
+//	w - получатель резервной копии.
+//	userID - идентификатор владельца задач.
+//	format - формат копии: FormatJSON или FormatCSV.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ошибка валидации поля format.
+func Export(w io.Writer, userID int64, format string) error {
+	if format != FormatJSON && format != FormatCSV {
+		return db.Validation("format", "tasks.param_invalid", "format")
+	}
+
+	tasks, err := backupTasks(userID)
+	if err != nil {
+		return err
+	}
+
+	if format == FormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(backupFile{Version: backupVersion, ExportedAt: time.Now().UTC().Format(time.RFC3339), Tasks: tasks})
+	}
+
+	cw := csv.NewWriter(w)
+	err = cw.Write(backupColumns)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		err = cw.Write([]string{t.ID, t.Date, t.Title, t.Comment, t.Repeat, t.RepeatUntil, strconv.Itoa(t.RepeatCount),
+			t.Time, strconv.Itoa(t.Duration), t.Timezone, strconv.Itoa(t.Priority), t.Project,
+			strings.Join(t.Tags, ","), strings.Join(t.DependsOn, ","), t.CreatedAt, t.CompletedAt})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// parseBackupJSON разбирает резервную копию в формате JSON.
+func parseBackupJSON(data []byte) ([]backupTask, error) {
+	var file backupFile
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, &MalformedError{Err: err}
+	}
+
+	if file.Version < 1 || file.Version > backupVersion {
+		return nil, db.Validation("file", "import.version_unsupported", file.Version)
+	}
+	return file.Tasks, nil
+}
+
+// splitList разбирает список значений через запятую. Пустые значения пропускаются.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// parseBackupCSV разбирает резервную копию в формате CSV. Столбцы определяются по первой строке копии,
+// неизвестные столбцы не учитываются, обязателен только столбец title.
+// Ошибка в значении числового столбца записывается в задачу и возвращается в результате её импорта.
+func parseBackupCSV(data []byte) ([]backupTask, error) {
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, &MalformedError{Err: err}
+	}
+
+	columns := map[string]int{}
+	if len(records) > 0 {
+		for i, name := range records[0] {
+			columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+		}
+	}
+
+	if _, ok := columns["title"]; !ok {
+		return nil, db.Validation("file", "import.csv_header_invalid")
+	}
+
+	var tasks []backupTask
+	for _, rec := range records[1:] {
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(rec) {
+				return rec[i]
+			}
+			return ""
+		}
+
+		var t backupTask
+		//atoi разбирает числовой столбец name, пустое значение равно нулю. Первая ошибка сохраняется в задаче.
+		atoi := func(name string, invalid error) int {
+			s := strings.TrimSpace(get(name))
+			if len(s) == 0 {
+				return 0
+			}
+			n, err := strconv.Atoi(s)
+			if err != nil && t.err == nil {
+				t.err = invalid
+			}
+			return n
+		}
+
+		t.ID, t.Date, t.Title, t.Comment = get("id"), get("date"), get("title"), get("comment")
+		t.Repeat, t.RepeatUntil, t.Time, t.Timezone = get("repeat"), get("repeat_until"), get("time"), get("timezone")
+		t.Project, t.CreatedAt, t.CompletedAt = get("project"), get("created_at"), get("completed_at")
+		t.Tags, t.DependsOn = splitList(get("tags")), splitList(get("depends_on"))
+		t.RepeatCount = atoi("repeat_count", db.Validation("repeat_count", "task.count_invalid"))
+		t.Duration = atoi("duration", db.Validation("duration", "task.duration_invalid", db.MaxDuration))
+		t.Priority = atoi("priority", db.Validation("priority", "task.priority_invalid", db.MaxPriority))
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+// backupImport - состояние загрузки резервной копии.
+type backupImport struct {
+	userID   int64
+	mode     string
+	dryRun   bool
+	check    CheckFunc
+	projects map[string]int64  //Идентификаторы проектов пользователя по названиям.
+	ids      map[string]string //Идентификаторы сохранённых и пропущенных задач по идентификаторам задач копии.
+}
+
+// projectID возвращает идентификатор проекта пользователя с названием name и создаёт проект, если его нет.
+// В пробном режиме проект не создаётся, а задача, которая будет в нём, возвращается без проекта.
+func (bi *backupImport) projectID(name string) (int64, error) {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return 0, nil
+	}
+
+	if n := utf8.RuneCountInString(name); n > db.MaxProjectLen {
+		return 0, db.Validation("project", "project.name_invalid")
+	}
+
+	if id, ok := bi.projects[name]; ok || bi.dryRun {
+		return id, nil
+	}
+
+	id, err := db.AddProject(bi.userID, name)
+	if err != nil {
+		return 0, err
+	}
+
+	bi.projects[name] = id
+	return id, nil
+}
+
+// task проверяет задачу копии функцией check и возвращает задачу для сохранения и её пункты.
+// Задаче в архиве дата не пересчитывается.
+func (bi *backupImport) task(b *backupTask) (*db.Task, []*db.Item, error) {
+	task := &db.Task{ID: b.ID, Date: b.Date, Title: strings.TrimSpace(b.Title), Comment: b.Comment, Repeat: b.Repeat,
+		RepeatUntil: b.RepeatUntil, RepeatCount: b.RepeatCount, Time: b.Time, Duration: b.Duration, Timezone: b.Timezone,
+		Priority: b.Priority, Tags: b.Tags, CreatedAt: b.CreatedAt, CompletedAt: b.CompletedAt}
+	if b.err != nil {
+		return task, nil, b.err
+	}
+
+	if len(b.Items) > db.MaxItems {
+		return task, nil, db.Validation("items", "item.too_many", db.MaxItems)
+	}
+
+	items := make([]*db.Item, 0, len(b.Items))
+	for _, item := range b.Items {
+		title := strings.TrimSpace(item.Title)
+		if n := utf8.RuneCountInString(title); n == 0 || n > db.MaxItemLen {
+			return task, nil, db.Validation("items", "item.title_invalid", db.MaxItemLen)
+		}
+		items = append(items, &db.Item{Title: title, Done: item.Done, Optional: item.Optional})
+	}
+
+	err := bi.check(task)
+	if err != nil {
+		return task, nil, err
+	}
+
+	if len(task.CompletedAt) > 0 && len(b.Date) > 0 {
+		task.Date = b.Date
+	}
+
+	task.ProjectID, err = bi.projectID(b.Project)
+	return task, items, err
+}
+
+// save сохраняет задачу копии и возвращает результат её импорта.
+func (bi *backupImport) save(index int, b *backupTask) (db.ImportItem, error) {
+	item := db.ImportItem{Index: index, UID: b.ID, Title: b.Title, Status: db.ImportCreated}
+
+	exists := false
+	if len(b.ID) > 0 && bi.mode != ModeDuplicate {
+		var err error
+		exists, err = db.TaskExists(bi.userID, b.ID, b.CreatedAt, strings.TrimSpace(b.Title))
+		if err != nil {
+			return item, err
+		}
+	}
+
+	if exists && bi.mode == ModeSkip {
+		item.Status, item.ID = db.ImportSkipped, b.ID
+		bi.ids[b.ID] = b.ID
+		return item, nil
+	}
+
+	task, items, err := bi.task(b)
+	item.Title = task.Title
+	if err != nil {
+		return item, err
+	}
+
+	if exists {
+		item.Status, item.ID = db.ImportUpdated, b.ID
+	}
+
+	if bi.dryRun {
+		item.Task = task
+		return item, nil
+	}
+
+	id, err := db.ImportTask(bi.userID, task, items, exists)
+	if err != nil {
+		return item, err
+	}
+
+	item.ID = strconv.FormatInt(id, 10)
+	if len(b.ID) > 0 {
+		bi.ids[b.ID] = item.ID
+	}
+	return item, nil
+}
+
+// Import загружает задачи из резервной копии пользователя.
+// Задача копии, которая совпадает с задачей пользователя (в том числе в архиве или корзине) по идентификатору и времени
+// создания, а без времени создания - по идентификатору и названию, пропускается, заменяется или создаётся заново
+// в зависимости от mode. Остальные задачи создаются с новыми идентификаторами.
+// Каждая задача проверяется отдельно: задачи с ошибками не сохраняются, ошибка возвращается в результате импорта задачи.
+// Зависимости восстанавливаются между сохранёнными задачами копии, зависимости, которые нельзя добавить, пропускаются.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задач.
+//	data - содержимое резервной копии.
+//	format - формат копии: FormatJSON или FormatCSV.
+//	mode - действие с существующими задачами: ModeSkip, ModeOverwrite или ModeDuplicate.
+//	dryRun - только проверить задачи, не сохраняя их.
+//	lang - язык сообщений об ошибках задач.
+//	check - проверка задачи копии перед сохранением.
+//
+// Возвращаемые значения:
+//
+//	*db.ImportResult - результат импорта.
+//	error - ошибка, которая могла возникнуть в ходе работы, ошибка валидации параметров или файла копии,
+//	MalformedError, если файл копии не удалось разобрать.
+func Import(userID int64, data []byte, format, mode string, dryRun bool, lang string, check CheckFunc) (*db.ImportResult, error) {
+	if mode != ModeSkip && mode != ModeOverwrite && mode != ModeDuplicate {
+		return nil, db.Validation("mode", "tasks.param_invalid", "mode")
+	}
+
+	var tasks []backupTask
+	var err error
+	switch format {
+	case FormatJSON:
+		tasks, err = parseBackupJSON(data)
+	case FormatCSV:
+		tasks, err = parseBackupCSV(data)
+	default:
+		err = db.Validation("format", "tasks.param_invalid", "format")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := db.Projects(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bi := &backupImport{userID: userID, mode: mode, dryRun: dryRun, check: check, projects: map[string]int64{},
+		ids: map[string]string{}}
+	for _, p := range projects {
+		bi.projects[p.Name] = p.ID
+	}
+
+	res := &db.ImportResult{DryRun: dryRun, Items: []db.ImportItem{}}
+	for i := range tasks {
+		item, err := bi.save(i+1, &tasks[i])
+		err = res.Add(item, err, lang)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//Каждой задаче копии соответствует один результат импорта с тем же порядковым номером.
+	for i, item := range res.Items {
+		if dryRun || item.Status != db.ImportCreated && item.Status != db.ImportUpdated {
+			continue
+		}
+
+		for _, dep := range tasks[i].DependsOn {
+			dependsOn, ok := bi.ids[dep]
+			if !ok {
+				continue
+			}
+
+			var de *db.Error
+			err = db.AddDependency(userID, item.ID, dependsOn)
+			if err != nil && !errors.As(err, &de) {
+				return nil, err
+			}
+		}
+	}
+	return res, nil
+}
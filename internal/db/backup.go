@@ -0,0 +1,264 @@
+package db
+
+// Файл содержит функции для резервного копирования задач: выборку всех задач пользователя вместе с пунктами
+// и сохранение задач, импортированных из резервной копии.
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ExportTasks возвращает все задачи пользователя, кроме задач в корзине, по возрастанию идентификатора.
+// В задачах заполняются теги и зависимости от задач, которые не перенесены в архив или корзину.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задач.
+//
+// Возвращаемые значения:
+//
+//	[]*Task - список задач, включая задачи в архиве.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func ExportTasks(userID int64) ([]*Task, error) {
+	tasks := []*Task{}
+
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE user_id = :user_id AND deleted_at = '' ORDER BY id`
+	rows, err := db.Query(query, sql.Named("user_id", userID))
+	if err != nil {
+		return tasks, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return tasks, err
+		}
+		tasks = append(tasks, t)
+	}
+
+	if err := rows.Err(); err != nil || len(tasks) == 0 {
+		return tasks, err
+	}
+
+	err = loadTags(tasks)
+	if err != nil {
+		return tasks, err
+	}
+	return tasks, loadDependencies(tasks)
+}
+
+// ExportItems возвращает пункты всех задач пользователя, кроме задач в корзине, по порядку.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задач.
+//
+// Возвращаемые значения:
+//
+//	map[string][]*Item - пункты задач по идентификаторам задач.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func ExportItems(userID int64) (map[string][]*Item, error) {
+	items := map[string][]*Item{}
+
+	query := `SELECT i.id, i.task_id, i.title, i.done, i.optional, i.position FROM task_items i
+			  JOIN scheduler s ON s.id = i.task_id WHERE s.user_id = :user_id AND s.deleted_at = ''
+			  ORDER BY i.task_id, i.position`
+	rows, err := db.Query(query, sql.Named("user_id", userID))
+	if err != nil {
+		return items, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &Item{}
+		err := rows.Scan(&item.ID, &item.TaskID, &item.Title, &item.Done, &item.Optional, &item.Position)
+		if err != nil {
+			return items, err
+		}
+		items[item.TaskID] = append(items[item.TaskID], item)
+	}
+
+	return items, rows.Err()
+}
+
+// TaskExists проверяет, есть ли у пользователя задача резервной копии, в том числе в архиве или в корзине.
+// Идентификаторы задач разных баз данных совпадают случайно, поэтому задача копии совпадает с задачей пользователя,
+// если у них одинаковые идентификатор и время создания. Если время создания в копии не указано,
+// вместо него должно совпадать название задачи.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	id - идентификатор задачи копии.
+//	createdAt - время создания задачи копии, пустая строка - время не указано.
+//	title - название задачи копии.
+//
+// Возвращаемые значения:
+//
+//	bool - true, если задача есть.
+//	error - ошибка, которая могла возникнуть в ходе работы, ошибка валидации поля created_at.
+func TaskExists(userID int64, id, createdAt, title string) (bool, error) {
+	createdAt, err := importTimestamp("created_at", createdAt)
+	if err != nil {
+		return false, err
+	}
+
+	var found string
+	query := `SELECT id FROM scheduler WHERE id = :id AND user_id = :user_id
+			  AND (datetime(created_at) = datetime(:created_at) OR :created_at = '' AND title = :title)`
+	row := db.QueryRow(query, sql.Named("id", id), sql.Named("user_id", userID), sql.Named("created_at", createdAt),
+		sql.Named("title", title))
+	err = row.Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// importTimestamp приводит время из резервной копии к формату, в котором SQLite хранит CURRENT_TIMESTAMP.
+// Принимается время в формате RFC3339 и в формате timestampFormat, пустая строка остаётся пустой.
+func importTimestamp(field, s string) (string, error) {
+	if len(s) == 0 {
+		return s, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse(timestampFormat, s)
+	}
+	if err != nil {
+		return "", Validation(field, "import.timestamp_invalid")
+	}
+	return t.UTC().Format(timestampFormat), nil
+}
+
+// ImportTask сохраняет задачу из резервной копии вместе с тегами и пунктами.
+// Время создания задачи берётся из копии, а если оно не указано - текущее. Задача с временем выполнения
+// сохраняется в архиве. При замене существующей задачи она восстанавливается из корзины, а её пункты
+// и зависимости удаляются.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	task - указатель на структуру Task, содержащую данные задачи.
+//	items - пункты задачи по порядку.
+//	overwrite - заменить задачу task.ID вместо добавления новой задачи.
+//
+// Возвращаемые значения:
+//
+//	int64 - идентификатор сохранённой задачи.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если заменяемая задача не найдена.
+func ImportTask(userID int64, task *Task, items []*Item, overwrite bool) (int64, error) {
+	var id int64
+
+	createdAt, err := importTimestamp("created_at", task.CreatedAt)
+	if err != nil {
+		return id, err
+	}
+
+	completedAt, err := importTimestamp("completed_at", task.CompletedAt)
+	if err != nil {
+		return id, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return id, err
+	}
+	defer tx.Rollback()
+
+	err = checkProject(tx, userID, task.ProjectID)
+	if err != nil {
+		return id, err
+	}
+
+	args := []any{
+		sql.Named("id", task.ID),
+		sql.Named("user_id", userID),
+		sql.Named("date", task.Date),
+		sql.Named("title", task.Title),
+		sql.Named("comment", task.Comment),
+		sql.Named("repeat", task.Repeat),
+		sql.Named("repeat_until", task.RepeatUntil),
+		sql.Named("repeat_count", task.RepeatCount),
+		sql.Named("time", task.Time),
+		sql.Named("duration", task.Duration),
+		sql.Named("timezone", task.Timezone),
+		sql.Named("priority", task.Priority),
+		sql.Named("project_id", task.ProjectID),
+		sql.Named("created_at", createdAt),
+		sql.Named("completed_at", completedAt),
+	}
+
+	if overwrite {
+		id, err = strconv.ParseInt(task.ID, 10, 64)
+		if err != nil {
+			return id, ErrTaskNotFound
+		}
+
+		query := `UPDATE scheduler SET date = :date, title = :title, comment = :comment, repeat = :repeat,
+				  repeat_until = :repeat_until, repeat_count = :repeat_count, time = :time, duration = :duration,
+				  timezone = :timezone, priority = :priority, project_id = :project_id,
+				  created_at = coalesce(nullif(:created_at, ''), created_at), completed_at = :completed_at, deleted_at = ''
+				  WHERE id = :id AND user_id = :user_id`
+		res, err := tx.Exec(query, args...)
+		if err != nil {
+			return id, err
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			return id, err
+		}
+
+		if count == 0 {
+			return id, ErrTaskNotFound
+		}
+
+		for _, query := range []string{`DELETE FROM task_items WHERE task_id = :id`, `DELETE FROM task_dependencies WHERE task_id = :id`} {
+			_, err = tx.Exec(query, sql.Named("id", task.ID))
+			if err != nil {
+				return id, err
+			}
+		}
+	} else {
+		query := `INSERT INTO scheduler (date, title, comment, repeat, repeat_until, repeat_count, time, duration, timezone,
+				  priority, project_id, user_id, created_at, completed_at)
+				  VALUES (:date, :title, :comment, :repeat, :repeat_until, :repeat_count, :time, :duration, :timezone,
+				  :priority, :project_id, :user_id, coalesce(nullif(:created_at, ''), CURRENT_TIMESTAMP), :completed_at)`
+		res, err := tx.Exec(query, args...)
+		if err != nil {
+			return id, err
+		}
+
+		id, err = res.LastInsertId()
+		if err != nil {
+			return id, err
+		}
+	}
+
+	err = setTaskTags(tx, userID, id, task.Tags)
+	if err != nil {
+		return id, err
+	}
+
+	for i, item := range items {
+		query := `INSERT INTO task_items (task_id, title, done, optional, position) VALUES (:task_id, :title, :done, :optional, :position)`
+		_, err = tx.Exec(query,
+			sql.Named("task_id", id),
+			sql.Named("title", item.Title),
+			sql.Named("done", item.Done),
+			sql.Named("optional", item.Optional),
+			sql.Named("position", i+1))
+		if err != nil {
+			return id, err
+		}
+	}
+
+	return id, tx.Commit()
+}
@@ -0,0 +1,84 @@
+package db
+
+// Файл содержит функции для синхронизации задач по протоколу CalDAV: поиск задачи по имени ресурса и по UID
+// и признак изменения коллекции задач пользователя.
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetTaskByName выполняет поиск задачи пользователя по имени ресурса CalDAV.
+// Задача, созданная клиентом CalDAV, находится по имени, под которым клиент её создал,
+// остальные задачи - по имени task-<идентификатор>.ics. Задачи в архиве и корзине не находятся.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	name - имя ресурса задачи.
+//
+// Возвращаемые значения:
+//
+//	*Task - найденная задача.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если задача не найдена.
+func GetTaskByName(userID int64, name string) (*Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE user_id = :user_id AND ` + activeTask + `
+			  AND (caldav_name = :name OR caldav_name = '' AND 'task-' || id || '.ics' = :name)`
+	row := db.QueryRow(query, sql.Named("user_id", userID), sql.Named("name", name))
+	t, err := scanTask(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, loadDetails([]*Task{t})
+}
+
+// TaskIDByUID возвращает идентификатор задачи пользователя с идентификатором компонента календаря uid,
+// в том числе задачи в архиве или в корзине. Задача, у которой UID не сохранён, находится по UID task-<идентификатор>@todo-list,
+// под которым она выдаётся в календаре.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	uid - идентификатор компонента календаря.
+//
+// Возвращаемые значения:
+//
+//	string - идентификатор задачи.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если задача не найдена.
+func TaskIDByUID(userID int64, uid string) (string, error) {
+	var id string
+	query := `SELECT id FROM scheduler WHERE user_id = :user_id
+			  AND (ical_uid = :uid OR ical_uid = '' AND 'task-' || id || '@todo-list' = :uid) ORDER BY id LIMIT 1`
+	err := db.QueryRow(query, sql.Named("user_id", userID), sql.Named("uid", uid)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrTaskNotFound
+	}
+	return id, err
+}
+
+// CollectionTag возвращает признак состояния задач пользователя, который меняется при любом изменении задач:
+// добавлении, изменении, выполнении, удалении и восстановлении.
+// Учитываются все задачи пользователя, включая задачи в архиве и корзине.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задач.
+//
+// Возвращаемые значения:
+//
+//	string - признак состояния задач.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func CollectionTag(userID int64) (string, error) {
+	var count, revisions, maxID int64
+
+	query := `SELECT count(*), coalesce(sum(revision), 0), coalesce(max(id), 0) FROM scheduler WHERE user_id = :user_id`
+	err := db.QueryRow(query, sql.Named("user_id", userID)).Scan(&count, &revisions, &maxID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d-%d", count, revisions, maxID), nil
+}
@@ -0,0 +1,228 @@
+package db
+
+// Файл содержит функции для работы с историей выполнения задач: отметка о выполнении, просмотр истории и отмена выполнения.
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Completion - запись о выполнении задачи.
+// Вместе с записью сохраняется снимок задачи, чтобы при отмене выполнения можно было восстановить её прежнее состояние.
+type Completion struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Date        string `json:"date"`      //Дата, на которую была назначена задача.
+	NextDate    string `json:"next_date"` //Дата следующего повторения, пустая строка, если задача перенесена в архив.
+	CompletedAt string `json:"completed_at"`
+	Note        string `json:"note"`
+}
+
+// CompleteTask отмечает задачу выполненной: записывает выполнение в историю и в той же транзакции
+// переносит задачу на дату следующего повторения или в архив, если nextDate пустая строка.
+// При переносе оставшееся количество повторений задачи уменьшается на единицу, если оно ограничено,
+// а отметки о выполнении пунктов задачи снимаются. Задачу с невыполненными обязательными пунктами
+// или зависящую от невыполненных задач выполнить нельзя. Задача выполняется, только если её версия
+// не изменилась с момента, когда была прочитана задача task.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	task - выполненная задача в состоянии до выполнения.
+//	nextDate - дата следующего повторения задачи или пустая строка.
+//	note - необязательная заметка о выполнении.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ошибка вида ErrConflict, если есть невыполненные
+//	обязательные пункты или задачи, от которых зависит задача, ErrTaskModified, если версия задачи изменилась.
+func CompleteTask(userID int64, task *Task, nextDate, note string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	itemsDone, open, err := doneItems(tx, task.ID)
+	if err != nil {
+		return err
+	}
+
+	if open > 0 {
+		return Conflict("task.items_open", open)
+	}
+
+	open, err = openDependencies(tx, task.ID)
+	if err != nil {
+		return err
+	}
+
+	if open > 0 {
+		return Conflict("task.blocked", open)
+	}
+
+	query := `INSERT INTO task_completions (task_id, user_id, date, next_date, note, title, comment, repeat,
+			  repeat_until, repeat_count, time, duration, timezone, priority, project_id, items_done, task_created_at)
+			  VALUES (:task_id, :user_id, :date, :next_date, :note, :title, :comment, :repeat,
+			  :repeat_until, :repeat_count, :time, :duration, :timezone, :priority, :project_id, :items_done, :created_at)`
+	_, err = tx.Exec(query,
+		sql.Named("task_id", task.ID),
+		sql.Named("user_id", userID),
+		sql.Named("date", task.Date),
+		sql.Named("next_date", nextDate),
+		sql.Named("note", note),
+		sql.Named("title", task.Title),
+		sql.Named("comment", task.Comment),
+		sql.Named("repeat", task.Repeat),
+		sql.Named("repeat_until", task.RepeatUntil),
+		sql.Named("repeat_count", task.RepeatCount),
+		sql.Named("time", task.Time),
+		sql.Named("duration", task.Duration),
+		sql.Named("timezone", task.Timezone),
+		sql.Named("priority", task.Priority),
+		sql.Named("project_id", task.ProjectID),
+		sql.Named("items_done", itemsDone),
+		sql.Named("created_at", task.CreatedAt))
+	if err != nil {
+		return err
+	}
+
+	var res sql.Result
+	if len(nextDate) == 0 {
+		query = `UPDATE scheduler SET completed_at = CURRENT_TIMESTAMP
+				 WHERE id = :id AND user_id = :user_id AND ` + activeTask + ` AND ` + revisionMatch
+		res, err = tx.Exec(query, sql.Named("id", task.ID), sql.Named("user_id", userID), sql.Named("revision", task.Revision))
+	} else {
+		query = `UPDATE scheduler SET date = :date, repeat_count = max(repeat_count - 1, 0)
+				 WHERE id = :id AND user_id = :user_id AND ` + activeTask + ` AND ` + revisionMatch
+		res, err = tx.Exec(query, sql.Named("date", nextDate), sql.Named("id", task.ID), sql.Named("user_id", userID),
+			sql.Named("revision", task.Revision))
+	}
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return missingTask(tx, userID, task.ID, task.Revision)
+	}
+
+	if len(nextDate) > 0 {
+		_, err = tx.Exec(`UPDATE task_items SET done = 0 WHERE task_id = :task_id`, sql.Named("task_id", task.ID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Completions возвращает историю выполнения задачи пользователя, начиная с последнего выполнения.
+// История доступна и для задач, которые были окончательно удалены после выполнения.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	taskID - идентификатор задачи.
+//
+// Возвращаемые значения:
+//
+//	[]*Completion - список выполнений задачи.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func Completions(userID int64, taskID string) ([]*Completion, error) {
+	completions := []*Completion{}
+
+	query := `SELECT id, task_id, date, next_date, completed_at, note FROM task_completions
+			  WHERE task_id = :task_id AND user_id = :user_id ORDER BY id DESC`
+	rows, err := db.Query(query, sql.Named("task_id", taskID), sql.Named("user_id", userID))
+	if err != nil {
+		return completions, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		c := &Completion{}
+		err := rows.Scan(&c.ID, &c.TaskID, &c.Date, &c.NextDate, &c.CompletedAt, &c.Note)
+		if err != nil {
+			return completions, err
+		}
+		completions = append(completions, c)
+	}
+
+	return completions, rows.Err()
+}
+
+// UndoCompletion отменяет последнее выполнение задачи: возвращает задаче дату, на которую она была назначена,
+// или возвращает задачу из архива, если при выполнении она была перенесена в архив.
+// Отметки о выполнении пунктов задачи восстанавливаются. Запись о выполнении удаляется из истории.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	taskID - идентификатор задачи.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func UndoCompletion(userID int64, taskID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	t := &Task{}
+	var nextDate, itemsDone string
+
+	query := `SELECT id, task_id, date, next_date, repeat_count, items_done
+			  FROM task_completions WHERE task_id = :task_id AND user_id = :user_id ORDER BY id DESC LIMIT 1`
+	row := tx.QueryRow(query, sql.Named("task_id", taskID), sql.Named("user_id", userID))
+	err = row.Scan(&id, &t.ID, &t.Date, &nextDate, &t.RepeatCount, &itemsDone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound("completion.none")
+	}
+	if err != nil {
+		return err
+	}
+
+	//Задача, перенесённая при выполнении в архив, возвращается из архива.
+	query = `UPDATE scheduler SET date = :date, repeat_count = :repeat_count, completed_at = ''
+			 WHERE id = :id AND user_id = :user_id AND deleted_at = '' AND (completed_at != '') = :archived`
+	res, err := tx.Exec(query,
+		sql.Named("date", t.Date),
+		sql.Named("repeat_count", t.RepeatCount),
+		sql.Named("id", t.ID),
+		sql.Named("user_id", userID),
+		sql.Named("archived", len(nextDate) == 0))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrTaskNotFound
+	}
+
+	query = `UPDATE task_items SET done = (',' || :items_done || ',' LIKE '%,' || id || ',%') WHERE task_id = :task_id`
+	_, err = tx.Exec(query, sql.Named("items_done", itemsDone), sql.Named("task_id", t.ID))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM task_completions WHERE id = :id`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -9,17 +9,9 @@ import (
 	_ "modernc.org/sqlite"
 )
 
-const createSchedulerTable string = `CREATE TABLE scheduler (
-									id INTEGER PRIMARY KEY AUTOINCREMENT,
-									date char(8) NOT NULL DEFAULT "",
-									title varchar NOT NULL DEFAULT "",
-									comment TEXT NOT NULL DEFAULT "",
-									repeat varchar(128) NOT NULL DEFAULT "");
-									CREATE INDEX scheduler_date on scheduler (date);`
-
 var db *sql.DB
 
-// Init инициализирует подключение к базе данных SQLite и создаёт таблицу scheduler, если база данных ещё не существует.
+// Init инициализирует подключение к базе данных SQLite и применяет к ней все неприменённые миграции схемы.
 //
 // Параметры:
 //
@@ -27,32 +19,35 @@ var db *sql.DB
 //
 // Возвращаемые значение:
 //
-//	error - ошибка, если не удалось установить подключение или выполнить sql-запрос.
+//	error - ошибка, если не удалось установить подключение или выполнить миграции.
 func Init(dbFile string) error {
-	_, err := os.Stat(dbFile)
-
-	var install bool
+	err := Open(dbFile)
 	if err != nil {
-		install = true
+		return err
 	}
 
+	_, err = MigrateUp(0)
+	return err
+}
+
+// Open устанавливает подключение к базе данных SQLite без применения миграций.
+// Если файла базы данных ещё нет, то создаются зависимые каталоги.
+//
+// Параметры:
+//
+//	dbFile - путь до файла БД.
+//
+// Возвращаемые значение:
+//
+//	error - ошибка, если не удалось установить подключение.
+func Open(dbFile string) error {
 	//Создаем зависимые каталоги.
-	err = os.MkdirAll(path.Dir(dbFile), 0744)
+	err := os.MkdirAll(path.Dir(dbFile), 0744)
 	if err != nil {
 		return err
 	}
 
 	db, err = sql.Open("sqlite", dbFile)
-	if err != nil {
-		return err
-	}
 	//Подключение не закрываем, т.к. оно должно быть открыто постоянно, пока работает сервис.
-
-	if install {
-		_, err := db.Exec(createSchedulerTable)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return err
 }
@@ -0,0 +1,145 @@
+package db
+
+// Файл содержит функции для работы с зависимостями задач: задача не может быть выполнена, пока не выполнены задачи, от которых она зависит.
+
+import (
+	"database/sql"
+)
+
+// openDependency - условие, при котором задача p, от которой зависит задача t, ещё не выполнена.
+// Разовая задача при выполнении переносится в архив, поэтому она не выполнена, пока не в архиве.
+// Повторяющаяся задача не выполнена, пока её очередное повторение назначено не позже задачи t.
+// Задача в корзине не блокирует зависящие от неё задачи.
+const openDependency = `(p.deleted_at = '' AND p.completed_at = '' AND (p.repeat = '' OR p.date <= t.date))`
+
+// blockedTask - условие выборки задач scheduler, которые зависят от невыполненных задач.
+const blockedTask = `EXISTS (SELECT 1 FROM task_dependencies d JOIN scheduler p ON p.id = d.depends_on
+	JOIN scheduler t ON t.id = d.task_id WHERE d.task_id = scheduler.id AND ` + openDependency + `)`
+
+// AddDependency добавляет зависимость задачи taskID от задачи dependsOn.
+// Повторное добавление существующей зависимости не считается ошибкой.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задач.
+//	taskID - идентификатор зависимой задачи.
+//	dependsOn - идентификатор задачи, которая должна быть выполнена раньше.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если одна из задач не найдена,
+//	ошибка валидации, если зависимость образует цикл.
+func AddDependency(userID int64, taskID, dependsOn string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range []string{taskID, dependsOn} {
+		err = checkTask(tx, userID, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	//Цикл образуется, если задача taskID достижима по зависимостям из задачи dependsOn, в том числе если это одна задача.
+	query := `WITH RECURSIVE reach(id) AS (
+				SELECT CAST(:depends_on AS INTEGER)
+				UNION SELECT d.depends_on FROM task_dependencies d JOIN reach ON d.task_id = reach.id)
+			  SELECT count(*) FROM reach WHERE id = CAST(:task_id AS INTEGER)`
+	var cycle int
+	row := tx.QueryRow(query, sql.Named("depends_on", dependsOn), sql.Named("task_id", taskID))
+	err = row.Scan(&cycle)
+	if err != nil {
+		return err
+	}
+
+	if cycle > 0 {
+		return Validation("depends_on", "dependency.cycle")
+	}
+
+	_, err = tx.Exec(`INSERT OR IGNORE INTO task_dependencies (task_id, depends_on) VALUES (:task_id, :depends_on)`,
+		sql.Named("task_id", taskID), sql.Named("depends_on", dependsOn))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteDependency удаляет зависимость задачи taskID от задачи dependsOn.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задач.
+//	taskID - идентификатор зависимой задачи.
+//	dependsOn - идентификатор задачи, от которой зависит задача taskID.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ошибка вида ErrNotFound, если зависимость не найдена.
+func DeleteDependency(userID int64, taskID, dependsOn string) error {
+	query := `DELETE FROM task_dependencies WHERE task_id = :task_id AND depends_on = :depends_on
+			  AND task_id IN (SELECT id FROM scheduler WHERE user_id = :user_id AND ` + activeTask + `)`
+	res, err := db.Exec(query, sql.Named("task_id", taskID), sql.Named("depends_on", dependsOn), sql.Named("user_id", userID))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return NotFound("dependency.not_found")
+	}
+	return nil
+}
+
+// openDependencies возвращает количество невыполненных задач, от которых зависит задача taskID.
+func openDependencies(tx *sql.Tx, taskID string) (int, error) {
+	query := `SELECT count(*) FROM task_dependencies d
+			  JOIN scheduler p ON p.id = d.depends_on JOIN scheduler t ON t.id = d.task_id
+			  WHERE d.task_id = :task_id AND ` + openDependency
+	var open int
+	row := tx.QueryRow(query, sql.Named("task_id", taskID))
+	err := row.Scan(&open)
+	return open, err
+}
+
+// loadDependencies заполняет список задач, от которых зависят задачи tasks, и признак блокировки задач.
+// Задачи в архиве и в корзине в список не попадают.
+func loadDependencies(tasks []*Task) error {
+	byID := make(map[string]*Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+
+	in, ids := taskParams(tasks)
+	query := `SELECT d.task_id, d.depends_on, ` + openDependency + ` FROM task_dependencies d
+			  JOIN scheduler p ON p.id = d.depends_on JOIN scheduler t ON t.id = d.task_id
+			  WHERE d.task_id IN (` + in + `) AND p.deleted_at = '' AND p.completed_at = '' ORDER BY d.depends_on`
+	rows, err := db.Query(query, ids...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, dependsOn string
+		var open bool
+		err := rows.Scan(&id, &dependsOn, &open)
+		if err != nil {
+			return err
+		}
+		if t, ok := byID[id]; ok {
+			t.DependsOn = append(t.DependsOn, dependsOn)
+			t.Blocked = t.Blocked || open
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,82 @@
+package db
+
+// Файл содержит типизированные ошибки пакета: объект не найден, конфликт с существующими данными, ошибка валидации
+// и изменение объекта другим запросом. Сообщения об ошибках хранятся в каталоге пакета i18n.
+// Вызывающий код различает их с помощью errors.Is (ErrNotFound, ErrConflict, ErrValidation, ErrModified) и errors.As (*Error).
+
+import (
+	"errors"
+
+	"github.com/xxxeh/todo-list/internal/i18n"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrModified   = errors.New("modified")
+)
+
+// Error - ошибка с видом (ErrNotFound, ErrConflict, ErrValidation или ErrModified), ключом сообщения в каталоге i18n
+// и, для ошибок валидации, названием поля, значение которого не прошло проверку.
+// Error() возвращает сообщение на языке по умолчанию, API переводит его на язык клиента с помощью Localize.
+type Error struct {
+	Kind  error
+	Field string
+	Key   string
+	Args  []any
+}
+
+func (e *Error) Error() string {
+	return e.Localize(i18n.Default)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Localize возвращает сообщение об ошибке на языке lang.
+func (e *Error) Localize(lang string) string {
+	return i18n.T(lang, e.Key, e.Args...)
+}
+
+// NotFound возвращает ошибку вида ErrNotFound с сообщением key из каталога i18n.
+func NotFound(key string, args ...any) error {
+	return &Error{Kind: ErrNotFound, Key: key, Args: args}
+}
+
+// Conflict возвращает ошибку вида ErrConflict с сообщением key из каталога i18n.
+func Conflict(key string, args ...any) error {
+	return &Error{Kind: ErrConflict, Key: key, Args: args}
+}
+
+// Validation возвращает ошибку вида ErrValidation для поля field с сообщением key из каталога i18n.
+func Validation(field, key string, args ...any) error {
+	return &Error{Kind: ErrValidation, Field: field, Key: key, Args: args}
+}
+
+// Modified возвращает ошибку вида ErrModified с сообщением key из каталога i18n:
+// объект изменён другим запросом после того, как клиент получил его версию.
+func Modified(key string, args ...any) error {
+	return &Error{Kind: ErrModified, Key: key, Args: args}
+}
+
+var (
+	ErrTaskNotFound    = NotFound("task.not_found")
+	ErrTaskModified    = Modified("task.modified")
+	ErrCalDAVNameTaken = Modified("caldav.name_taken")
+	ErrUserNotFound    = NotFound("user.not_found")
+	ErrSessionNotFound = NotFound("session.not_found")
+	ErrTagNotFound     = NotFound("tag.not_found")
+	ErrProjectNotFound = NotFound("project.not_found")
+	ErrItemNotFound    = NotFound("item.not_found")
+	ErrViewNotFound    = NotFound("view.not_found")
+)
+
+// isUniqueViolation проверяет, что ошибка вызвана нарушением ограничения уникальности.
+func isUniqueViolation(err error) bool {
+	var e *sqlite.Error
+	return errors.As(err, &e) && e.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
@@ -0,0 +1,171 @@
+package db
+
+// Файл содержит условия фильтра списка задач и их перевод в SQL.
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Поля условий фильтра списка задач.
+const (
+	FieldDue      string = "due"      //Дата задачи в формате 20060102.
+	FieldTag      string = "tag"      //Название тега задачи, только оператор "=".
+	FieldProject  string = "project"  //Название проекта задачи, только оператор "=".
+	FieldPriority string = "priority" //Приоритет задачи.
+	FieldRepeat   string = "repeat"   //Правило повторения задачи, операторы "=" и "!=".
+	FieldStatus   string = "status"   //Состояние задачи по зависимостям: StatusBlocked или StatusReady, только оператор "=".
+)
+
+// Операторы условий фильтра списка задач.
+const (
+	OpEq      string = "="
+	OpNe      string = "!="
+	OpLt      string = "<"
+	OpLe      string = "<="
+	OpGt      string = ">"
+	OpGe      string = ">="
+	OpBetween string = "between" //Значение от Value до To включительно.
+)
+
+// DateFunc - дата условия, которая зависит от текущей даты, например "сегодня" или "начало недели".
+// Функция получает текущую дату в часовом поясе задачи (время 00:00 UTC) и возвращает дату в формате 20060102,
+// поэтому каждая задача сравнивается со своей текущей датой.
+type DateFunc func(today time.Time) string
+
+// Condition - условие фильтра списка задач.
+type Condition struct {
+	Field string //Поле, одно из Field*.
+	Op    string //Оператор, один из Op*.
+	Value any    //Значение поля, для поля FieldDue - дата или DateFunc.
+	To    any    //Верхняя граница значения для оператора OpBetween.
+	Not   bool   //Отрицание условия.
+}
+
+// fieldOps - допустимые операторы полей условий.
+var fieldOps = map[string][]string{
+	FieldDue:      {OpEq, OpNe, OpLt, OpLe, OpGt, OpGe, OpBetween},
+	FieldPriority: {OpEq, OpNe, OpLt, OpLe, OpGt, OpGe, OpBetween},
+	FieldTag:      {OpEq},
+	FieldProject:  {OpEq},
+	FieldRepeat:   {OpEq, OpNe},
+	FieldStatus:   {OpEq},
+}
+
+// ValidOp проверяет, что оператор op допустим для поля field.
+func ValidOp(field, op string) bool {
+	for _, o := range fieldOps[field] {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// zoneDates возвращает текущую дату (время 00:00 UTC) в часовых поясах задач пользователя.
+// По ключу "" возвращается текущая дата в часовом поясе loc, в котором она определяется для задач без часового пояса.
+func zoneDates(userID int64, loc *time.Location) (map[string]time.Time, error) {
+	now := time.Now()
+	date := func(l *time.Location) time.Time {
+		y, m, d := now.In(l).Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	res := map[string]time.Time{"": date(loc)}
+	rows, err := db.Query(`SELECT DISTINCT timezone FROM scheduler WHERE user_id = :user_id AND timezone != ''`,
+		sql.Named("user_id", userID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var zone string
+		err := rows.Scan(&zone)
+		if err != nil {
+			return nil, err
+		}
+
+		//Задачи с неизвестным часовым поясом сравниваются с текущей датой по ключу "".
+		l, err := time.LoadLocation(zone)
+		if err == nil {
+			res[zone] = date(l)
+		}
+	}
+	return res, rows.Err()
+}
+
+// conditionValue возвращает выражение SQL значения условия v и его параметры с именами, начинающимися с name.
+// Значение DateFunc вычисляется для текущей даты today в каждом часовом поясе задач и выбирается по часовому поясу задачи.
+func conditionValue(name string, v any, today map[string]time.Time) (string, []any) {
+	f, ok := v.(DateFunc)
+	if !ok {
+		return ":" + name, []any{sql.Named(name, v)}
+	}
+
+	args := []any{sql.Named(name, f(today[""]))}
+	if len(today) < 2 {
+		return ":" + name, args
+	}
+
+	zones := make([]string, 0, len(today))
+	for zone := range today {
+		if len(zone) > 0 {
+			zones = append(zones, zone)
+		}
+	}
+	sort.Strings(zones)
+
+	var expr strings.Builder
+	expr.WriteString("(CASE timezone")
+	for i, zone := range zones {
+		p := fmt.Sprintf("%s_%d", name, i)
+		fmt.Fprintf(&expr, " WHEN :%s_tz THEN :%s", p, p)
+		args = append(args, sql.Named(p+"_tz", zone), sql.Named(p, f(today[zone])))
+	}
+	expr.WriteString(" ELSE :" + name + " END)")
+	return expr.String(), args
+}
+
+// sql возвращает условие WHERE для таблицы scheduler и его параметры.
+// Значения условия передаются в параметрах с именами, начинающимися с name,
+// даты DateFunc вычисляются по текущим датам today в часовых поясах задач.
+func (c Condition) sql(name string, today map[string]time.Time) (string, []any, error) {
+	if !ValidOp(c.Field, c.Op) {
+		return "", nil, fmt.Errorf("недопустимое условие %s %s", c.Field, c.Op)
+	}
+
+	param, args := conditionValue(name, c.Value, today)
+
+	var expr string
+	switch c.Field {
+	case FieldTag:
+		expr = `id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+			WHERE tags.user_id = :user_id AND tags.name = ` + param + `)`
+	case FieldProject:
+		expr = `project_id IN (SELECT id FROM projects WHERE user_id = :user_id AND name = ` + param + `)`
+	case FieldStatus:
+		expr = blockedTask
+		if c.Value == StatusReady {
+			expr = "NOT " + expr
+		}
+		args = nil
+	default:
+		column := map[string]string{FieldDue: "date", FieldPriority: "priority", FieldRepeat: "repeat"}[c.Field]
+		if c.Op == OpBetween {
+			to, toArgs := conditionValue(name+"_to", c.To, today)
+			expr = fmt.Sprintf("%s BETWEEN %s AND %s", column, param, to)
+			args = append(args, toArgs...)
+		} else {
+			expr = fmt.Sprintf("%s %s %s", column, c.Op, param)
+		}
+	}
+
+	if c.Not {
+		expr = "NOT (" + expr + ")"
+	}
+	return expr, args, nil
+}
@@ -0,0 +1,71 @@
+package db
+
+// Файл содержит результат импорта задач из файла, общий для импорта из iCalendar и загрузки резервной копии.
+
+import "errors"
+
+// Состояния задач в результате импорта.
+const (
+	ImportCreated string = "created" //Задача создана.
+	ImportUpdated string = "updated" //Существующая задача заменена задачей из файла.
+	ImportSkipped string = "skipped" //Задача пропущена, например выполненная задача календаря.
+	ImportFailed  string = "failed"  //Задачу нельзя сохранить, причина указана в поле error.
+)
+
+// ImportItem - результат импорта одной задачи из файла.
+type ImportItem struct {
+	Index  int    `json:"index"`         //Порядковый номер задачи в файле, начиная с 1.
+	UID    string `json:"uid,omitempty"` //Идентификатор задачи в файле: UID компонента календаря или id задачи резервной копии.
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`   //Идентификатор сохранённой задачи, в пробном режиме - только заменяемой.
+	Task   *Task  `json:"task,omitempty"` //Задача в том виде, в котором она будет сохранена, только в пробном режиме.
+	Error  string `json:"error,omitempty"`
+	Field  string `json:"field,omitempty"`
+}
+
+// ImportResult - результат импорта. В пробном режиме задачи не сохраняются,
+// а состояния задач и счётчики показывают, что произойдёт при импорте.
+type ImportResult struct {
+	DryRun  bool         `json:"dry_run"`
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Skipped int          `json:"skipped"`
+	Failed  int          `json:"failed"`
+	Items   []ImportItem `json:"items"`
+}
+
+// Add добавляет в результат импорта задачу item, сохранение которой завершилось ошибкой err.
+// Ошибка валидации записывается в результат задачи на языке lang, прочие ошибки возвращаются и прерывают импорт.
+//
+// Параметры:
+//
+//	item - результат импорта задачи.
+//	err - ошибка сохранения задачи или nil.
+//	lang - язык сообщения об ошибке валидации.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка err, если это не ошибка валидации.
+func (res *ImportResult) Add(item ImportItem, err error, lang string) error {
+	var de *Error
+	switch {
+	case errors.As(err, &de) && errors.Is(de, ErrValidation):
+		item.Status, item.Error, item.Field, item.Task = ImportFailed, de.Localize(lang), de.Field, nil
+	case err != nil:
+		return err
+	}
+
+	switch item.Status {
+	case ImportCreated:
+		res.Created++
+	case ImportUpdated:
+		res.Updated++
+	case ImportSkipped:
+		res.Skipped++
+	case ImportFailed:
+		res.Failed++
+	}
+	res.Items = append(res.Items, item)
+	return nil
+}
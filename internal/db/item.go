@@ -0,0 +1,341 @@
+package db
+
+// Файл содержит функции для работы с пунктами задачи (чек-листом): добавление, изменение, упорядочивание и удаление пунктов.
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// MaxItems - максимальное количество пунктов в задаче.
+const MaxItems = 100
+
+// MaxItemLen - максимальная длина названия пункта задачи в символах.
+const MaxItemLen = 256
+
+// Item - пункт задачи.
+// Задачу нельзя отметить выполненной, пока не выполнены все её обязательные пункты.
+type Item struct {
+	ID       int64  `json:"id"`
+	TaskID   string `json:"task_id"`
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Optional bool   `json:"optional"` //Необязательный пункт не мешает выполнению задачи.
+	Position int    `json:"position"` //Порядковый номер пункта в задаче, начиная с 1.
+}
+
+// Progress - прогресс выполнения пунктов задачи.
+type Progress struct {
+	Done  int `json:"done"`  //Количество выполненных пунктов.
+	Total int `json:"total"` //Количество пунктов.
+	Open  int `json:"open"`  //Количество невыполненных обязательных пунктов.
+}
+
+// Items возвращает пункты задачи пользователя по порядку.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	taskID - идентификатор задачи.
+//
+// Возвращаемые значения:
+//
+//	[]*Item - список пунктов задачи.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если задача не найдена.
+func Items(userID int64, taskID string) ([]*Item, error) {
+	items := []*Item{}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return items, err
+	}
+	defer tx.Rollback()
+
+	err = checkTask(tx, userID, taskID)
+	if err != nil {
+		return items, err
+	}
+
+	query := `SELECT id, task_id, title, done, optional, position FROM task_items WHERE task_id = :task_id ORDER BY position, id`
+	rows, err := tx.Query(query, sql.Named("task_id", taskID))
+	if err != nil {
+		return items, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &Item{}
+		err := rows.Scan(&item.ID, &item.TaskID, &item.Title, &item.Done, &item.Optional, &item.Position)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// AddItem добавляет пункт в конец списка пунктов задачи пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	item - указатель на структуру Item с идентификатором задачи и данными пункта.
+//
+// Возвращаемые значения:
+//
+//	int64 - идентификатор добавленного пункта.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если задача не найдена.
+func AddItem(userID int64, item *Item) (int64, error) {
+	var id int64
+
+	tx, err := db.Begin()
+	if err != nil {
+		return id, err
+	}
+	defer tx.Rollback()
+
+	err = checkTask(tx, userID, item.TaskID)
+	if err != nil {
+		return id, err
+	}
+
+	var count, position int
+	row := tx.QueryRow(`SELECT count(*), coalesce(max(position), 0) FROM task_items WHERE task_id = :task_id`, sql.Named("task_id", item.TaskID))
+	err = row.Scan(&count, &position)
+	if err != nil {
+		return id, err
+	}
+
+	if count >= MaxItems {
+		return id, Validation("task_id", "item.too_many", MaxItems)
+	}
+
+	query := `INSERT INTO task_items (task_id, title, done, optional, position) VALUES (:task_id, :title, :done, :optional, :position)`
+	res, err := tx.Exec(query,
+		sql.Named("task_id", item.TaskID),
+		sql.Named("title", item.Title),
+		sql.Named("done", item.Done),
+		sql.Named("optional", item.Optional),
+		sql.Named("position", position+1))
+	if err != nil {
+		return id, err
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return id, err
+	}
+
+	return id, tx.Commit()
+}
+
+// UpdateItem изменяет название, отметку о выполнении и обязательность пункта задачи пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	item - указатель на структуру Item с идентификатором и данными пункта.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrItemNotFound, если пункт не найден.
+func UpdateItem(userID int64, item *Item) error {
+	query := `UPDATE task_items SET title = :title, done = :done, optional = :optional
+			  WHERE id = :id AND task_id IN (SELECT id FROM scheduler WHERE user_id = :user_id AND ` + activeTask + `)`
+	res, err := db.Exec(query,
+		sql.Named("title", item.Title),
+		sql.Named("done", item.Done),
+		sql.Named("optional", item.Optional),
+		sql.Named("id", item.ID),
+		sql.Named("user_id", userID))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+// ToggleItem переключает отметку о выполнении пункта задачи пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	id - идентификатор пункта.
+//
+// Возвращаемые значения:
+//
+//	bool - новое значение отметки о выполнении.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrItemNotFound, если пункт не найден.
+func ToggleItem(userID int64, id int64) (bool, error) {
+	var done bool
+	query := `UPDATE task_items SET done = 1 - done
+			  WHERE id = :id AND task_id IN (SELECT id FROM scheduler WHERE user_id = :user_id AND ` + activeTask + `) RETURNING done`
+	row := db.QueryRow(query, sql.Named("id", id), sql.Named("user_id", userID))
+	err := row.Scan(&done)
+	if errors.Is(err, sql.ErrNoRows) {
+		return done, ErrItemNotFound
+	}
+	return done, err
+}
+
+// ReorderItems задаёт порядок пунктов задачи пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	taskID - идентификатор задачи.
+//	ids - идентификаторы всех пунктов задачи в новом порядке.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если задача не найдена,
+//	ошибка валидации, если ids не совпадает с набором пунктов задачи.
+func ReorderItems(userID int64, taskID string, ids []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkTask(tx, userID, taskID)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	row := tx.QueryRow(`SELECT count(*) FROM task_items WHERE task_id = :task_id`, sql.Named("task_id", taskID))
+	err = row.Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count != len(ids) {
+		return Validation("ids", "item.order_invalid")
+	}
+
+	seen := make(map[int64]bool, len(ids))
+	for i, id := range ids {
+		if seen[id] {
+			return Validation("ids", "item.order_invalid")
+		}
+		seen[id] = true
+
+		res, err := tx.Exec(`UPDATE task_items SET position = :position WHERE id = :id AND task_id = :task_id`,
+			sql.Named("position", i+1), sql.Named("id", id), sql.Named("task_id", taskID))
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return Validation("ids", "item.order_invalid")
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteItem удаляет пункт задачи пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	id - идентификатор пункта.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrItemNotFound, если пункт не найден.
+func DeleteItem(userID int64, id int64) error {
+	query := `DELETE FROM task_items WHERE id = :id AND task_id IN (SELECT id FROM scheduler WHERE user_id = :user_id AND ` + activeTask + `)`
+	res, err := db.Exec(query, sql.Named("id", id), sql.Named("user_id", userID))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+// doneItems возвращает идентификаторы выполненных пунктов задачи через запятую
+// и количество невыполненных обязательных пунктов.
+func doneItems(tx *sql.Tx, taskID string) (string, int, error) {
+	rows, err := tx.Query(`SELECT id, done, optional FROM task_items WHERE task_id = :task_id`, sql.Named("task_id", taskID))
+	if err != nil {
+		return "", 0, err
+	}
+
+	defer rows.Close()
+
+	var done []string
+	var open int
+	for rows.Next() {
+		var id int64
+		var isDone, optional bool
+		err := rows.Scan(&id, &isDone, &optional)
+		if err != nil {
+			return "", 0, err
+		}
+		if isDone {
+			done = append(done, strconv.FormatInt(id, 10))
+		} else if !optional {
+			open++
+		}
+	}
+
+	return strings.Join(done, ","), open, rows.Err()
+}
+
+// loadProgress заполняет прогресс выполнения пунктов задач tasks.
+func loadProgress(tasks []*Task) error {
+	byID := make(map[string]*Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+
+	in, ids := taskParams(tasks)
+	query := `SELECT task_id, count(*), sum(done), sum(done = 0 AND optional = 0) FROM task_items
+			  WHERE task_id IN (` + in + `) GROUP BY task_id`
+	rows, err := db.Query(query, ids...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		p := &Progress{}
+		err := rows.Scan(&id, &p.Total, &p.Done, &p.Open)
+		if err != nil {
+			return err
+		}
+		if t, ok := byID[id]; ok {
+			t.Progress = p
+		}
+	}
+
+	return rows.Err()
+}
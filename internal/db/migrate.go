@@ -0,0 +1,325 @@
+package db
+
+// Файл содержит подсистему версионных миграций схемы базы данных.
+// Миграции хранятся в каталоге migrations в виде пар файлов NNNN_имя.up.sql и NNNN_имя.down.sql
+// и встраиваются в исполняемый файл. Применённые версии записываются в таблицу schema_migrations.
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const createMigrationsTable string = `CREATE TABLE IF NOT EXISTS schema_migrations (
+									version INTEGER PRIMARY KEY,
+									name varchar NOT NULL DEFAULT "",
+									applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP);`
+
+// baselineVersion - последняя версия схемы, которую могли создать версии сервиса до появления миграций.
+const baselineVersion int = 2
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// MigrationState описывает миграцию и момент её применения.
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt string //Пустая строка, если миграция ещё не применена.
+}
+
+// loadMigrations читает встроенные файлы миграций и возвращает их, упорядоченными по возрастанию версии.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, e := range entries {
+		fname := e.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(fname, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fname, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("Недопустимое имя файла миграции %s", fname)
+		}
+
+		base := strings.TrimSuffix(fname, "."+direction+".sql")
+		num, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("Недопустимое имя файла миграции %s", fname)
+		}
+
+		version, err := strconv.Atoi(num)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("Недопустимый номер миграции %s", fname)
+		}
+
+		body, err := migrationFiles.ReadFile("migrations/" + fname)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if m.name != name {
+			return nil, fmt.Errorf("Разные имена у миграции %d: %s и %s", version, m.name, name)
+		}
+
+		if direction == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if len(m.up) == 0 {
+			return nil, fmt.Errorf("Не найден файл up для миграции %d", m.version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// appliedVersions возвращает применённые версии миграций и время их применения.
+// Перед этим создаётся таблица schema_migrations и, при необходимости, фиксируется исходная версия схемы.
+func appliedVersions() (map[int]string, error) {
+	_, err := db.Exec(createMigrationsTable)
+	if err != nil {
+		return nil, err
+	}
+
+	err = baseline()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// baseline отмечает как применённые миграции, схема которых уже существует в базе данных,
+// созданной до появления миграций. Для пустой базы данных ничего не делает.
+func baseline() error {
+	var count int
+	err := db.QueryRow(`SELECT count(*) FROM schema_migrations`).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	version := 0
+	if tableExists("scheduler") {
+		version = 1
+		if tableExists("users") {
+			version = baselineVersion
+		}
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version > version {
+			break
+		}
+		_, err := db.Exec(`INSERT INTO schema_migrations (version, name) VALUES (:version, :name)`,
+			sql.Named("version", m.version), sql.Named("name", m.name))
+		if err != nil {
+			return err
+		}
+		log.Printf("Migration %04d_%s marked as applied (existing schema)", m.version, m.name)
+	}
+
+	return nil
+}
+
+// tableExists проверяет наличие таблицы в базе данных.
+func tableExists(name string) bool {
+	var count int
+	err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = :name`, sql.Named("name", name)).Scan(&count)
+	return err == nil && count > 0
+}
+
+// MigrationStatus возвращает список всех известных миграций с отметкой о применении.
+//
+// Возвращаемые значения:
+//
+//	[]MigrationState - миграции в порядке возрастания версии.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func MigrationStatus() ([]MigrationState, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		states = append(states, MigrationState{Version: m.version, Name: m.name, AppliedAt: applied[m.version]})
+	}
+
+	return states, nil
+}
+
+// MigrateUp применяет все неприменённые миграции с версией не больше target.
+// Каждая миграция выполняется в отдельной транзакции вместе с записью в schema_migrations.
+//
+// Параметры:
+//
+//	target - версия, до которой нужно обновить схему; 0 - до последней версии.
+//
+// Возвращаемые значения:
+//
+//	int - количество применённых миграций.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func MigrateUp(target int) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := appliedVersions()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if target > 0 && m.version > target {
+			break
+		}
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		err := runMigration(m.up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (:version, :name)`,
+				sql.Named("version", m.version), sql.Named("name", m.name))
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("Миграция %04d_%s: %w", m.version, m.name, err)
+		}
+
+		log.Printf("Migration %04d_%s applied", m.version, m.name)
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrateDown откатывает последние применённые миграции.
+//
+// Параметры:
+//
+//	steps - количество откатываемых миграций.
+//
+// Возвращаемые значения:
+//
+//	int - количество откаченных миграций.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func MigrateDown(steps int) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := appliedVersions()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.version]; !ok {
+			continue
+		}
+		if len(m.down) == 0 {
+			return count, fmt.Errorf("Миграция %04d_%s не может быть откачена", m.version, m.name)
+		}
+
+		err := runMigration(m.down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = :version`, sql.Named("version", m.version))
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("Откат миграции %04d_%s: %w", m.version, m.name, err)
+		}
+
+		log.Printf("Migration %04d_%s rolled back", m.version, m.name)
+		count++
+	}
+
+	return count, nil
+}
+
+// runMigration выполняет sql-скрипт миграции и функцию record, обновляющую schema_migrations, в одной транзакции.
+func runMigration(script string, record func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(script)
+	if err != nil {
+		return err
+	}
+
+	err = record(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS scheduler (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	date char(8) NOT NULL DEFAULT "",
+	title varchar NOT NULL DEFAULT "",
+	comment TEXT NOT NULL DEFAULT "",
+	repeat varchar(128) NOT NULL DEFAULT "");
+CREATE INDEX IF NOT EXISTS scheduler_date on scheduler (date);
@@ -0,0 +1,3 @@
+DROP INDEX scheduler_user;
+ALTER TABLE scheduler DROP COLUMN user_id;
+DROP TABLE users;
@@ -0,0 +1,7 @@
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	login varchar(64) NOT NULL UNIQUE,
+	password_hash varchar NOT NULL DEFAULT "",
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP);
+ALTER TABLE scheduler ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX scheduler_user on scheduler (user_id);
@@ -0,0 +1,8 @@
+CREATE TABLE refresh_tokens (
+	id varchar(64) PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	token_hash varchar(64) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at DATETIME NOT NULL,
+	revoked_at DATETIME);
+CREATE INDEX refresh_tokens_user on refresh_tokens (user_id);
@@ -0,0 +1,2 @@
+DROP INDEX scheduler_user_created;
+ALTER TABLE scheduler DROP COLUMN created_at;
@@ -0,0 +1,3 @@
+ALTER TABLE scheduler ADD COLUMN created_at DATETIME NOT NULL DEFAULT "";
+UPDATE scheduler SET created_at = CURRENT_TIMESTAMP;
+CREATE INDEX scheduler_user_created on scheduler (user_id, created_at);
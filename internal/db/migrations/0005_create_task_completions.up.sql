@@ -0,0 +1,13 @@
+CREATE TABLE task_completions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	date char(8) NOT NULL DEFAULT "",
+	next_date char(8) NOT NULL DEFAULT "",
+	completed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	note TEXT NOT NULL DEFAULT "",
+	title varchar NOT NULL DEFAULT "",
+	comment TEXT NOT NULL DEFAULT "",
+	repeat varchar(128) NOT NULL DEFAULT "",
+	task_created_at DATETIME NOT NULL DEFAULT "");
+CREATE INDEX task_completions_task on task_completions (user_id, task_id);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN lang VARCHAR(8) NOT NULL DEFAULT "";
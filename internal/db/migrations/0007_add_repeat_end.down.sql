@@ -0,0 +1,4 @@
+ALTER TABLE task_completions DROP COLUMN repeat_count;
+ALTER TABLE task_completions DROP COLUMN repeat_until;
+ALTER TABLE scheduler DROP COLUMN repeat_count;
+ALTER TABLE scheduler DROP COLUMN repeat_until;
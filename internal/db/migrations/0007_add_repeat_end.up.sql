@@ -0,0 +1,4 @@
+ALTER TABLE scheduler ADD COLUMN repeat_until char(8) NOT NULL DEFAULT "";
+ALTER TABLE scheduler ADD COLUMN repeat_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE task_completions ADD COLUMN repeat_until char(8) NOT NULL DEFAULT "";
+ALTER TABLE task_completions ADD COLUMN repeat_count INTEGER NOT NULL DEFAULT 0;
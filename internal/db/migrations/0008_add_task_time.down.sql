@@ -0,0 +1,6 @@
+ALTER TABLE task_completions DROP COLUMN timezone;
+ALTER TABLE task_completions DROP COLUMN duration;
+ALTER TABLE task_completions DROP COLUMN time;
+ALTER TABLE scheduler DROP COLUMN timezone;
+ALTER TABLE scheduler DROP COLUMN duration;
+ALTER TABLE scheduler DROP COLUMN time;
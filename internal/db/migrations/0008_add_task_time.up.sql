@@ -0,0 +1,6 @@
+ALTER TABLE scheduler ADD COLUMN time char(5) NOT NULL DEFAULT "";
+ALTER TABLE scheduler ADD COLUMN duration INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scheduler ADD COLUMN timezone varchar(64) NOT NULL DEFAULT "";
+ALTER TABLE task_completions ADD COLUMN time char(5) NOT NULL DEFAULT "";
+ALTER TABLE task_completions ADD COLUMN duration INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE task_completions ADD COLUMN timezone varchar(64) NOT NULL DEFAULT "";
@@ -0,0 +1,8 @@
+ALTER TABLE task_completions DROP COLUMN project_id;
+ALTER TABLE task_completions DROP COLUMN priority;
+DROP INDEX scheduler_project;
+ALTER TABLE scheduler DROP COLUMN project_id;
+ALTER TABLE scheduler DROP COLUMN priority;
+DROP TABLE task_tags;
+DROP TABLE tags;
+DROP TABLE projects;
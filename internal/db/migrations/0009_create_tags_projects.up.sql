@@ -0,0 +1,21 @@
+CREATE TABLE projects (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name varchar(128) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP);
+CREATE UNIQUE INDEX projects_user_name on projects (user_id, name);
+CREATE TABLE tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name varchar(64) NOT NULL);
+CREATE UNIQUE INDEX tags_user_name on tags (user_id, name);
+CREATE TABLE task_tags (
+	task_id INTEGER NOT NULL,
+	tag_id INTEGER NOT NULL,
+	PRIMARY KEY (task_id, tag_id));
+CREATE INDEX task_tags_tag on task_tags (tag_id);
+ALTER TABLE scheduler ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scheduler ADD COLUMN project_id INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX scheduler_project on scheduler (project_id);
+ALTER TABLE task_completions ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE task_completions ADD COLUMN project_id INTEGER NOT NULL DEFAULT 0;
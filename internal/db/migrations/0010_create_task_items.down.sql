@@ -0,0 +1,2 @@
+ALTER TABLE task_completions DROP COLUMN items_done;
+DROP TABLE task_items;
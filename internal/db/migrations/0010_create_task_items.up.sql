@@ -0,0 +1,9 @@
+CREATE TABLE task_items (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	task_id INTEGER NOT NULL,
+	title varchar(256) NOT NULL DEFAULT "",
+	done INTEGER NOT NULL DEFAULT 0,
+	optional INTEGER NOT NULL DEFAULT 0,
+	position INTEGER NOT NULL DEFAULT 0);
+CREATE INDEX task_items_task on task_items (task_id, position);
+ALTER TABLE task_completions ADD COLUMN items_done TEXT NOT NULL DEFAULT "";
@@ -0,0 +1,5 @@
+CREATE TABLE task_dependencies (
+	task_id INTEGER NOT NULL,
+	depends_on INTEGER NOT NULL,
+	PRIMARY KEY (task_id, depends_on));
+CREATE INDEX task_dependencies_depends_on on task_dependencies (depends_on);
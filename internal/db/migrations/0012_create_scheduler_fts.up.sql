@@ -0,0 +1,22 @@
+CREATE VIRTUAL TABLE scheduler_fts USING fts5(
+	title,
+	comment,
+	content='scheduler',
+	content_rowid='id',
+	tokenize='unicode61 remove_diacritics 2');
+INSERT INTO scheduler_fts (rowid, title, comment)
+	SELECT id, replace(replace(title, 'ё', 'е'), 'Ё', 'Е'), replace(replace(comment, 'ё', 'е'), 'Ё', 'Е') FROM scheduler;
+CREATE TRIGGER scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+	INSERT INTO scheduler_fts (rowid, title, comment)
+		VALUES (new.id, replace(replace(new.title, 'ё', 'е'), 'Ё', 'Е'), replace(replace(new.comment, 'ё', 'е'), 'Ё', 'Е'));
+END;
+CREATE TRIGGER scheduler_fts_delete AFTER DELETE ON scheduler BEGIN
+	INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment)
+		VALUES ('delete', old.id, replace(replace(old.title, 'ё', 'е'), 'Ё', 'Е'), replace(replace(old.comment, 'ё', 'е'), 'Ё', 'Е'));
+END;
+CREATE TRIGGER scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler BEGIN
+	INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment)
+		VALUES ('delete', old.id, replace(replace(old.title, 'ё', 'е'), 'Ё', 'Е'), replace(replace(old.comment, 'ё', 'е'), 'Ё', 'Е'));
+	INSERT INTO scheduler_fts (rowid, title, comment)
+		VALUES (new.id, replace(replace(new.title, 'ё', 'е'), 'Ё', 'Е'), replace(replace(new.comment, 'ё', 'е'), 'Ё', 'Е'));
+END;
@@ -0,0 +1,13 @@
+CREATE TABLE views (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name varchar(128) NOT NULL,
+	search TEXT NOT NULL DEFAULT "",
+	tag varchar(64) NOT NULL DEFAULT "",
+	project_id INTEGER NOT NULL DEFAULT 0,
+	priority INTEGER NOT NULL DEFAULT 0,
+	status varchar(16) NOT NULL DEFAULT "",
+	sort varchar(16) NOT NULL DEFAULT "",
+	sort_order varchar(4) NOT NULL DEFAULT "",
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP);
+CREATE UNIQUE INDEX views_user_name on views (user_id, name);
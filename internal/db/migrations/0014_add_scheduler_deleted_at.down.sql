@@ -0,0 +1,7 @@
+DELETE FROM task_tags WHERE task_id IN (SELECT id FROM scheduler WHERE deleted_at != "");
+DELETE FROM task_items WHERE task_id IN (SELECT id FROM scheduler WHERE deleted_at != "");
+DELETE FROM task_dependencies WHERE task_id IN (SELECT id FROM scheduler WHERE deleted_at != "")
+	OR depends_on IN (SELECT id FROM scheduler WHERE deleted_at != "");
+DELETE FROM scheduler WHERE deleted_at != "";
+DROP INDEX scheduler_deleted;
+ALTER TABLE scheduler DROP COLUMN deleted_at;
@@ -0,0 +1,2 @@
+ALTER TABLE scheduler ADD COLUMN deleted_at DATETIME NOT NULL DEFAULT "";
+CREATE INDEX scheduler_deleted on scheduler (deleted_at);
@@ -0,0 +1,3 @@
+DELETE FROM scheduler WHERE completed_at != "";
+DROP INDEX scheduler_user_completed;
+ALTER TABLE scheduler DROP COLUMN completed_at;
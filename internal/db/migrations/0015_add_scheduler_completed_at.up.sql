@@ -0,0 +1,10 @@
+ALTER TABLE scheduler ADD COLUMN completed_at DATETIME NOT NULL DEFAULT "";
+CREATE INDEX scheduler_user_completed on scheduler (user_id, completed_at);
+INSERT INTO scheduler (id, date, title, comment, repeat, repeat_until, repeat_count, time, duration, timezone,
+	priority, project_id, user_id, created_at, completed_at)
+SELECT c.task_id, c.date, c.title, c.comment, c.repeat, c.repeat_until, c.repeat_count, c.time, c.duration, c.timezone,
+	c.priority, CASE WHEN c.project_id IN (SELECT id FROM projects) THEN c.project_id ELSE 0 END,
+	c.user_id, c.task_created_at, c.completed_at
+FROM task_completions c
+WHERE c.next_date = "" AND c.id = (SELECT max(id) FROM task_completions WHERE task_id = c.task_id)
+	AND c.task_id NOT IN (SELECT id FROM scheduler);
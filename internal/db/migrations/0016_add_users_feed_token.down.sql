@@ -0,0 +1,2 @@
+DROP INDEX users_feed_token;
+ALTER TABLE users DROP COLUMN feed_token_hash;
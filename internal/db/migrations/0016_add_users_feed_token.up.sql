@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN feed_token_hash varchar(64) NOT NULL DEFAULT "";
+CREATE INDEX users_feed_token on users (feed_token_hash);
@@ -0,0 +1,5 @@
+DROP TRIGGER tags_revision;
+DROP TRIGGER task_tags_revision_delete;
+DROP TRIGGER task_tags_revision_insert;
+DROP TRIGGER scheduler_revision;
+ALTER TABLE scheduler DROP COLUMN revision;
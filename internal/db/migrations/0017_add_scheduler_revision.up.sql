@@ -0,0 +1,14 @@
+ALTER TABLE scheduler ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+CREATE TRIGGER scheduler_revision AFTER UPDATE OF date, title, comment, repeat, repeat_until, repeat_count, time, duration,
+	timezone, priority, project_id, completed_at, deleted_at ON scheduler BEGIN
+	UPDATE scheduler SET revision = old.revision + 1 WHERE id = new.id;
+END;
+CREATE TRIGGER task_tags_revision_insert AFTER INSERT ON task_tags BEGIN
+	UPDATE scheduler SET revision = revision + 1 WHERE id = new.task_id;
+END;
+CREATE TRIGGER task_tags_revision_delete AFTER DELETE ON task_tags BEGIN
+	UPDATE scheduler SET revision = revision + 1 WHERE id = old.task_id;
+END;
+CREATE TRIGGER tags_revision AFTER UPDATE OF name ON tags BEGIN
+	UPDATE scheduler SET revision = revision + 1 WHERE id IN (SELECT task_id FROM task_tags WHERE tag_id = new.id);
+END;
@@ -0,0 +1,3 @@
+DROP INDEX scheduler_caldav_name;
+ALTER TABLE scheduler DROP COLUMN caldav_name;
+ALTER TABLE scheduler DROP COLUMN ical_uid;
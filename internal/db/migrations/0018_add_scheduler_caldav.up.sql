@@ -0,0 +1,3 @@
+ALTER TABLE scheduler ADD COLUMN ical_uid VARCHAR(255) NOT NULL DEFAULT "";
+ALTER TABLE scheduler ADD COLUMN caldav_name VARCHAR(255) NOT NULL DEFAULT "";
+CREATE INDEX scheduler_caldav_name on scheduler (user_id, caldav_name);
@@ -0,0 +1,2 @@
+DROP INDEX scheduler_caldav_name;
+CREATE INDEX scheduler_caldav_name on scheduler (user_id, caldav_name);
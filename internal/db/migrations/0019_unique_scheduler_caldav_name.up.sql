@@ -0,0 +1,4 @@
+UPDATE scheduler SET caldav_name = '' WHERE caldav_name != '' AND EXISTS (SELECT 1 FROM scheduler s
+	WHERE s.user_id = scheduler.user_id AND s.caldav_name = scheduler.caldav_name AND s.id > scheduler.id);
+DROP INDEX scheduler_caldav_name;
+CREATE UNIQUE INDEX scheduler_caldav_name on scheduler (user_id, caldav_name) WHERE caldav_name != '';
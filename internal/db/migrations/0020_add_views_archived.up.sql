@@ -0,0 +1 @@
+ALTER TABLE views ADD COLUMN archived varchar(8) NOT NULL DEFAULT "";
@@ -0,0 +1,162 @@
+package db
+
+// Файл содержит функции для работы с проектами пользователя: создание, список, переименование и удаление.
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// MaxProjectLen - максимальная длина названия проекта в символах.
+const MaxProjectLen = 128
+
+type Project struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Projects возвращает список проектов пользователя, упорядоченный по названию.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца проектов.
+//
+// Возвращаемые значения:
+//
+//	[]*Project - список проектов.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func Projects(userID int64) ([]*Project, error) {
+	projects := []*Project{}
+
+	query := `SELECT id, name, created_at FROM projects WHERE user_id = :user_id ORDER BY name, id`
+	rows, err := db.Query(query, sql.Named("user_id", userID))
+	if err != nil {
+		return projects, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		p := &Project{}
+		err := rows.Scan(&p.ID, &p.Name, &p.CreatedAt)
+		if err != nil {
+			return projects, err
+		}
+		projects = append(projects, p)
+	}
+
+	return projects, rows.Err()
+}
+
+// AddProject добавляет новый проект пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца проекта.
+//	name - название проекта, должно быть уникальным среди проектов пользователя.
+//
+// Возвращаемые значения:
+//
+//	int64 - идентификатор добавленного проекта.
+//	error - ошибка, которая могла возникнуть в ходе работы, ошибка вида ErrConflict, если название занято.
+func AddProject(userID int64, name string) (int64, error) {
+	var id int64
+	query := `INSERT INTO projects (user_id, name) VALUES (:user_id, :name)`
+	res, err := db.Exec(query, sql.Named("user_id", userID), sql.Named("name", name))
+	if isUniqueViolation(err) {
+		return id, Conflict("project.exists", name)
+	}
+	if err == nil {
+		id, err = res.LastInsertId()
+	}
+	return id, err
+}
+
+// UpdateProject переименовывает проект пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца проекта.
+//	project - указатель на структуру Project с идентификатором и новым названием проекта.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrProjectNotFound, если проект не найден,
+//	ошибка вида ErrConflict, если название занято.
+func UpdateProject(userID int64, project *Project) error {
+	query := `UPDATE projects SET name = :name WHERE id = :id AND user_id = :user_id`
+	res, err := db.Exec(query, sql.Named("name", project.Name), sql.Named("id", project.ID), sql.Named("user_id", userID))
+	if isUniqueViolation(err) {
+		return Conflict("project.exists", project.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// DeleteProject удаляет проект пользователя. Задачи проекта остаются без проекта.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца проекта.
+//	id - идентификатор проекта.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrProjectNotFound, если проект не найден.
+func DeleteProject(userID, id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM projects WHERE id = :id AND user_id = :user_id`, sql.Named("id", id), sql.Named("user_id", userID))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrProjectNotFound
+	}
+
+	_, err = tx.Exec(`UPDATE scheduler SET project_id = 0 WHERE project_id = :id AND user_id = :user_id`,
+		sql.Named("id", id), sql.Named("user_id", userID))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkProject проверяет, что проект с идентификатором id принадлежит пользователю.
+// Идентификатор 0 означает задачу без проекта.
+func checkProject(tx *sql.Tx, userID, id int64) error {
+	if id == 0 {
+		return nil
+	}
+
+	var found int64
+	row := tx.QueryRow(`SELECT id FROM projects WHERE id = :id AND user_id = :user_id`, sql.Named("id", id), sql.Named("user_id", userID))
+	err := row.Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Validation("project_id", "project.not_found")
+	}
+	return err
+}
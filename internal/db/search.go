@@ -0,0 +1,146 @@
+package db
+
+// Файл содержит функции разбора поисковых запросов по задачам.
+
+import (
+	"html"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// searchNormalizer заменяет "ё" на "е": так же текст задач нормализуется при записи в полнотекстовый индекс scheduler_fts.
+var searchNormalizer = strings.NewReplacer("ё", "е", "Ё", "Е")
+
+// Управляющие символы, которыми функция snippet FTS5 отмечает совпадения во фрагменте текста задачи.
+// Фрагмент экранируется для HTML, и только после этого отметки заменяются тегами <mark>.
+const (
+	snippetOpen  string = "\x02"
+	snippetClose string = "\x03"
+)
+
+// snippetMarks заменяет отметки совпадений во фрагменте текста задачи тегами <mark>.
+var snippetMarks = strings.NewReplacer(snippetOpen, "<mark>", snippetClose, "</mark>")
+
+// highlight возвращает фрагмент текста задачи, экранированный для HTML, с совпадениями, выделенными тегами <mark>.
+func highlight(snippet string) string {
+	return snippetMarks.Replace(html.EscapeString(snippet))
+}
+
+// SearchDate проверяет, что поисковый запрос является датой в формате 02.01.2006.
+//
+// Параметры:
+//
+//	search - поисковый запрос.
+//
+// Возвращаемые значения:
+//
+//	string - дата в формате 20060102.
+//	bool - true, если запрос является датой.
+func SearchDate(search string) (string, bool) {
+	date, err := time.Parse("02.01.2006", search)
+	if err != nil {
+		return "", false
+	}
+	return date.Format("20060102"), true
+}
+
+// ftsQuery переводит поисковый запрос пользователя в запрос FTS5.
+// Слова запроса ищутся по началу слова без учёта регистра (звёздочка в конце слова допускается, но не обязательна),
+// текст в двойных кавычках - как фраза из целых слов. Слова объединяются по И, между словами можно указать OR,
+// а слово или фраза после NOT или с минусом в начале исключаются из результата.
+// Каждое слово записывается в запрос FTS5 в кавычках, поэтому служебные символы FTS5 в запросе пользователя не действуют.
+//
+// Параметры:
+//
+//	search - поисковый запрос пользователя.
+//
+// Возвращаемые значения:
+//
+//	string - запрос FTS5.
+//	error - ошибка валидации поля search, если запрос составлен неверно.
+func ftsQuery(search string) (string, error) {
+	errInvalid := Validation("search", "tasks.search_invalid")
+
+	var include, exclude []string
+	var op string
+	var not bool
+
+	s := searchNormalizer.Replace(search)
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if len(s) == 0 {
+			break
+		}
+
+		var term string
+		if strings.HasPrefix(s, "-") {
+			not, s = true, s[1:]
+			continue
+		}
+
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				return "", errInvalid
+			}
+			term, s = `"`+strings.ReplaceAll(s[1:end+1], `"`, `""`)+`"`, s[end+2:]
+		} else {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			word := s[:end]
+			s = s[end:]
+
+			switch word {
+			case "OR", "AND":
+				if len(op) > 0 || not || len(include) == 0 {
+					return "", errInvalid
+				}
+				op = word
+				continue
+			case "NOT":
+				if not {
+					return "", errInvalid
+				}
+				not = true
+				continue
+			}
+
+			word = strings.TrimSuffix(word, "*")
+			if !strings.ContainsFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+				//Слова без букв и цифр не попадают в индекс.
+				if len(op) > 0 || not {
+					return "", errInvalid
+				}
+				continue
+			}
+
+			term = `"` + strings.ReplaceAll(word, `"`, `""`) + `"*`
+		}
+
+		switch {
+		case not:
+			if len(op) > 0 {
+				return "", errInvalid
+			}
+			exclude = append(exclude, term)
+		case op == "OR":
+			include = append(include, "OR", term)
+		default:
+			include = append(include, term)
+		}
+		op, not = "", false
+	}
+
+	if len(include) == 0 || len(op) > 0 || not {
+		return "", errInvalid
+	}
+
+	query := strings.Join(include, " ")
+	if len(exclude) > 0 {
+		query = "(" + query + ") NOT " + strings.Join(exclude, " NOT ")
+	}
+	return query, nil
+}
@@ -0,0 +1,195 @@
+package db
+
+// Файл содержит функции для работы с тегами пользователя и их связями с задачами.
+
+import (
+	"database/sql"
+)
+
+type Tag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Tags возвращает список тегов пользователя, упорядоченный по названию.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца тегов.
+//
+// Возвращаемые значения:
+//
+//	[]*Tag - список тегов.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func Tags(userID int64) ([]*Tag, error) {
+	tags := []*Tag{}
+
+	query := `SELECT id, name FROM tags WHERE user_id = :user_id ORDER BY name`
+	rows, err := db.Query(query, sql.Named("user_id", userID))
+	if err != nil {
+		return tags, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		t := &Tag{}
+		err := rows.Scan(&t.ID, &t.Name)
+		if err != nil {
+			return tags, err
+		}
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
+
+// AddTag добавляет новый тег пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца тега.
+//	name - название тега, должно быть уникальным среди тегов пользователя.
+//
+// Возвращаемые значения:
+//
+//	int64 - идентификатор добавленного тега.
+//	error - ошибка, которая могла возникнуть в ходе работы, ошибка вида ErrConflict, если название занято.
+func AddTag(userID int64, name string) (int64, error) {
+	var id int64
+	query := `INSERT INTO tags (user_id, name) VALUES (:user_id, :name)`
+	res, err := db.Exec(query, sql.Named("user_id", userID), sql.Named("name", name))
+	if isUniqueViolation(err) {
+		return id, Conflict("tag.exists", name)
+	}
+	if err == nil {
+		id, err = res.LastInsertId()
+	}
+	return id, err
+}
+
+// UpdateTag переименовывает тег пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца тега.
+//	tag - указатель на структуру Tag с идентификатором и новым названием тега.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTagNotFound, если тег не найден,
+//	ошибка вида ErrConflict, если название занято.
+func UpdateTag(userID int64, tag *Tag) error {
+	query := `UPDATE tags SET name = :name WHERE id = :id AND user_id = :user_id`
+	res, err := db.Exec(query, sql.Named("name", tag.Name), sql.Named("id", tag.ID), sql.Named("user_id", userID))
+	if isUniqueViolation(err) {
+		return Conflict("tag.exists", tag.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// DeleteTag удаляет тег пользователя и снимает его со всех задач.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца тега.
+//	id - идентификатор тега.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTagNotFound, если тег не найден.
+func DeleteTag(userID, id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM tags WHERE id = :id AND user_id = :user_id`, sql.Named("id", id), sql.Named("user_id", userID))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrTagNotFound
+	}
+
+	_, err = tx.Exec(`DELETE FROM task_tags WHERE tag_id = :id`, sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setTaskTags заменяет теги задачи на теги с названиями names. Недостающие теги пользователя создаются.
+func setTaskTags(tx *sql.Tx, userID int64, taskID any, names []string) error {
+	_, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = :task_id`, sql.Named("task_id", taskID))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		_, err = tx.Exec(`INSERT OR IGNORE INTO tags (user_id, name) VALUES (:user_id, :name)`,
+			sql.Named("user_id", userID), sql.Named("name", name))
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT OR IGNORE INTO task_tags (task_id, tag_id)
+				  SELECT :task_id, id FROM tags WHERE user_id = :user_id AND name = :name`
+		_, err = tx.Exec(query, sql.Named("task_id", taskID), sql.Named("user_id", userID), sql.Named("name", name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags заполняет теги задач tasks.
+func loadTags(tasks []*Task) error {
+	byID := make(map[string]*Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+
+	in, ids := taskParams(tasks)
+	query := `SELECT task_tags.task_id, tags.name FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+			  WHERE task_tags.task_id IN (` + in + `) ORDER BY tags.name`
+	rows, err := db.Query(query, ids...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, name string
+		err := rows.Scan(&id, &name)
+		if err != nil {
+			return err
+		}
+		if t, ok := byID[id]; ok {
+			t.Tags = append(t.Tags, name)
+		}
+	}
+
+	return rows.Err()
+}
@@ -4,67 +4,457 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Task struct {
-	ID      string `json:"id"`
-	Date    string `json:"date"`
-	Title   string `json:"title"`
-	Comment string `json:"comment"`
-	Repeat  string `json:"repeat"`
+	ID          string    `json:"id"`
+	Date        string    `json:"date"`
+	Title       string    `json:"title"`
+	Comment     string    `json:"comment"`
+	Repeat      string    `json:"repeat"`
+	RepeatUntil string    `json:"repeat_until"`           //Дата, после которой задача не повторяется, пустая строка - без ограничения.
+	RepeatCount int       `json:"repeat_count,omitempty"` //Оставшееся количество повторений, включая текущее, 0 - без ограничения.
+	Time        string    `json:"time"`                   //Время начала в формате 15:04, пустая строка - задача на весь день.
+	Duration    int       `json:"duration,omitempty"`     //Продолжительность в минутах.
+	Timezone    string    `json:"timezone"`               //Часовой пояс IANA, пустая строка - часовой пояс сервера.
+	Priority    int       `json:"priority,omitempty"`     //Приоритет от 0 (без приоритета) до MaxPriority.
+	ProjectID   int64     `json:"project_id,omitempty"`   //Идентификатор проекта, 0 - задача без проекта.
+	Tags        []string  `json:"tags,omitempty"`         //Названия тегов задачи.
+	Progress    *Progress `json:"progress,omitempty"`     //Прогресс выполнения пунктов задачи, nil - у задачи нет пунктов.
+	DependsOn   []string  `json:"depends_on,omitempty"`   //Идентификаторы задач, которые должны быть выполнены раньше.
+	Blocked     bool      `json:"blocked,omitempty"`      //Задача зависит от невыполненных задач.
+	Snippet     string    `json:"snippet,omitempty"`      //Фрагмент текста с выделенными совпадениями, только в результатах поиска.
+	Rank        float64   `json:"-"`                      //Оценка релевантности в результатах поиска, чем меньше, тем выше.
+	CreatedAt   string    `json:"created_at"`
+	CompletedAt string    `json:"completed_at,omitempty"`   //Время выполнения задачи, перенесённой в архив, пустая строка - задача не в архиве.
+	DeletedAt   string    `json:"deleted_at,omitempty"`     //Время удаления задачи в корзину, пустая строка - задача не удалена.
+	Revision    int64     `json:"version,omitempty,string"` //Версия задачи, увеличивается при каждом изменении, 0 - версия не указана.
+	ICalUID     string    `json:"-"`                        //UID задачи клиента CalDAV или импортированной задачи, пустая строка - UID по идентификатору.
+	CalDAVName  string    `json:"-"`                        //Имя ресурса задачи, созданной клиентом CalDAV, пустая строка - имя по идентификатору.
 }
 
-// AddTask добавляет новую задачу в базу данных.
+// MaxPriority - наивысший приоритет задачи.
+const MaxPriority = 3
+
+// MaxDuration - максимальная продолжительность задачи в минутах.
+const MaxDuration = 7 * 24 * 60
+
+// UnmarshalJSON разбирает задачу в формате JSON. Версия задачи version принимается и строкой, и числом,
+// версия, которая не является целым числом, - ошибка валидации поля version.
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type task Task
+	aux := struct {
+		*task
+		Version json.Number `json:"version,omitempty"`
+	}{task: (*task)(t)}
+
+	err := json.Unmarshal(data, &aux)
+	if err != nil {
+		return err
+	}
+
+	if len(aux.Version) > 0 {
+		t.Revision, err = strconv.ParseInt(aux.Version.String(), 10, 64)
+		if err != nil {
+			return Validation("version", "task.version_invalid", aux.Version.String())
+		}
+	}
+	return nil
+}
+
+// Поля сортировки списка задач.
+const (
+	SortDate      string = "date"
+	SortTitle     string = "title"
+	SortID        string = "id"
+	SortCreated   string = "created"
+	SortRank      string = "rank"      //Релевантность, только для полнотекстового поиска.
+	SortCompleted string = "completed" //Время выполнения, для задач в архиве.
+)
+
+// sortColumns сопоставляет полям сортировки столбцы таблицы scheduler.
+var sortColumns = map[string]string{
+	SortDate:      "date",
+	SortTitle:     "title",
+	SortID:        "id",
+	SortCreated:   "created_at",
+	SortRank:      "fts.score",
+	SortCompleted: "completed_at",
+}
+
+// timestampFormat - формат, в котором SQLite хранит значение CURRENT_TIMESTAMP.
+const timestampFormat string = "2006-01-02 15:04:05"
+
+// activeTask - условие выборки задач scheduler, которые не перенесены в архив и не удалены в корзину.
+const activeTask string = `completed_at = '' AND deleted_at = ''`
+
+// revisionMatch - условие выборки задачи scheduler версии :revision, при :revision = 0 версия не проверяется.
+const revisionMatch string = `(:revision = 0 OR revision = :revision)`
+
+// ftsJoin - подзапрос полнотекстового поиска по запросу FTS5 :search с оценкой релевантности и фрагментом текста задачи.
+// Совпадения в названии задачи весят вдвое больше совпадений в комментарии,
+// совпадения во фрагменте отмечаются символами snippetOpen и snippetClose.
+const ftsJoin string = `JOIN (SELECT rowid AS fts_id, bm25(scheduler_fts, 2.0, 1.0) AS score,
+	snippet(scheduler_fts, -1, char(2), char(3), '…', 12) AS snippet
+	FROM scheduler_fts WHERE scheduler_fts MATCH :search) fts ON fts.fts_id = scheduler.id`
+
+// taskColumns - столбцы таблицы scheduler в порядке, ожидаемом функцией scanTask.
+const taskColumns string = `id, date, title, comment, repeat, repeat_until, repeat_count, time, duration, timezone, priority, project_id,
+	created_at, completed_at, deleted_at, revision, ical_uid, caldav_name`
+
+// TasksQuery описывает параметры выборки списка задач.
+type TasksQuery struct {
+	Search   string //Дата в формате 02.01.2006 или полнотекстовый запрос по названию и комментарию задачи.
+	Sort     string //Поле сортировки, по умолчанию SortDate.
+	Desc     bool   //Сортировка по убыванию.
+	Limit    int    //Максимальное количество задач в результате.
+	AfterKey string //Значение поля сортировки последней задачи предыдущей страницы.
+	AfterID  int64  //Идентификатор последней задачи предыдущей страницы, 0 - первая страница.
+
+	Tag         string //Название тега, пустая строка - без фильтра по тегу.
+	ProjectID   int64  //Идентификатор проекта, 0 - без фильтра по проекту.
+	MinPriority int    //Минимальный приоритет задач, 0 - без фильтра по приоритету.
+	Status      string //Состояние задач: StatusBlocked, StatusReady или пустая строка - без фильтра по состоянию.
+
+	Archived      string    //Задачи в архиве: ArchivedInclude, ArchivedOnly или пустая строка - без задач в архиве.
+	CompletedFrom time.Time //Начало периода выполнения задач в архиве, нулевое значение - без ограничения.
+	CompletedTo   time.Time //Конец периода выполнения задач в архиве, не включая его, нулевое значение - без ограничения.
+
+	Conditions []Condition    //Дополнительные условия фильтра, все условия должны выполняться.
+	Location   *time.Location //Часовой пояс, в котором определяется текущая дата задач без часового пояса, nil - местный.
+}
+
+// Состояния задач по зависимостям.
+const (
+	StatusBlocked string = "blocked" //Задача зависит от невыполненных задач.
+	StatusReady   string = "ready"   //Все задачи, от которых зависит задача, выполнены.
+)
+
+// Выборка задач в архиве.
+const (
+	ArchivedInclude string = "include" //Задачи в архиве выбираются вместе с остальными задачами.
+	ArchivedOnly    string = "only"    //Выбираются только задачи в архиве.
+)
+
+// scanner - общий интерфейс *sql.Row и *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// fields возвращает указатели на поля задачи в порядке столбцов taskColumns.
+func (t *Task) fields() []any {
+	return []any{&t.ID, &t.Date, &t.Title, &t.Comment, &t.Repeat, &t.RepeatUntil, &t.RepeatCount,
+		&t.Time, &t.Duration, &t.Timezone, &t.Priority, &t.ProjectID, &t.CreatedAt, &t.CompletedAt, &t.DeletedAt,
+		&t.Revision, &t.ICalUID, &t.CalDAVName}
+}
+
+// scanTask читает задачу из строки результата запроса, выбирающего столбцы taskColumns.
+func scanTask(row scanner) (*Task, error) {
+	t := &Task{}
+	err := row.Scan(t.fields()...)
+	return t, err
+}
+
+// taskParams возвращает список параметров "?, ?, ..." для условия IN и идентификаторы задач tasks в качестве значений.
+func taskParams(tasks []*Task) (string, []any) {
+	ids := make([]any, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+	return "?" + strings.Repeat(", ?", len(ids)-1), ids
+}
+
+// checkTask проверяет, что задача taskID принадлежит пользователю и не перенесена в архив или корзину.
+func checkTask(tx *sql.Tx, userID int64, taskID string) error {
+	var id string
+	row := tx.QueryRow(`SELECT id FROM scheduler WHERE id = :id AND user_id = :user_id AND `+activeTask, sql.Named("id", taskID), sql.Named("user_id", userID))
+	err := row.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTaskNotFound
+	}
+	return err
+}
+
+// missingTask возвращает ошибку для задачи taskID, которую не удалось изменить с проверкой версии revision:
+// ErrTaskModified, если задача не удалена в корзину, но её версия изменилась, иначе ErrTaskNotFound.
+func missingTask(tx *sql.Tx, userID int64, taskID string, revision int64) error {
+	if revision == 0 {
+		return ErrTaskNotFound
+	}
+
+	var id string
+	row := tx.QueryRow(`SELECT id FROM scheduler WHERE id = :id AND user_id = :user_id AND deleted_at = ''`,
+		sql.Named("id", taskID), sql.Named("user_id", userID))
+	err := row.Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTaskNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrTaskModified
+}
+
+// loadDetails заполняет теги, прогресс выполнения пунктов и зависимости задач tasks.
+func loadDetails(tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	err := loadTags(tasks)
+	if err != nil {
+		return err
+	}
+
+	err = loadProgress(tasks)
+	if err != nil {
+		return err
+	}
+	return loadDependencies(tasks)
+}
+
+// SortKey возвращает значение поля сортировки sort для задачи.
+func (t *Task) SortKey(sort string) string {
+	switch sort {
+	case SortTitle:
+		return t.Title
+	case SortID:
+		return t.ID
+	case SortCreated:
+		return t.CreatedAt
+	case SortRank:
+		return strconv.FormatFloat(t.Rank, 'g', -1, 64)
+	case SortCompleted:
+		return t.CompletedAt
+	default:
+		return t.Date
+	}
+}
+
+// AddTask добавляет новую задачу пользователя в базу данных.
 // Параметры:
 //
+//	userID - идентификатор владельца задачи.
 //	task - указатель на структуру Task, содержащую данные задачи.
 //
 // Возвращаемые значения:
 //
 //	int64 - идентификатор добавленной задачи.
-//	error - ошибка, которая могла возникнуть в ходе работы.
-func AddTask(task *Task) (int64, error) {
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrCalDAVNameTaken, если имя ресурса CalDAV
+//	уже занято другой задачей пользователя, например задачей в архиве или корзине.
+func AddTask(userID int64, task *Task) (int64, error) {
 	var id int64
-	query := `INSERT INTO scheduler (date, title, comment, repeat) VALUES (:date, :title, :comment, :repeat)`
-	res, err := db.Exec(query,
+
+	tx, err := db.Begin()
+	if err != nil {
+		return id, err
+	}
+	defer tx.Rollback()
+
+	err = checkProject(tx, userID, task.ProjectID)
+	if err != nil {
+		return id, err
+	}
+
+	query := `INSERT INTO scheduler (date, title, comment, repeat, repeat_until, repeat_count, time, duration, timezone,
+			  priority, project_id, user_id, created_at, ical_uid, caldav_name)
+			  VALUES (:date, :title, :comment, :repeat, :repeat_until, :repeat_count, :time, :duration, :timezone,
+			  :priority, :project_id, :user_id, CURRENT_TIMESTAMP, :ical_uid, :caldav_name)`
+	res, err := tx.Exec(query,
+		sql.Named("user_id", userID),
 		sql.Named("date", task.Date),
 		sql.Named("title", task.Title),
 		sql.Named("comment", task.Comment),
-		sql.Named("repeat", task.Repeat))
-	if err == nil {
-		id, err = res.LastInsertId()
+		sql.Named("repeat", task.Repeat),
+		sql.Named("repeat_until", task.RepeatUntil),
+		sql.Named("repeat_count", task.RepeatCount),
+		sql.Named("time", task.Time),
+		sql.Named("duration", task.Duration),
+		sql.Named("timezone", task.Timezone),
+		sql.Named("priority", task.Priority),
+		sql.Named("project_id", task.ProjectID),
+		sql.Named("ical_uid", task.ICalUID),
+		sql.Named("caldav_name", task.CalDAVName))
+	if isUniqueViolation(err) {
+		return id, ErrCalDAVNameTaken
 	}
-	return id, err
+	if err != nil {
+		return id, err
+	}
+
+	id, err = res.LastInsertId()
+	if err != nil {
+		return id, err
+	}
+
+	err = setTaskTags(tx, userID, id, task.Tags)
+	if err != nil {
+		return id, err
+	}
+
+	return id, tx.Commit()
 }
 
-// Tasks выполняет поиск задач в базе данных.
+// Tasks выполняет поиск задач пользователя в базе данных.
+// Задачи в корзине не выбираются, задачи в архиве - только если это указано в q.Archived.
+// Поисковый запрос, который не является датой, выполняется по полнотекстовому индексу,
+// в найденных задачах заполняются оценка релевантности и фрагмент текста с совпадениями.
+// Используется постраничная выборка по ключу: следующая страница начинается после задачи,
+// указанной в AfterKey и AfterID, поэтому добавление и удаление задач не приводит к пропускам и повторам.
 //
 // Параметры:
 //
-//	search - параметр, по которому фильтруются задачи (дата или часть названия/комментария задачи). Может быть пустой строкой, если фильтрация не требуется.
-//	limit - максимальное количество задач в результате.
+//	userID - идентификатор владельца задач.
+//	q - параметры выборки: фильтр, сортировка, размер и начало страницы.
 //
 // Возвращаемые значения:
 //
 //	[]*Task - список найденных задач.
 //	error - ошибка, которая могла возникнуть в ходе работы.
-func Tasks(search string, limit int) ([]*Task, error) {
+func Tasks(userID int64, q TasksQuery) ([]*Task, error) {
 	var tasks []*Task
 
-	query := `SELECT * FROM scheduler ORDER BY date LIMIT :limit`
+	column, ok := sortColumns[q.Sort]
+	if !ok {
+		if len(q.Sort) > 0 {
+			return tasks, Validation("sort", "tasks.sort_invalid", q.Sort)
+		}
+		q.Sort, column = SortDate, sortColumns[SortDate]
+	}
+
+	search, join := q.Search, ""
+	where := []string{"user_id = :user_id", "deleted_at = ''"}
+	switch q.Archived {
+	case "":
+		where = append(where, "completed_at = ''")
+	case ArchivedOnly:
+		where = append(where, "completed_at != ''")
+	case ArchivedInclude:
+	default:
+		return tasks, Validation("archived", "tasks.param_invalid", "archived")
+	}
+	if !q.CompletedFrom.IsZero() {
+		where = append(where, "completed_at >= :completed_from")
+	}
+	if !q.CompletedTo.IsZero() {
+		where = append(where, "completed_at < :completed_to")
+	}
 	if len(search) > 0 {
-		date, err := time.Parse("02.01.2006", search)
-		if err == nil {
-			search = date.Format("20060102")
-			query = `SELECT * FROM scheduler WHERE date = :search ORDER BY date LIMIT :limit`
+		if date, ok := SearchDate(search); ok {
+			search = date
+			where = append(where, "date = :search")
 		} else {
-			query = `SELECT * FROM scheduler WHERE title LIKE '%' || :search || '%' OR comment LIKE '%' || :search || '%' ORDER BY date LIMIT :limit`
+			var err error
+			search, err = ftsQuery(search)
+			if err != nil {
+				return tasks, err
+			}
+			join = ftsJoin
+		}
+	}
+
+	if q.Sort == SortRank && len(join) == 0 {
+		return tasks, Validation("sort", "tasks.sort_invalid", q.Sort)
+	}
+
+	//Оценка релевантности сравнивается как число, а не как строка.
+	var afterKey any = q.AfterKey
+	if q.Sort == SortRank && q.AfterID > 0 {
+		rank, err := strconv.ParseFloat(q.AfterKey, 64)
+		if err != nil {
+			return tasks, Validation("cursor", "tasks.cursor_invalid")
 		}
+		afterKey = rank
 	}
 
-	rows, err := db.Query(query, sql.Named("limit", limit), sql.Named("search", search))
+	//Время создания и выполнения возвращается в задаче в формате RFC 3339, а хранится в формате timestampFormat.
+	if (q.Sort == SortCreated || q.Sort == SortCompleted) && q.AfterID > 0 {
+		key, err := time.Parse(time.RFC3339, q.AfterKey)
+		if err != nil {
+			return tasks, Validation("cursor", "tasks.cursor_invalid")
+		}
+		afterKey = key.UTC().Format(timestampFormat)
+	}
+	if len(q.Tag) > 0 {
+		where = append(where, `id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+			WHERE tags.user_id = :user_id AND tags.name = :tag)`)
+	}
+	if q.ProjectID > 0 {
+		where = append(where, "project_id = :project_id")
+	}
+	if q.MinPriority > 0 {
+		where = append(where, "priority >= :priority")
+	}
+	switch q.Status {
+	case StatusBlocked:
+		where = append(where, blockedTask)
+	case StatusReady:
+		where = append(where, "NOT "+blockedTask)
+	}
+
+	var args []any
+	var today map[string]time.Time
+	if len(q.Conditions) > 0 {
+		loc := q.Location
+		if loc == nil {
+			loc = time.Local
+		}
+
+		var err error
+		today, err = zoneDates(userID, loc)
+		if err != nil {
+			return tasks, err
+		}
+	}
+	for i, c := range q.Conditions {
+		expr, params, err := c.sql(fmt.Sprintf("cond%d", i), today)
+		if err != nil {
+			return tasks, err
+		}
+		where = append(where, expr)
+		args = append(args, params...)
+	}
+
+	dir, cmp := "ASC", ">"
+	if q.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if q.AfterID > 0 {
+		if q.Sort == SortID {
+			where = append(where, fmt.Sprintf("id %s :after_id", cmp))
+		} else {
+			where = append(where, fmt.Sprintf("(%[1]s %[2]s :after_key OR (%[1]s = :after_key AND id %[2]s :after_id))", column, cmp))
+		}
+	}
+
+	columns := taskColumns
+	if len(join) > 0 {
+		columns += ", fts.score, fts.snippet"
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM scheduler %s WHERE %s ORDER BY %s %s, id %s LIMIT :limit`,
+		columns, join, strings.Join(where, " AND "), column, dir, dir)
+
+	args = append(args,
+		sql.Named("user_id", userID),
+		sql.Named("limit", q.Limit),
+		sql.Named("search", search),
+		sql.Named("after_key", afterKey),
+		sql.Named("after_id", q.AfterID),
+		sql.Named("tag", q.Tag),
+		sql.Named("project_id", q.ProjectID),
+		sql.Named("priority", q.MinPriority),
+		sql.Named("completed_from", q.CompletedFrom.UTC().Format(timestampFormat)),
+		sql.Named("completed_to", q.CompletedTo.UTC().Format(timestampFormat)))
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return tasks, err
 	}
@@ -73,57 +463,135 @@ func Tasks(search string, limit int) ([]*Task, error) {
 
 	for rows.Next() {
 		t := &Task{}
-		err := rows.Scan(&t.ID, &t.Date, &t.Title, &t.Comment, &t.Repeat)
+		fields := t.fields()
+		if len(join) > 0 {
+			fields = append(fields, &t.Rank, &t.Snippet)
+		}
+		err := rows.Scan(fields...)
 		if err != nil {
 			return tasks, err
 		}
+		t.Snippet = highlight(t.Snippet)
 		tasks = append(tasks, t)
 	}
 
+	if err := rows.Err(); err != nil {
+		return tasks, err
+	}
+
 	if tasks == nil {
 		return []*Task{}, nil
 	}
 
-	return tasks, nil
+	return tasks, loadDetails(tasks)
 }
 
-// GetTask выполняет поиск задачи в базе данных по заданному идентификатору.
+// ScheduledTasks возвращает все задачи пользователя, кроме задач в архиве и корзине, назначенные не позже даты to, в порядке идентификаторов.
+// Используется для вычисления повторений задач: у задач, назначенных позже, повторений до этой даты нет.
 //
 // Параметры:
 //
-//	id - идентификатор задачи.
+//	userID - идентификатор владельца задач.
+//	to - дата в формате 20060102.
 //
-// Возвращеаемы значения:
+// Возвращаемые значения:
 //
-//	*Task - найденная задача.
+//	[]*Task - список найденных задач.
 //	error - ошибка, которая могла возникнуть в ходе работы.
-func GetTask(id string) (*Task, error) {
-	t := &Task{}
+func ScheduledTasks(userID int64, to string) ([]*Task, error) {
+	tasks := []*Task{}
+
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE user_id = :user_id AND ` + activeTask + ` AND date <= :to ORDER BY id`
+	rows, err := db.Query(query, sql.Named("user_id", userID), sql.Named("to", to))
+	if err != nil {
+		return tasks, err
+	}
 
-	query := `SELECT * FROM scheduler WHERE id = :id`
-	row := db.QueryRow(query, sql.Named("id", id))
-	err := row.Scan(&t.ID, &t.Date, &t.Title, &t.Comment, &t.Repeat)
+	defer rows.Close()
 
-	return t, err
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return tasks, err
+		}
+		tasks = append(tasks, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return tasks, err
+	}
+
+	return tasks, loadDetails(tasks)
 }
 
-// UpdateTask обновляет информацию о задаче в базе данных.
+// GetTask выполняет поиск задачи пользователя в базе данных по заданному идентификатору.
+// Задачи в архиве и корзине не находятся.
 //
 // Параметры:
 //
+//	userID - идентификатор владельца задачи.
+//	id - идентификатор задачи.
+//
+// Возвращеаемы значения:
+//
+//	*Task - найденная задача.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если задача не найдена.
+func GetTask(userID int64, id string) (*Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE id = :id AND user_id = :user_id AND ` + activeTask
+	row := db.QueryRow(query, sql.Named("id", id), sql.Named("user_id", userID))
+	t, err := scanTask(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, loadDetails([]*Task{t})
+}
+
+// UpdateTask обновляет информацию о задаче пользователя в базе данных.
+// Если в задаче указана версия Revision, задача обновляется, только если её версия не изменилась,
+// после обновления в Revision записывается новая версия задачи.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
 //	task - указатель на структуру Task, содержащую данные задачи.
 //
 // Возвращаемые значения:
 //
-//	error - ошибка, которая могла возникнуть в ходе работы.
-func UpdateTask(task *Task) error {
-	query := `UPDATE scheduler SET date = :date, title = :title, comment = :comment, repeat = :repeat WHERE id = :id`
-	res, err := db.Exec(query,
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskModified, если версия задачи изменилась.
+func UpdateTask(userID int64, task *Task) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkProject(tx, userID, task.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE scheduler SET date = :date, title = :title, comment = :comment, repeat = :repeat,
+			  repeat_until = :repeat_until, repeat_count = :repeat_count, time = :time, duration = :duration, timezone = :timezone,
+			  priority = :priority, project_id = :project_id
+			  WHERE id = :id AND user_id = :user_id AND ` + activeTask + ` AND ` + revisionMatch
+	res, err := tx.Exec(query,
 		sql.Named("id", task.ID),
+		sql.Named("user_id", userID),
+		sql.Named("revision", task.Revision),
 		sql.Named("date", task.Date),
 		sql.Named("title", task.Title),
 		sql.Named("comment", task.Comment),
-		sql.Named("repeat", task.Repeat))
+		sql.Named("repeat", task.Repeat),
+		sql.Named("repeat_until", task.RepeatUntil),
+		sql.Named("repeat_count", task.RepeatCount),
+		sql.Named("time", task.Time),
+		sql.Named("duration", task.Duration),
+		sql.Named("timezone", task.Timezone),
+		sql.Named("priority", task.Priority),
+		sql.Named("project_id", task.ProjectID))
 	if err != nil {
 		return err
 	}
@@ -134,54 +602,45 @@ func UpdateTask(task *Task) error {
 	}
 
 	if count == 0 {
-		return fmt.Errorf("Задача не найдена")
+		return missingTask(tx, userID, task.ID, task.Revision)
 	}
 
-	return nil
-}
-
-// DeleteTask удалаяет задачу из базы данных.
-//
-// Параметры:
-//
-//	id - идентификатор задачи.
-//
-// Возвращаемые значения:
-//
-//	error - ошибка, которая могла возникнуть в ходе работы.
-func DeleteTask(id string) error {
-	query := `DELETE FROM scheduler WHERE id = :id`
-	res, err := db.Exec(query, sql.Named("id", id))
-
+	err = setTaskTags(tx, userID, task.ID, task.Tags)
 	if err != nil {
 		return err
 	}
 
-	count, err := res.RowsAffected()
+	err = tx.QueryRow(`SELECT revision FROM scheduler WHERE id = :id`, sql.Named("id", task.ID)).Scan(&task.Revision)
 	if err != nil {
 		return err
 	}
 
-	if count == 0 {
-		return fmt.Errorf("Задача не найдена")
-	}
-
-	return nil
+	return tx.Commit()
 }
 
-// UpdateDate обновляет дату задачи.
+// DeleteTask перемещает задачу пользователя в корзину. Задача перестаёт попадать в списки задач,
+// но её теги, пункты и зависимости сохраняются до восстановления или окончательного удаления задачи.
 //
 // Параметры:
 //
-//	date - новая дата.
+//	userID - идентификатор владельца задачи.
 //	id - идентификатор задачи.
+//	revision - версия задачи, которую удаляет клиент, 0 - без проверки версии.
 //
 // Возвращаемые значения:
 //
-//	error - ошибка, которая могла возникнуть в ходе работы.
-func UpdateDate(date string, id string) error {
-	query := `UPDATE scheduler SET date = :date WHERE id = :id`
-	res, err := db.Exec(query, sql.Named("date", date), sql.Named("id", id))
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если задача не найдена,
+//	ErrTaskModified, если версия задачи изменилась.
+func DeleteTask(userID int64, id string, revision int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE scheduler SET deleted_at = CURRENT_TIMESTAMP WHERE id = :id AND user_id = :user_id AND deleted_at = ''
+			  AND ` + revisionMatch
+	res, err := tx.Exec(query, sql.Named("id", id), sql.Named("user_id", userID), sql.Named("revision", revision))
 	if err != nil {
 		return err
 	}
@@ -192,8 +651,7 @@ func UpdateDate(date string, id string) error {
 	}
 
 	if count == 0 {
-		return fmt.Errorf("Задача не найдена")
+		return missingTask(tx, userID, id, revision)
 	}
-
-	return nil
+	return tx.Commit()
 }
@@ -0,0 +1,131 @@
+package db
+
+// Файл содержит функции для работы с refresh-токенами сессий пользователей: создание, ротация и отзыв.
+// Идентификатор refresh-токена одновременно является идентификатором сессии, который записывается в access-токен.
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type RefreshToken struct {
+	ID        string
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	Revoked   bool
+}
+
+// AddRefreshToken сохраняет refresh-токен новой сессии пользователя.
+//
+// Параметры:
+//
+//	token - указатель на структуру RefreshToken, содержащую данные токена.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func AddRefreshToken(token *RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at) VALUES (:id, :user_id, :token_hash, :expires_at)`
+	_, err := db.Exec(query,
+		sql.Named("id", token.ID),
+		sql.Named("user_id", token.UserID),
+		sql.Named("token_hash", token.TokenHash),
+		sql.Named("expires_at", token.ExpiresAt.UTC().Format(time.RFC3339)))
+	return err
+}
+
+// GetRefreshToken выполняет поиск refresh-токена по идентификатору сессии.
+//
+// Параметры:
+//
+//	id - идентификатор сессии.
+//
+// Возвращаемые значения:
+//
+//	*RefreshToken - найденный токен.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrSessionNotFound, если токен не найден.
+func GetRefreshToken(id string) (*RefreshToken, error) {
+	t := &RefreshToken{}
+	var expiresAt string
+	var revokedAt sql.NullString
+
+	query := `SELECT id, user_id, token_hash, expires_at, revoked_at FROM refresh_tokens WHERE id = :id`
+	row := db.QueryRow(query, sql.Named("id", id))
+	err := row.Scan(&t.ID, &t.UserID, &t.TokenHash, &expiresAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return t, err
+	}
+
+	t.ExpiresAt, err = time.Parse(time.RFC3339, expiresAt)
+	t.Revoked = revokedAt.Valid
+	return t, err
+}
+
+// RotateRefreshToken заменяет refresh-токен сессии на новый.
+// Замена выполняется, только если сессия не отозвана и текущий хэш токена совпадает с oldHash,
+// что исключает повторное использование одного и того же токена при параллельных запросах.
+//
+// Параметры:
+//
+//	id - идентификатор сессии.
+//	oldHash - хэш предъявленного refresh-токена.
+//	newHash - хэш нового refresh-токена.
+//	expiresAt - новый срок действия сессии.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func RotateRefreshToken(id, oldHash, newHash string, expiresAt time.Time) error {
+	query := `UPDATE refresh_tokens SET token_hash = :new_hash, expires_at = :expires_at
+			  WHERE id = :id AND token_hash = :old_hash AND revoked_at IS NULL`
+	res, err := db.Exec(query,
+		sql.Named("id", id),
+		sql.Named("old_hash", oldHash),
+		sql.Named("new_hash", newHash),
+		sql.Named("expires_at", expiresAt.UTC().Format(time.RFC3339)))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeRefreshToken отзывает сессию. После отзыва не действуют ни refresh-токен, ни выданные в рамках сессии access-токены.
+//
+// Параметры:
+//
+//	id - идентификатор сессии.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func RevokeRefreshToken(id string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = :id AND revoked_at IS NULL`
+	_, err := db.Exec(query, sql.Named("id", id))
+	return err
+}
+
+// DeleteExpiredRefreshTokens удаляет из базы данных сессии, срок действия которых истёк.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func DeleteExpiredRefreshTokens() error {
+	query := `DELETE FROM refresh_tokens WHERE expires_at < :now`
+	_, err := db.Exec(query, sql.Named("now", time.Now().UTC().Format(time.RFC3339)))
+	return err
+}
@@ -0,0 +1,169 @@
+package db
+
+// Файл содержит функции для работы с корзиной: просмотр, восстановление и окончательное удаление задач.
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Trash возвращает задачи пользователя в корзине, начиная с удалённых последними.
+// Задачи, которые находятся в корзине дольше ttl и ожидают окончательного удаления, не выбираются.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задач.
+//	ttl - срок хранения задач в корзине.
+//
+// Возвращаемые значения:
+//
+//	[]*Task - список задач в корзине.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func Trash(userID int64, ttl time.Duration) ([]*Task, error) {
+	tasks := []*Task{}
+
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE user_id = :user_id AND deleted_at != ''
+			  AND deleted_at >= datetime('now', :age) ORDER BY deleted_at DESC, id DESC`
+	rows, err := db.Query(query, sql.Named("user_id", userID), sql.Named("age", trashAge(ttl)))
+	if err != nil {
+		return tasks, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return tasks, err
+		}
+		tasks = append(tasks, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return tasks, err
+	}
+
+	return tasks, loadDetails(tasks)
+}
+
+// RestoreTask восстанавливает задачу пользователя из корзины вместе с её тегами, пунктами и зависимостями.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	id - идентификатор задачи.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если задачи нет в корзине.
+func RestoreTask(userID int64, id string) error {
+	query := `UPDATE scheduler SET deleted_at = '' WHERE id = :id AND user_id = :user_id AND deleted_at != ''`
+	res, err := db.Exec(query, sql.Named("id", id), sql.Named("user_id", userID))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// PurgeTask окончательно удаляет задачу пользователя из корзины.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задачи.
+//	id - идентификатор задачи.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrTaskNotFound, если задачи нет в корзине.
+func PurgeTask(userID int64, id string) error {
+	count, err := purgeTasks(`id = :id AND user_id = :user_id AND deleted_at != ''`,
+		sql.Named("id", id), sql.Named("user_id", userID))
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// EmptyTrash окончательно удаляет все задачи пользователя из корзины.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца задач.
+//
+// Возвращаемые значения:
+//
+//	int64 - количество удалённых задач.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func EmptyTrash(userID int64) (int64, error) {
+	return purgeTasks(`user_id = :user_id AND deleted_at != ''`, sql.Named("user_id", userID))
+}
+
+// PurgeTrash окончательно удаляет задачи всех пользователей, которые находятся в корзине дольше ttl.
+//
+// Параметры:
+//
+//	ttl - срок хранения задач в корзине.
+//
+// Возвращаемые значения:
+//
+//	int64 - количество удалённых задач.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func PurgeTrash(ttl time.Duration) (int64, error) {
+	//Время удаления хранится в UTC в формате CURRENT_TIMESTAMP, поэтому граница вычисляется средствами SQLite.
+	return purgeTasks(`deleted_at != '' AND deleted_at < datetime('now', :age)`, sql.Named("age", trashAge(ttl)))
+}
+
+// trashAge возвращает модификатор функции datetime SQLite, отсчитывающий срок хранения ttl назад от текущего времени.
+func trashAge(ttl time.Duration) string {
+	return fmt.Sprintf("-%d seconds", int64(ttl.Seconds()))
+}
+
+// purgeTasks окончательно удаляет задачи, подходящие под условие cond, вместе с их тегами, пунктами и зависимостями.
+// История выполнения задач сохраняется.
+func purgeTasks(cond string, args ...any) (int64, error) {
+	var count int64
+
+	tx, err := db.Begin()
+	if err != nil {
+		return count, err
+	}
+	defer tx.Rollback()
+
+	ids := `(SELECT id FROM scheduler WHERE ` + cond + `)`
+	for _, query := range []string{
+		`DELETE FROM task_tags WHERE task_id IN ` + ids,
+		`DELETE FROM task_items WHERE task_id IN ` + ids,
+		`DELETE FROM task_dependencies WHERE task_id IN ` + ids + ` OR depends_on IN ` + ids,
+	} {
+		_, err = tx.Exec(query, args...)
+		if err != nil {
+			return count, err
+		}
+	}
+
+	res, err := tx.Exec(`DELETE FROM scheduler WHERE `+cond, args...)
+	if err != nil {
+		return count, err
+	}
+
+	count, err = res.RowsAffected()
+	if err != nil {
+		return count, err
+	}
+
+	return count, tx.Commit()
+}
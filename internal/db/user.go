@@ -0,0 +1,182 @@
+package db
+
+// Файл содержит функции для работы с пользователями в базе данных: регистрация, поиск пользователей и изменение настроек.
+
+import (
+	"database/sql"
+	"errors"
+)
+
+type User struct {
+	ID           int64  `json:"id"`
+	Login        string `json:"login"`
+	PasswordHash string `json:"-"`
+	CreatedAt    string `json:"created_at"`
+	Lang         string `json:"lang"`
+}
+
+// AddUser добавляет нового пользователя в базу данных.
+//
+// Параметры:
+//
+//	login - имя пользователя, должно быть уникальным.
+//	passwordHash - хэш пароля пользователя.
+//
+// Возвращаемые значения:
+//
+//	int64 - идентификатор добавленного пользователя.
+//	error - ошибка, которая могла возникнуть в ходе работы, ошибка вида ErrConflict, если логин занят.
+func AddUser(login, passwordHash string) (int64, error) {
+	var id int64
+	query := `INSERT INTO users (login, password_hash) VALUES (:login, :password_hash)`
+	res, err := db.Exec(query, sql.Named("login", login), sql.Named("password_hash", passwordHash))
+	if isUniqueViolation(err) {
+		return id, Conflict("user.exists")
+	}
+	if err == nil {
+		id, err = res.LastInsertId()
+	}
+	return id, err
+}
+
+// GetUserByLogin выполняет поиск пользователя в базе данных по имени.
+//
+// Параметры:
+//
+//	login - имя пользователя.
+//
+// Возвращаемые значения:
+//
+//	*User - найденный пользователь.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrUserNotFound, если пользователь не найден.
+func GetUserByLogin(login string) (*User, error) {
+	u := &User{}
+
+	query := `SELECT id, login, password_hash, created_at, lang FROM users WHERE login = :login`
+	row := db.QueryRow(query, sql.Named("login", login))
+	err := row.Scan(&u.ID, &u.Login, &u.PasswordHash, &u.CreatedAt, &u.Lang)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	return u, err
+}
+
+// GetUser выполняет поиск пользователя в базе данных по идентификатору.
+//
+// Параметры:
+//
+//	id - идентификатор пользователя.
+//
+// Возвращаемые значения:
+//
+//	*User - найденный пользователь.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrUserNotFound, если пользователь не найден.
+func GetUser(id int64) (*User, error) {
+	u := &User{}
+
+	query := `SELECT id, login, password_hash, created_at, lang FROM users WHERE id = :id`
+	row := db.QueryRow(query, sql.Named("id", id))
+	err := row.Scan(&u.ID, &u.Login, &u.PasswordHash, &u.CreatedAt, &u.Lang)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	return u, err
+}
+
+// SetUserLang сохраняет язык сообщений, выбранный пользователем.
+//
+// Параметры:
+//
+//	id - идентификатор пользователя.
+//	lang - код языка или пустая строка, если язык определяется по заголовку Accept-Language.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrUserNotFound, если пользователь не найден.
+func SetUserLang(id int64, lang string) error {
+	query := `UPDATE users SET lang = :lang WHERE id = :id`
+	res, err := db.Exec(query, sql.Named("lang", lang), sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SetFeedToken сохраняет хэш токена календаря пользователя. Прежний токен календаря перестаёт действовать.
+//
+// Параметры:
+//
+//	id - идентификатор пользователя.
+//	hash - хэш нового токена или пустая строка, если токен календаря отозван.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrUserNotFound, если пользователь не найден.
+func SetFeedToken(id int64, hash string) error {
+	query := `UPDATE users SET feed_token_hash = :hash WHERE id = :id`
+	res, err := db.Exec(query, sql.Named("hash", hash), sql.Named("id", id))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// GetUserByFeedToken выполняет поиск пользователя в базе данных по хэшу токена календаря.
+//
+// Параметры:
+//
+//	hash - хэш токена календаря.
+//
+// Возвращаемые значения:
+//
+//	*User - найденный пользователь.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrUserNotFound, если пользователь не найден.
+func GetUserByFeedToken(hash string) (*User, error) {
+	u := &User{}
+	if len(hash) == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	query := `SELECT id, login, password_hash, created_at, lang FROM users WHERE feed_token_hash = :hash`
+	row := db.QueryRow(query, sql.Named("hash", hash))
+	err := row.Scan(&u.ID, &u.Login, &u.PasswordHash, &u.CreatedAt, &u.Lang)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+
+	return u, err
+}
+
+// ClaimTasks передаёт пользователю задачи, у которых нет владельца.
+// Используется при переходе со старой базы данных, где все задачи были общими.
+//
+// Параметры:
+//
+//	userID - идентификатор нового владельца задач.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func ClaimTasks(userID int64) error {
+	query := `UPDATE scheduler SET user_id = :user_id WHERE user_id = 0`
+	_, err := db.Exec(query, sql.Named("user_id", userID))
+	return err
+}
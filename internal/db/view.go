@@ -0,0 +1,188 @@
+package db
+
+// Файл содержит функции для работы с сохранёнными представлениями списка задач: создание, чтение, изменение и удаление.
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// View - сохранённое представление списка задач: именованный набор параметров запроса GET /api/tasks.
+type View struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Search    string `json:"search"`               //Запрос на языке запросов списка задач.
+	Tag       string `json:"tag"`                  //Фильтр по названию тега.
+	ProjectID int64  `json:"project_id,omitempty"` //Фильтр по идентификатору проекта.
+	Priority  int    `json:"priority,omitempty"`   //Минимальный приоритет задач.
+	Status    string `json:"status"`               //Фильтр по зависимостям: StatusBlocked или StatusReady.
+	Sort      string `json:"sort"`                 //Поле сортировки.
+	Order     string `json:"order"`                //Направление сортировки: asc или desc.
+	Archived  string `json:"archived"`             //Задачи в архиве: ArchivedInclude, ArchivedOnly или пустая строка - без них.
+	CreatedAt string `json:"created_at"`
+}
+
+// viewColumns - столбцы таблицы views в порядке, ожидаемом функцией scanView.
+const viewColumns string = `id, name, search, tag, project_id, priority, status, sort, sort_order, archived, created_at`
+
+// scanView читает представление из строки результата запроса, выбирающего столбцы viewColumns.
+func scanView(row scanner) (*View, error) {
+	v := &View{}
+	err := row.Scan(&v.ID, &v.Name, &v.Search, &v.Tag, &v.ProjectID, &v.Priority, &v.Status, &v.Sort, &v.Order, &v.Archived,
+		&v.CreatedAt)
+	return v, err
+}
+
+// Views возвращает список сохранённых представлений пользователя, упорядоченный по названию.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца представлений.
+//
+// Возвращаемые значения:
+//
+//	[]*View - список представлений.
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func Views(userID int64) ([]*View, error) {
+	views := []*View{}
+
+	query := `SELECT ` + viewColumns + ` FROM views WHERE user_id = :user_id ORDER BY name, id`
+	rows, err := db.Query(query, sql.Named("user_id", userID))
+	if err != nil {
+		return views, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		v, err := scanView(rows)
+		if err != nil {
+			return views, err
+		}
+		views = append(views, v)
+	}
+
+	return views, rows.Err()
+}
+
+// GetView возвращает сохранённое представление пользователя по идентификатору.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца представления.
+//	id - идентификатор представления.
+//
+// Возвращаемые значения:
+//
+//	*View - найденное представление.
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrViewNotFound, если представление не найдено.
+func GetView(userID, id int64) (*View, error) {
+	query := `SELECT ` + viewColumns + ` FROM views WHERE id = :id AND user_id = :user_id`
+	row := db.QueryRow(query, sql.Named("id", id), sql.Named("user_id", userID))
+	v, err := scanView(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrViewNotFound
+	}
+	return v, err
+}
+
+// AddView сохраняет новое представление пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца представления.
+//	view - указатель на структуру View с данными представления, название должно быть уникальным среди представлений пользователя.
+//
+// Возвращаемые значения:
+//
+//	int64 - идентификатор добавленного представления.
+//	error - ошибка, которая могла возникнуть в ходе работы, ошибка вида ErrConflict, если название занято.
+func AddView(userID int64, view *View) (int64, error) {
+	var id int64
+	query := `INSERT INTO views (user_id, name, search, tag, project_id, priority, status, sort, sort_order, archived)
+			  VALUES (:user_id, :name, :search, :tag, :project_id, :priority, :status, :sort, :sort_order, :archived)`
+	res, err := db.Exec(query, append(view.params(), sql.Named("user_id", userID))...)
+	if isUniqueViolation(err) {
+		return id, Conflict("view.exists", view.Name)
+	}
+	if err == nil {
+		id, err = res.LastInsertId()
+	}
+	return id, err
+}
+
+// UpdateView изменяет сохранённое представление пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца представления.
+//	view - указатель на структуру View с идентификатором и новыми данными представления.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrViewNotFound, если представление не найдено,
+//	ошибка вида ErrConflict, если название занято.
+func UpdateView(userID int64, view *View) error {
+	query := `UPDATE views SET name = :name, search = :search, tag = :tag, project_id = :project_id, priority = :priority,
+			  status = :status, sort = :sort, sort_order = :sort_order, archived = :archived
+			  WHERE id = :id AND user_id = :user_id`
+	res, err := db.Exec(query, append(view.params(), sql.Named("id", view.ID), sql.Named("user_id", userID))...)
+	if isUniqueViolation(err) {
+		return Conflict("view.exists", view.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrViewNotFound
+	}
+	return nil
+}
+
+// DeleteView удаляет сохранённое представление пользователя.
+//
+// Параметры:
+//
+//	userID - идентификатор владельца представления.
+//	id - идентификатор представления.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы, ErrViewNotFound, если представление не найдено.
+func DeleteView(userID, id int64) error {
+	res, err := db.Exec(`DELETE FROM views WHERE id = :id AND user_id = :user_id`, sql.Named("id", id), sql.Named("user_id", userID))
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrViewNotFound
+	}
+	return nil
+}
+
+// params возвращает значения параметров запросов добавления и изменения представления.
+func (v *View) params() []any {
+	return []any{
+		sql.Named("name", v.Name),
+		sql.Named("search", v.Search),
+		sql.Named("tag", v.Tag),
+		sql.Named("project_id", v.ProjectID),
+		sql.Named("priority", v.Priority),
+		sql.Named("status", v.Status),
+		sql.Named("sort", v.Sort),
+		sql.Named("sort_order", v.Order),
+		sql.Named("archived", v.Archived),
+	}
+}
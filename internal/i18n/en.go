@@ -0,0 +1,101 @@
+package i18n
+
+// en - сообщения на английском языке.
+var en = map[string]string{
+	"request.malformed":             "Malformed request: %s",
+	"request.precondition_failed":   "The resource has been changed by another request",
+	"request.precondition_required": "The task version is missing: send the If-Match header or the version parameter",
+	"request.method_not_allowed":    "Method %s is not allowed",
+	"internal":                      "Internal server error",
+
+	"auth.required":              "Authentication required",
+	"auth.token_expired":         "Token expired",
+	"auth.invalid_credentials":   "Invalid login or password",
+	"auth.invalid_refresh_token": "Invalid refresh token",
+	"auth.invalid_feed_token":    "Invalid calendar feed token",
+
+	"user.not_found":      "User not found",
+	"user.exists":         "User already exists",
+	"user.login_invalid":  "Invalid login",
+	"user.login_reserved": "Login %s is reserved",
+	"user.password_short": "Password must be at least %d characters long",
+	"user.lang_invalid":   "Unsupported language %s",
+	"session.not_found":   "Session not found",
+
+	"task.not_found":        "Task not found",
+	"task.modified":         "The task has been changed by another request, reload it and try again",
+	"task.version_invalid":  "Task version must be an integer, got %s",
+	"task.id_required":      "Task id is required",
+	"task.title_required":   "Task title is required",
+	"task.date_invalid":     "Invalid date format",
+	"task.until_invalid":    "Invalid repeat end date format",
+	"task.count_invalid":    "Repeat count cannot be negative",
+	"task.time_invalid":     "Invalid time format, expected HH:MM",
+	"task.duration_invalid": "Duration must be between 0 and %d minutes",
+	"task.timezone_invalid": "Unknown time zone %s",
+	"task.priority_invalid": "Priority must be between 0 and %d",
+	"task.tag_invalid":      "Invalid tag name %s",
+	"task.items_open":       "Required task items are not done: %d",
+	"task.blocked":          "The task depends on open tasks: %d",
+	"completion.none":       "There are no completions to undo",
+
+	"item.not_found":     "Task item not found",
+	"item.title_invalid": "Item title must be between 1 and %d characters long",
+	"item.too_many":      "A task cannot have more than %d items",
+	"item.order_invalid": "The item list must contain every task item exactly once",
+
+	"dependency.not_found": "Dependency not found",
+	"dependency.cycle":     "The dependency creates a cycle",
+
+	"tag.not_found":        "Tag not found",
+	"tag.exists":           "Tag %s already exists",
+	"tag.name_invalid":     "Invalid tag name",
+	"project.not_found":    "Project not found",
+	"project.exists":       "Project %s already exists",
+	"project.name_invalid": "Invalid project name",
+
+	"tasks.sort_invalid":          "Invalid sort field %s",
+	"tasks.order_invalid":         "Invalid sort order %s",
+	"tasks.limit_invalid":         "Limit must be between 1 and %d",
+	"tasks.cursor_invalid":        "Invalid cursor",
+	"tasks.search_invalid":        "Invalid search query",
+	"tasks.param_invalid":         "Invalid value of parameter %s",
+	"query.quote_unclosed":        "Unclosed quote in the search query",
+	"query.value_invalid":         "Invalid value %s of field %s",
+	"query.operator_invalid":      "Operator %s cannot be used with field %s",
+	"occurrences.range_invalid":   "The end of the period is before its start",
+	"occurrences.range_too_large": "The period cannot be longer than %d days",
+
+	"view.not_found":    "View not found",
+	"view.exists":       "View %s already exists",
+	"view.name_invalid": "Invalid view name",
+
+	"repeat.rule_invalid":       "Invalid repeat rule %s",
+	"repeat.interval_missing":   "Repeat interval is missing",
+	"repeat.interval_invalid":   "Invalid repeat interval %s",
+	"repeat.interval_too_large": "Maximum allowed interval is %d (got %d)",
+	"repeat.weekday_invalid":    "Invalid day of week %s",
+	"repeat.day_invalid":        "Invalid day of month %s",
+	"repeat.month_invalid":      "Invalid month %s",
+	"repeat.no_dates":           "Repeat rule never produces a date",
+
+	"rrule.part_invalid":      "Invalid RRULE part %s",
+	"rrule.freq_invalid":      "Invalid repeat frequency FREQ %s",
+	"rrule.param_unsupported": "RRULE parameter %s is not supported",
+	"rrule.count_until":       "COUNT and UNTIL cannot be used together",
+	"rrule.not_convertible":   "Rule %s cannot be written as an RRULE",
+
+	"import.calendar_invalid":    "The file is not an iCalendar calendar",
+	"import.line_invalid":        "Invalid calendar line %s",
+	"import.version_unsupported": "Unsupported backup version %d",
+	"import.csv_header_invalid":  "The first line of the CSV file has no title column",
+	"import.timestamp_invalid":   "Invalid timestamp format",
+
+	"caldav.tasks":              "Tasks",
+	"caldav.not_found":          "Resource not found",
+	"caldav.todo_required":      "The calendar must contain exactly one VTODO task",
+	"caldav.name_invalid":       "Invalid resource name",
+	"caldav.name_reserved":      "Names like task-<number>.ics are reserved for tasks created on the server",
+	"caldav.name_taken":         "The resource name is taken by an archived or deleted task",
+	"caldav.report_unsupported": "Report %s is not supported",
+}
@@ -0,0 +1,106 @@
+// Пакет i18n содержит каталог сообщений API на поддерживаемых языках и функции выбора языка.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Поддерживаемые языки.
+const (
+	RU string = "ru"
+	EN string = "en"
+)
+
+// Default - язык сообщений, если клиент не указал поддерживаемый язык.
+const Default string = RU
+
+// catalog содержит сообщения для каждого поддерживаемого языка.
+var catalog = map[string]map[string]string{
+	RU: ru,
+	EN: en,
+}
+
+// Supported проверяет, поддерживается ли язык lang.
+func Supported(lang string) bool {
+	_, ok := catalog[lang]
+	return ok
+}
+
+// T возвращает сообщение key на языке lang, подставляя в него аргументы args по правилам fmt.Sprintf.
+// Если сообщения нет на языке lang, то используется язык по умолчанию, если нет и там - возвращается сам ключ.
+//
+// Параметры:
+//
+//	lang - язык сообщения.
+//	key - ключ сообщения в каталоге.
+//	args - аргументы сообщения.
+//
+// Возвращаемое значение:
+//
+//	string - текст сообщения.
+func T(lang, key string, args ...any) string {
+	msg, ok := catalog[lang][key]
+	if !ok {
+		msg, ok = catalog[Default][key]
+	}
+	if !ok {
+		return key
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Match выбирает поддерживаемый язык по значению заголовка Accept-Language, например "en-US,en;q=0.9,ru;q=0.8".
+// Учитываются веса q, региональная часть тега отбрасывается.
+//
+// Параметры:
+//
+//	header - значение заголовка Accept-Language.
+//
+// Возвращаемое значение:
+//
+//	string - наиболее предпочтительный для клиента поддерживаемый язык или Default.
+func Match(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !Supported(lang) {
+			continue
+		}
+
+		q := 1.0
+		if val, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+
+		if q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Default
+	}
+
+	//Стабильная сортировка сохраняет порядок языков с одинаковым весом.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].lang
+}
@@ -0,0 +1,101 @@
+package i18n
+
+// ru - сообщения на русском языке.
+var ru = map[string]string{
+	"request.malformed":             "Не удалось разобрать запрос: %s",
+	"request.precondition_failed":   "Ресурс изменён другим запросом",
+	"request.precondition_required": "Не указана версия задачи: передайте заголовок If-Match или параметр version",
+	"request.method_not_allowed":    "Метод %s не поддерживается",
+	"internal":                      "Внутренняя ошибка сервера",
+
+	"auth.required":              "Требуется авторизация",
+	"auth.token_expired":         "Истёк срок действия токена",
+	"auth.invalid_credentials":   "Неверный логин или пароль",
+	"auth.invalid_refresh_token": "Недействительный refresh-токен",
+	"auth.invalid_feed_token":    "Недействительный токен календаря",
+
+	"user.not_found":      "Пользователь не найден",
+	"user.exists":         "Пользователь уже существует",
+	"user.login_invalid":  "Недопустимый логин",
+	"user.login_reserved": "Логин %s зарезервирован",
+	"user.password_short": "Пароль должен содержать не менее %d символов",
+	"user.lang_invalid":   "Неподдерживаемый язык %s",
+	"session.not_found":   "Сессия не найдена",
+
+	"task.not_found":        "Задача не найдена",
+	"task.modified":         "Задача изменена другим запросом, обновите её и повторите изменение",
+	"task.version_invalid":  "Версия задачи должна быть целым числом, получено %s",
+	"task.id_required":      "Не указан идентификатор",
+	"task.title_required":   "Не указан заголовок задачи",
+	"task.date_invalid":     "Неверный формат даты",
+	"task.until_invalid":    "Неверный формат даты окончания повторений",
+	"task.count_invalid":    "Количество повторений не может быть отрицательным",
+	"task.time_invalid":     "Неверный формат времени, ожидается ЧЧ:ММ",
+	"task.duration_invalid": "Продолжительность должна быть от 0 до %d минут",
+	"task.timezone_invalid": "Неизвестный часовой пояс %s",
+	"task.priority_invalid": "Приоритет должен быть от 0 до %d",
+	"task.tag_invalid":      "Недопустимое название тега %s",
+	"task.items_open":       "Не выполнено обязательных пунктов задачи: %d",
+	"task.blocked":          "Задача зависит от невыполненных задач: %d",
+	"completion.none":       "Нет выполнений для отмены",
+
+	"item.not_found":     "Пункт задачи не найден",
+	"item.title_invalid": "Название пункта должно содержать от 1 до %d символов",
+	"item.too_many":      "В задаче не может быть больше %d пунктов",
+	"item.order_invalid": "Список пунктов должен содержать все пункты задачи по одному разу",
+
+	"dependency.not_found": "Зависимость не найдена",
+	"dependency.cycle":     "Зависимость образует цикл",
+
+	"tag.not_found":        "Тег не найден",
+	"tag.exists":           "Тег %s уже существует",
+	"tag.name_invalid":     "Недопустимое название тега",
+	"project.not_found":    "Проект не найден",
+	"project.exists":       "Проект %s уже существует",
+	"project.name_invalid": "Недопустимое название проекта",
+
+	"tasks.sort_invalid":          "Недопустимое поле сортировки %s",
+	"tasks.order_invalid":         "Недопустимое направление сортировки %s",
+	"tasks.limit_invalid":         "Количество задач должно быть от 1 до %d",
+	"tasks.cursor_invalid":        "Недопустимый курсор",
+	"tasks.search_invalid":        "Неверный поисковый запрос",
+	"tasks.param_invalid":         "Недопустимое значение параметра %s",
+	"query.quote_unclosed":        "Незакрытая кавычка в поисковом запросе",
+	"query.value_invalid":         "Недопустимое значение %s поля %s",
+	"query.operator_invalid":      "Оператор %s нельзя использовать с полем %s",
+	"occurrences.range_invalid":   "Конец периода раньше его начала",
+	"occurrences.range_too_large": "Период не может быть длиннее %d дней",
+
+	"view.not_found":    "Представление не найдено",
+	"view.exists":       "Представление %s уже существует",
+	"view.name_invalid": "Недопустимое название представления",
+
+	"repeat.rule_invalid":       "Недопустимый символ %s",
+	"repeat.interval_missing":   "Не указан интервал",
+	"repeat.interval_invalid":   "Недопустимый интервал %s",
+	"repeat.interval_too_large": "Превышен максимально допустимый интервал %d (%d)",
+	"repeat.weekday_invalid":    "Недопустимое значение дня недели %s",
+	"repeat.day_invalid":        "Недопустимое значение дня %s",
+	"repeat.month_invalid":      "Недопустимое значение месяца %s",
+	"repeat.no_dates":           "Правило повторения не даёт ни одной даты",
+
+	"rrule.part_invalid":      "Недопустимая часть правила RRULE %s",
+	"rrule.freq_invalid":      "Недопустимая частота повторения FREQ %s",
+	"rrule.param_unsupported": "Параметр RRULE %s не поддерживается",
+	"rrule.count_until":       "COUNT и UNTIL не могут быть указаны одновременно",
+	"rrule.not_convertible":   "Правило %s нельзя записать в формате RRULE",
+
+	"import.calendar_invalid":    "Файл не является календарём iCalendar",
+	"import.line_invalid":        "Недопустимая строка календаря %s",
+	"import.version_unsupported": "Неподдерживаемая версия резервной копии %d",
+	"import.csv_header_invalid":  "В первой строке файла CSV нет столбца title",
+	"import.timestamp_invalid":   "Неверный формат времени",
+
+	"caldav.tasks":              "Задачи",
+	"caldav.not_found":          "Ресурс не найден",
+	"caldav.todo_required":      "Календарь должен содержать ровно одну задачу VTODO",
+	"caldav.name_invalid":       "Недопустимое имя ресурса",
+	"caldav.name_reserved":      "Имена вида task-<номер>.ics зарезервированы для задач, созданных на сервере",
+	"caldav.name_taken":         "Имя ресурса занято задачей в архиве или корзине",
+	"caldav.report_unsupported": "Отчёт %s не поддерживается",
+}
@@ -9,13 +9,18 @@ import (
 )
 
 // Run запускает HTTP-сервер на порту, определённом в переменной окружения TODO_PORT.
-// Функция инициализирует API и начинает прослушивание указанного порта для обработки входящих запросов.
+// Функция инициализирует API, запускает фоновую очистку корзины и начинает прослушивание указанного порта для обработки входящих запросов.
 func Run() error {
 	port := os.Getenv("TODO_PORT")
 	if len(port) == 0 {
 		return fmt.Errorf("Environment variable TODO_PORT is not defined")
 	}
 
+	err := api.StartTrashPurge()
+	if err != nil {
+		return err
+	}
+
 	r := api.Init()
 	return http.ListenAndServe(fmt.Sprintf(":%s", port), r)
 }
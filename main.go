@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	_ "time/tzdata" //База часовых поясов встраивается в приложение на случай, если её нет в системе.
 
 	"github.com/joho/godotenv"
 	"github.com/xxxeh/todo-list/internal/db"
@@ -22,6 +23,24 @@ func main() {
 		log.Panic("Не определена переменная окружения TODO_DBFILE")
 	}
 
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "migrate":
+			err = runMigrate(dbFile, os.Args[2:])
+		case "export":
+			err = runExport(dbFile, os.Args[2:])
+		case "import":
+			err = runImport(dbFile, os.Args[2:])
+		default:
+			log.Fatalf("Неизвестная команда %s, доступны migrate, export и import", os.Args[1])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	err := db.Init(dbFile)
 	if err != nil {
 		log.Panic(err)
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/xxxeh/todo-list/internal/db"
+)
+
+const migrateUsage string = `Использование: todo-list migrate <команда>
+
+Команды:
+  status        список миграций и отметка об их применении
+  up [версия]   применить миграции до указанной версии (по умолчанию - до последней)
+  down [N]      откатить N последних миграций (по умолчанию - одну)`
+
+// runMigrate выполняет подкоманду migrate с аргументами args.
+//
+// Параметры:
+//
+//	dbFile - путь до файла БД.
+//	args - аргументы командной строки после слова migrate.
+//
+// Возвращаемые значения:
+//
+//	error - ошибка, которая могла возникнуть в ходе работы.
+func runMigrate(dbFile string, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	num := 0
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return fmt.Errorf("Недопустимое число %s\n%s", args[1], migrateUsage)
+		}
+		num = n
+	}
+
+	err := db.Open(dbFile)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "status":
+		states, err := db.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			applied := "не применена"
+			if len(s.AppliedAt) > 0 {
+				applied = "применена " + s.AppliedAt
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+
+	case "up":
+		count, err := db.MigrateUp(num)
+		fmt.Printf("Применено миграций: %d\n", count)
+		return err
+
+	case "down":
+		if num == 0 {
+			num = 1
+		}
+		count, err := db.MigrateDown(num)
+		fmt.Printf("Откачено миграций: %d\n", count)
+		return err
+
+	default:
+		return fmt.Errorf("Неизвестная команда %s\n%s", args[0], migrateUsage)
+	}
+
+	return nil
+}
@@ -33,6 +33,10 @@ func requestJSON(apipath string, values map[string]any, method string) ([]byte,
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	//Тесты, не проверяющие версии задач, изменяют задачи независимо от версии.
+	if method != http.MethodGet {
+		req.Header.Set("If-Match", "*")
+	}
 
 	client := &http.Client{}
 	if len(Token) > 0 {
@@ -13,6 +13,18 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// TestMain получает токен авторизации перед запуском тестов, если он не указан в settings.go.
+func TestMain(m *testing.M) {
+	if len(Token) == 0 {
+		token, err := signIn(Login, Password)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Не удалось получить токен:", err)
+		}
+		Token = token
+	}
+	os.Exit(m.Run())
+}
+
 func getURL(path string) string {
 	port := Port
 	envPort := os.Getenv("TODO_PORT")
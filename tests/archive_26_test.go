@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// archiveIDs возвращает идентификаторы задач страницы архива, найденных с параметрами запроса query, и курсор следующей страницы.
+func archiveIDs(t *testing.T, query string) ([]string, string) {
+	m, err := postJSON("api/archive?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	ids := []string{}
+	tasks, _ := m["tasks"].([]any)
+	for _, v := range tasks {
+		task := v.(map[string]any)
+		assert.NotEmpty(t, task["completed_at"])
+		ids = append(ids, fmt.Sprint(task["id"]))
+	}
+	next, _ := m["next"].(string)
+	return ids, next
+}
+
+func TestArchive(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	login := fmt.Sprintf("archive%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		now := time.Now()
+		today := now.Format(`20060102`)
+		var ids []string
+		for _, title := range []string{"Оплатить счёт", "Сдать отчёт", "Позвонить маме"} {
+			ret, err := postJSON("api/task", map[string]any{"title": title, "date": today}, http.MethodPost)
+			assert.NoError(t, err)
+			ids = append(ids, fmt.Sprint(ret["id"]))
+		}
+		bill, report, call := ids[0], ids[1], ids[2]
+
+		completeTask(t, bill)
+		completeTask(t, report)
+		notFoundTask(t, bill)
+
+		assert.Equal(t, []string{call}, taskIDs(t, ""))
+		assert.Equal(t, []string{bill, report, call}, taskIDs(t, "archived=include&sort=id"))
+		assert.Equal(t, []string{bill, report}, taskIDs(t, "archived=only&sort=id"))
+
+		//По умолчанию архив сортируется по времени выполнения, начиная с последних.
+		got, _ := archiveIDs(t, "")
+		assert.Equal(t, []string{report, bill}, got)
+
+		//Постраничная выборка по времени выполнения.
+		got, next := archiveIDs(t, "limit=1")
+		assert.Equal(t, []string{report}, got)
+		assert.NotEmpty(t, next)
+		got, next = archiveIDs(t, "limit=1&cursor="+url.QueryEscape(next))
+		assert.Equal(t, []string{bill}, got)
+		assert.Empty(t, next)
+
+		//Фильтр по периоду выполнения.
+		_, err = db.Exec(`UPDATE scheduler SET completed_at = datetime('now', '-10 days') WHERE id = ?`, bill)
+		assert.NoError(t, err)
+		weekAgo := now.AddDate(0, 0, -7).Format(`20060102`)
+		got, _ = archiveIDs(t, "from="+weekAgo+"&to="+today)
+		assert.Equal(t, []string{report}, got)
+		got, _ = archiveIDs(t, "to="+weekAgo)
+		assert.Equal(t, []string{bill}, got)
+		got, _ = archiveIDs(t, "search=Оплатить")
+		assert.Equal(t, []string{bill}, got)
+
+		status, m := requestStatus(t, "api/archive?from=2026-01-01", "", http.MethodGet)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "from", m["field"])
+		status, _ = requestStatus(t, "api/tasks?archived=all", "", http.MethodGet)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+		//Задачу в архиве нельзя выполнить повторно или изменить.
+		status, _ = requestStatus(t, "api/task/done?id="+report, "", http.MethodPost)
+		assert.Equal(t, http.StatusNotFound, status)
+		status, _ = requestStatus(t, "api/task", fmt.Sprintf(`{"id": %q, "title": "Новое название", "date": %q}`, report, today),
+			http.MethodPut)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		//Отмена выполнения возвращает задачу из архива.
+		_, err = postJSON("api/task/undo?id="+report, nil, http.MethodPost)
+		assert.NoError(t, err)
+		m, err = postJSON("api/task?id="+report, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Nil(t, m["completed_at"])
+		got, _ = archiveIDs(t, "")
+		assert.Equal(t, []string{bill}, got)
+
+		//Задачу из архива можно удалить в корзину и восстановить обратно в архив.
+		_, err = postJSON("api/task?id="+bill, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		got, _ = archiveIDs(t, "")
+		assert.Empty(t, got)
+		assert.Equal(t, []string{bill}, trashIDs(t))
+		_, err = postJSON("api/trash/restore?id="+bill, nil, http.MethodPost)
+		assert.NoError(t, err)
+		got, _ = archiveIDs(t, "")
+		assert.Equal(t, []string{bill}, got)
+	})
+}
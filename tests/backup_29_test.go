@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exportTasks выгружает резервную копию задач пользователя с параметрами запроса query и возвращает её содержимое.
+func exportTasks(t *testing.T, query string) string {
+	req, err := http.NewRequest(http.MethodGet, getURL("api/export?"+query), nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
+	return string(body)
+}
+
+// importTasks загружает резервную копию backup с параметрами запроса query и проверяет счётчики результата импорта:
+// количество созданных, заменённых, пропущенных задач и задач с ошибками.
+func importTasks(t *testing.T, query, backup string, counts ...float64) []any {
+	status, m := requestStatus(t, "api/import?"+query, backup, http.MethodPost)
+	if !assert.Equal(t, http.StatusOK, status, m) {
+		return nil
+	}
+	assert.Equal(t, counts, []float64{m["created"].(float64), m["updated"].(float64), m["skipped"].(float64),
+		m["failed"].(float64)})
+	return m["items"].([]any)
+}
+
+func TestBackup(t *testing.T) {
+	today := time.Now().Format(`20060102`)
+	var backup string
+	var ids []string
+
+	login := fmt.Sprintf("backup%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		ret, err := postJSON("api/project", map[string]any{"name": "Дом"}, http.MethodPost)
+		assert.NoError(t, err)
+		project := ret["id"]
+
+		for _, task := range []map[string]any{
+			{"title": "Уборка", "date": today, "repeat": "d 7", "tags": []string{"быт"}, "project_id": project, "priority": 2},
+			{"title": "Пригласить гостей", "date": today, "comment": "После уборки"},
+			{"title": "Оплатить счёт", "date": today},
+			{"title": "Черновик", "date": today},
+		} {
+			ret, err := postJSON("api/task", task, http.MethodPost)
+			assert.NoError(t, err)
+			ids = append(ids, fmt.Sprint(ret["id"]))
+		}
+		cleaning, guests, bill, draft := ids[0], ids[1], ids[2], ids[3]
+
+		for _, title := range []string{"Пропылесосить", "Помыть полы"} {
+			_, err = postJSON("api/task/item", map[string]any{"task_id": cleaning, "title": title}, http.MethodPost)
+			assert.NoError(t, err)
+		}
+		addDependency(t, guests, cleaning)
+		completeTask(t, bill)
+		_, err = postJSON("api/task?id="+draft, nil, http.MethodDelete)
+		assert.NoError(t, err)
+
+		//В копию входят задачи в архиве, но не задачи в корзине.
+		backup = exportTasks(t, "")
+		var file struct {
+			Version int              `json:"version"`
+			Tasks   []map[string]any `json:"tasks"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(backup), &file))
+		assert.Equal(t, 1, file.Version)
+		if !assert.Len(t, file.Tasks, 3) {
+			return
+		}
+		assert.Equal(t, []any{cleaning, guests, bill}, []any{file.Tasks[0]["id"], file.Tasks[1]["id"], file.Tasks[2]["id"]})
+		assert.Equal(t, "Дом", file.Tasks[0]["project"])
+		assert.Equal(t, []any{"быт"}, file.Tasks[0]["tags"])
+		assert.Len(t, file.Tasks[0]["items"], 2)
+		assert.Equal(t, []any{cleaning}, file.Tasks[1]["depends_on"])
+		assert.NotEmpty(t, file.Tasks[2]["completed_at"])
+
+		rows, err := csv.NewReader(strings.NewReader(exportTasks(t, "format=csv"))).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, rows, 4)
+		assert.Equal(t, "id", rows[0][0])
+		assert.Equal(t, "Уборка", rows[1][2])
+
+		//Задачи, которые уже есть у пользователя, пропускаются или заменяются.
+		importTasks(t, "", backup, 0, 0, 3, 0)
+		_, err = postJSON("api/task", map[string]any{"id": cleaning, "title": "Генеральная уборка", "date": today},
+			http.MethodPut)
+		assert.NoError(t, err)
+		importTasks(t, "mode=overwrite", backup, 0, 3, 0, 0)
+		ret, err = postJSON("api/task?id="+cleaning, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, "Уборка", ret["title"])
+		assert.Equal(t, []any{"Пропылесосить", "Помыть полы"}, getItems(t, cleaning))
+		got, _ := archiveIDs(t, "")
+		assert.Equal(t, []string{bill}, got)
+
+		//В пробном режиме задачи не сохраняются.
+		items := importTasks(t, "mode=duplicate&dry_run=true", backup, 3, 0, 0, 0)
+		assert.NotNil(t, items[0].(map[string]any)["task"])
+		assert.Equal(t, []string{cleaning, guests}, taskIDs(t, "sort=id"))
+	})
+
+	//Копия загружается другому пользователю вместе с проектом, пунктами, зависимостями и архивом.
+	login = fmt.Sprintf("restore%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err = signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		items := importTasks(t, "", backup, 3, 0, 0, 0)
+		var restored []string
+		for _, item := range items {
+			restored = append(restored, fmt.Sprint(item.(map[string]any)["id"]))
+		}
+		assert.NotEqual(t, ids[:3], restored)
+		assert.Equal(t, restored[:2], taskIDs(t, "sort=id"))
+		got, _ := archiveIDs(t, "")
+		assert.Equal(t, restored[2:], got)
+
+		ret, err := postJSON("api/task?id="+restored[0], nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"быт"}, ret["tags"])
+		assert.Equal(t, 2.0, ret["priority"])
+		assert.NotEmpty(t, ret["project_id"])
+		assert.Equal(t, []any{"Пропылесосить", "Помыть полы"}, getItems(t, restored[0]))
+		ret, err = postJSON("api/task?id="+restored[1], nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{restored[0]}, ret["depends_on"])
+
+		//Задача с тем же идентификатором заменяется, только если совпадает время создания, а без него - название.
+		items = importTasks(t, "format=csv&mode=overwrite", strings.Join([]string{
+			"id,title,date,created_at",
+			restored[0] + ",Чужая задача," + today + ",2001-01-01T00:00:00Z",
+			restored[0] + ",Чужая задача," + today + ",",
+			restored[0] + ",Уборка," + today + ",",
+		}, "\n"), 2, 1, 0, 0)
+		assert.NotEqual(t, restored[0], items[0].(map[string]any)["id"])
+		assert.NotEqual(t, restored[0], items[1].(map[string]any)["id"])
+		assert.Equal(t, restored[0], items[2].(map[string]any)["id"])
+
+		//Строки CSV с ошибками не загружаются, остальные загружаются.
+		items = importTasks(t, "format=csv", strings.Join([]string{
+			"title,date,repeat,priority,tags",
+			"Полить цветы," + today + ",d 3,1,дом",
+			"Проверить почту," + today + ",FREQ=HOURLY,,",
+			"Купить молоко," + today + ",,высокий,",
+			"," + today + ",,,",
+		}, "\n"), 1, 0, 0, 3)
+		assert.Equal(t, "created", items[0].(map[string]any)["status"])
+		for i, field := range []string{"repeat", "priority", "title"} {
+			assert.Equal(t, field, items[i+1].(map[string]any)["field"])
+		}
+
+		status, m := requestStatus(t, "api/import?format=xml", backup, http.MethodPost)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "format", m["field"])
+		status, m = requestStatus(t, "api/import?mode=replace", backup, http.MethodPost)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "mode", m["field"])
+		status, m = requestStatus(t, "api/import", `{"version": 2, "tasks": []}`, http.MethodPost)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "file", m["field"])
+		status, _ = requestStatus(t, "api/import", `{"version": 1, "tasks": [}`, http.MethodPost)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
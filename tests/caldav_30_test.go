@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// davClient выполняет запросы CalDAV с логином и паролем пользователя и не следует перенаправлениям.
+type davClient struct {
+	login, password string
+}
+
+// do выполняет запрос method к пути apipath с телом body и заголовками headers (имя, значение, ...)
+// и возвращает ответ и его тело.
+func (c davClient) do(t *testing.T, method, apipath, body string, headers ...string) (*http.Response, string) {
+	req, err := http.NewRequest(method, getURL(apipath), strings.NewReader(body))
+	assert.NoError(t, err)
+	req.SetBasicAuth(c.login, c.password)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	client := http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	if !assert.NoError(t, err) {
+		return &http.Response{}, ""
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp, string(data)
+}
+
+type davPropstat struct {
+	Status string `xml:"status"`
+	Prop   struct {
+		ETag         string `xml:"getetag"`
+		CTag         string `xml:"getctag"`
+		DisplayName  string `xml:"displayname"`
+		Data         string `xml:"calendar-data"`
+		Principal    string `xml:"current-user-principal>href"`
+		Home         string `xml:"calendar-home-set>href"`
+		ResourceType struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"resourcetype"`
+	} `xml:"prop"`
+}
+
+type davResponse struct {
+	Href     string        `xml:"href"`
+	Status   string        `xml:"status"`
+	Propstat []davPropstat `xml:"propstat"`
+}
+
+// multistatus выполняет запрос PROPFIND или REPORT и возвращает ответы по ресурсам.
+func (c davClient) multistatus(t *testing.T, method, apipath, depth, body string) []davResponse {
+	resp, data := c.do(t, method, apipath, body, "Depth", depth, "Content-Type", "application/xml; charset=utf-8")
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode, data)
+
+	var m struct {
+		Responses []davResponse `xml:"response"`
+	}
+	assert.NoError(t, xml.Unmarshal([]byte(data), &m))
+	return m.Responses
+}
+
+// davProps - тело запроса с элементом prop, содержащим свойства props.
+func davProps(root string, props ...string) string {
+	return `<?xml version="1.0" encoding="utf-8"?><` + root + ` xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" ` +
+		`xmlns:cs="http://calendarserver.org/ns/"><d:prop><` + strings.Join(props, "/><") + `/></d:prop>`
+}
+
+// vtodo возвращает календарь с задачей VTODO из свойств props.
+func vtodo(props ...string) string {
+	lines := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Example//Tasks//EN", "BEGIN:VTODO"}, props...)
+	return strings.Join(append(lines, "END:VTODO", "END:VCALENDAR"), "\r\n") + "\r\n"
+}
+
+func TestCalDAV(t *testing.T) {
+	today := time.Now().Format(`20060102`)
+	date := time.Now().AddDate(0, 0, 3).Format(`20060102`)
+
+	login := fmt.Sprintf("caldav%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+	dav := davClient{login: login, password: "password123"}
+
+	//Без пароля и с неверным паролем клиент получает запрос авторизации.
+	for _, client := range []davClient{{}, {login: login, password: "wrong-password"}} {
+		resp, _ := client.do(t, "PROPFIND", "caldav/", "", "Depth", "0")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "Basic")
+	}
+
+	resp, _ := dav.do(t, "PROPFIND", ".well-known/caldav", "")
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "/caldav/", resp.Header.Get("Location"))
+
+	resp, _ = dav.do(t, http.MethodOptions, "caldav/tasks/", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("DAV"), "calendar-access")
+
+	asUser(token, func() {
+		ret, err := postJSON("api/task", map[string]any{"title": "Полить цветы", "date": today, "repeat": "d 3",
+			"tags": []string{"дом"}}, http.MethodPost)
+		assert.NoError(t, err)
+		id := fmt.Sprint(ret["id"])
+
+		//Обнаружение: принципал, домашний каталог и коллекция задач.
+		responses := dav.multistatus(t, "PROPFIND", "caldav/", "0",
+			davProps("d:propfind", "d:current-user-principal", "c:calendar-home-set", "d:quota-used-bytes")+"</d:propfind>")
+		if assert.Len(t, responses, 1) && assert.Len(t, responses[0].Propstat, 2) {
+			assert.Equal(t, "/caldav/", responses[0].Propstat[0].Prop.Principal)
+			assert.Equal(t, "/caldav/", responses[0].Propstat[0].Prop.Home)
+			assert.Contains(t, responses[0].Propstat[1].Status, "404")
+		}
+
+		responses = dav.multistatus(t, "PROPFIND", "caldav/", "1", "")
+		if assert.Len(t, responses, 2) {
+			assert.Equal(t, "/caldav/tasks/", responses[1].Href)
+			assert.Contains(t, responses[1].Propstat[0].Prop.ResourceType.Inner, "calendar")
+		}
+
+		responses = dav.multistatus(t, "PROPFIND", "caldav/tasks/", "1",
+			davProps("d:propfind", "d:resourcetype", "d:getetag", "cs:getctag")+"</d:propfind>")
+		if !assert.Len(t, responses, 2) {
+			return
+		}
+		ctag := responses[0].Propstat[0].Prop.CTag
+		assert.NotEmpty(t, ctag)
+		href := responses[1].Href
+		assert.Equal(t, "/caldav/tasks/task-"+id+".ics", href)
+		assert.NotEmpty(t, responses[1].Propstat[0].Prop.ETag)
+
+		responses = dav.multistatus(t, "REPORT", "caldav/tasks/", "1",
+			davProps("c:calendar-query", "d:getetag", "c:calendar-data")+
+				`<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter></c:calendar-query>`)
+		if assert.Len(t, responses, 1) {
+			data := responses[0].Propstat[0].Prop.Data
+			assert.Contains(t, data, "UID:task-"+id+"@todo-list\r\n")
+			assert.Contains(t, data, "SUMMARY:Полить цветы\r\n")
+			assert.Contains(t, data, "RRULE:FREQ=DAILY;INTERVAL=3\r\n")
+		}
+
+		//Клиент создаёт задачу, повторное создание с If-None-Match: * не выполняется.
+		todo := vtodo("UID:bread@example.com", "SUMMARY:Купить хлеб", "DTSTART;TZID=Europe/Moscow:"+date+"T090000",
+			"DUE;TZID=Europe/Moscow:"+date+"T093000", "CATEGORIES:магазин", "PRIORITY:1", "STATUS:NEEDS-ACTION")
+		resp, body := dav.do(t, http.MethodPut, "caldav/tasks/bread.ics", todo, "If-None-Match", "*",
+			"Content-Type", "text/calendar; charset=utf-8")
+		assert.Equal(t, http.StatusCreated, resp.StatusCode, body)
+		resp, _ = dav.do(t, http.MethodPut, "caldav/tasks/bread.ics", todo, "If-None-Match", "*")
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+		ids := taskIDs(t, "sort=id")
+		if !assert.Len(t, ids, 2) {
+			return
+		}
+		bread := ids[1]
+		ret, err = postJSON("api/task?id="+bread, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, "Купить хлеб", ret["title"])
+		assert.Equal(t, date, ret["date"])
+		assert.Equal(t, "09:00", ret["time"])
+		assert.Equal(t, 30.0, ret["duration"])
+		assert.Equal(t, "Europe/Moscow", ret["timezone"])
+		assert.Equal(t, []any{"магазин"}, ret["tags"])
+		assert.Equal(t, 3.0, ret["priority"])
+
+		resp, body = dav.do(t, http.MethodGet, "caldav/tasks/bread.ics", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "UID:bread@example.com\r\n")
+		etag := resp.Header.Get("ETag")
+		assert.NotEmpty(t, etag)
+
+		//Изменение с устаревшим ETag не выполняется, с текущим - выполняется и меняет ETag и ctag.
+		todo = strings.Replace(todo, "SUMMARY:Купить хлеб", "SUMMARY:Купить хлеб и молоко", 1)
+		resp, _ = dav.do(t, http.MethodPut, "caldav/tasks/bread.ics", todo, "If-Match", `"`+bread+`-0"`)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+		resp, body = dav.do(t, http.MethodPut, "caldav/tasks/bread.ics", todo, "If-Match", etag)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode, body)
+
+		resp, _ = dav.do(t, http.MethodGet, "caldav/tasks/bread.ics", "")
+		assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+		etag = resp.Header.Get("ETag")
+		responses = dav.multistatus(t, "PROPFIND", "caldav/tasks/", "0", davProps("d:propfind", "cs:getctag")+"</d:propfind>")
+		if assert.Len(t, responses, 1) {
+			assert.NotEqual(t, ctag, responses[0].Propstat[0].Prop.CTag)
+		}
+
+		//Изменение задачи через API тоже меняет ETag.
+		ret, err = postJSON("api/task?id="+bread, nil, http.MethodGet)
+		assert.NoError(t, err)
+		ret["comment"] = "Ржаной"
+		_, err = postJSON("api/task", ret, http.MethodPut)
+		assert.NoError(t, err)
+		resp, _ = dav.do(t, http.MethodGet, "caldav/tasks/bread.ics", "")
+		assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+		etag = resp.Header.Get("ETag")
+
+		responses = dav.multistatus(t, "REPORT", "caldav/tasks/", "1",
+			davProps("c:calendar-multiget", "d:getetag", "c:calendar-data")+
+				"<d:href>/caldav/tasks/bread.ics</d:href><d:href>/caldav/tasks/missing.ics</d:href></c:calendar-multiget>")
+		if assert.Len(t, responses, 2) {
+			assert.Equal(t, etag, responses[0].Propstat[0].Prop.ETag)
+			assert.Contains(t, responses[0].Propstat[0].Prop.Data, "SUMMARY:Купить хлеб и молоко\r\n")
+			assert.Contains(t, responses[0].Propstat[0].Prop.Data, "DESCRIPTION:Ржаной\r\n")
+			assert.Contains(t, responses[1].Status, "404")
+		}
+
+		//Выполненная в клиенте повторяющаяся задача переносится на следующую дату, правило повторения сохраняется.
+		resp, body = dav.do(t, http.MethodGet, href[1:], "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		body = strings.Replace(body, "STATUS:NEEDS-ACTION", "STATUS:COMPLETED", 1)
+		resp, body = dav.do(t, http.MethodPut, href[1:], body, "If-Match", resp.Header.Get("ETag"))
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode, body)
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, date, ret["date"])
+		assert.Equal(t, "d 3", ret["repeat"])
+		assert.Equal(t, []any{"дом"}, ret["tags"])
+
+		//Удаление переносит задачу в корзину.
+		resp, _ = dav.do(t, http.MethodDelete, "caldav/tasks/bread.ics", "", "If-Match", `"`+bread+`-0"`)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+		resp, _ = dav.do(t, http.MethodDelete, "caldav/tasks/bread.ics", "", "If-Match", etag)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp, _ = dav.do(t, http.MethodGet, "caldav/tasks/bread.ics", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, []string{bread}, trashIDs(t))
+
+		//Имя задачи в корзине остаётся занятым: новая задача с этим именем не создаётся.
+		resp, _ = dav.do(t, http.MethodPut, "caldav/tasks/bread.ics", todo, "If-None-Match", "*")
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+		//Имена вида task-<число>.ics зарезервированы для задач, созданных на сервере.
+		resp, _ = dav.do(t, http.MethodPut, "caldav/tasks/task-999999999.ics", vtodo("UID:reserved@example.com",
+			"SUMMARY:Чужое имя"), "If-None-Match", "*")
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+		resp, _ = dav.do(t, http.MethodPut, "caldav/tasks/event.ics",
+			strings.ReplaceAll(vtodo("UID:event@example.com", "SUMMARY:Встреча"), "VTODO", "VEVENT"))
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		resp, _ = dav.do(t, http.MethodPut, "caldav/tasks/hourly.ics", vtodo("UID:hourly@example.com", "SUMMARY:Почта",
+			"RRULE:FREQ=HOURLY"))
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, []string{id}, taskIDs(t, ""))
+	})
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getCalendar запрашивает календарь по пути apipath без авторизации
+// и возвращает код ответа и строки календаря с объединёнными переносами.
+func getCalendar(t *testing.T, apipath string) (int, []string) {
+	resp, err := http.Get(getURL(apipath))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+
+	assert.Equal(t, "text/calendar; charset=utf-8", resp.Header.Get("Content-Type"))
+	for _, line := range strings.Split(string(body), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+	return resp.StatusCode, strings.Split(strings.ReplaceAll(string(body), "\r\n ", ""), "\r\n")
+}
+
+// calendarComponent возвращает свойства компонента календаря с идентификатором задачи id.
+func calendarComponent(lines []string, id string) []string {
+	var props []string
+	found := false
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "BEGIN:V") && line != "BEGIN:VCALENDAR":
+			props = []string{line}
+		case strings.HasPrefix(line, "END:V") && line != "END:VCALENDAR":
+			if found {
+				return append(props, line)
+			}
+		default:
+			props = append(props, line)
+			found = found || line == "UID:task-"+id+"@todo-list"
+		}
+	}
+	return nil
+}
+
+// filterLines возвращает строки lines, которые начинаются с одного из префиксов prefixes.
+func filterLines(lines []string, prefixes ...string) []string {
+	var res []string
+	for _, line := range lines {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(line, prefix) {
+				res = append(res, line)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// calendarTimezone возвращает свойства компонента VTIMEZONE часового пояса tzid вместе с вложенными компонентами.
+func calendarTimezone(lines []string, tzid string) []string {
+	var props []string
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VTIMEZONE":
+			props = []string{line}
+		case line == "END:VTIMEZONE" && len(props) > 1 && props[1] == "TZID:"+tzid:
+			return append(props, line)
+		case len(props) > 0:
+			props = append(props, line)
+		}
+	}
+	return nil
+}
+
+func TestCalendar(t *testing.T) {
+	login := fmt.Sprintf("calendar%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+		comment := strings.Repeat("Обсудить план, бюджет; сроки. ", 5)
+		ret, err := postJSON("api/task", map[string]any{"title": "Планёрка", "comment": comment, "date": date,
+			"time": "09:30", "duration": 45, "timezone": "Europe/Moscow", "repeat": "w 1,3", "repeat_count": 3,
+			"tags": []string{"work"}, "priority": 3}, http.MethodPost)
+		assert.NoError(t, err)
+		meeting := fmt.Sprint(ret["id"])
+
+		ret, err = postJSON("api/task", map[string]any{"title": "Купить хлеб", "date": date}, http.MethodPost)
+		assert.NoError(t, err)
+		bread := fmt.Sprint(ret["id"])
+
+		ret, err = postJSON("api/task", map[string]any{"title": "Зарплата", "date": date, "repeat": "m 1,15,2w1"},
+			http.MethodPost)
+		assert.NoError(t, err)
+		salary := fmt.Sprint(ret["id"])
+
+		ret, err = postJSON("api/task", map[string]any{"title": "Созвон", "date": date, "time": "18:00",
+			"timezone": "Europe/Berlin"}, http.MethodPost)
+		assert.NoError(t, err)
+		call := fmt.Sprint(ret["id"])
+
+		ret, err = postJSON("api/task", map[string]any{"title": "Полить цветы", "date": date, "repeat": "d 3"},
+			http.MethodPost)
+		assert.NoError(t, err)
+		flowers := fmt.Sprint(ret["id"])
+
+		status, _ := getCalendar(t, "api/calendar.ics?token=unknown")
+		assert.Equal(t, http.StatusUnauthorized, status)
+
+		m, err := postJSON("api/user/feed", nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Equal(t, "/api/calendar.ics?token="+fmt.Sprint(m["token"]), m["url"])
+		feed := strings.TrimPrefix(fmt.Sprint(m["url"]), "/")
+
+		status, lines := getCalendar(t, feed)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "BEGIN:VCALENDAR", lines[0])
+
+		event := calendarComponent(lines, meeting)
+		assert.Equal(t, "BEGIN:VEVENT", event[0])
+		assert.Contains(t, event, "SUMMARY:Планёрка")
+		assert.Contains(t, event, `DESCRIPTION:`+strings.Repeat(`Обсудить план\, бюджет\; сроки. `, 5))
+		assert.Contains(t, event, "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3")
+		assert.Contains(t, event, "CATEGORIES:work")
+		assert.Contains(t, event, "PRIORITY:1")
+		m, err = postJSON("api/task?id="+meeting, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Contains(t, event, "DTSTART;TZID=Europe/Moscow:"+m["date"].(string)+"T093000")
+		assert.Contains(t, event, "DTEND;TZID=Europe/Moscow:"+m["date"].(string)+"T101500")
+
+		//Часовые пояса задач описываются компонентами VTIMEZONE.
+		tz := calendarTimezone(lines, "Europe/Moscow")
+		assert.Contains(t, tz, "TZOFFSETTO:+0300")
+		tz = calendarTimezone(lines, "Europe/Berlin")
+		assert.Contains(t, tz, "BEGIN:DAYLIGHT")
+		assert.Contains(t, tz, "TZOFFSETTO:+0200")
+		assert.Contains(t, tz, "RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU")
+		assert.Contains(t, tz, "BEGIN:STANDARD")
+		assert.Contains(t, tz, "RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU")
+		assert.Equal(t, []string{"BEGIN:VEVENT", "DTSTART;TZID=Europe/Berlin:" + date + "T180000"},
+			filterLines(calendarComponent(lines, call), "BEGIN:", "DTSTART", "DTEND", "DUE"))
+
+		event = calendarComponent(lines, bread)
+		assert.Contains(t, event, "DTSTART;VALUE=DATE:"+date)
+		assert.Contains(t, event, "DTEND;VALUE=DATE:"+time.Now().AddDate(0, 0, 2).Format(`20060102`))
+
+		//Правило, которое нельзя записать в формате RRULE, не выдаётся.
+		event = calendarComponent(lines, salary)
+		assert.NotEmpty(t, event)
+		for _, line := range event {
+			assert.False(t, strings.HasPrefix(line, "RRULE"), line)
+		}
+
+		status, lines = getCalendar(t, feed+"&kind=todo")
+		assert.Equal(t, http.StatusOK, status)
+		todo := calendarComponent(lines, bread)
+		assert.Equal(t, "BEGIN:VTODO", todo[0])
+		assert.Contains(t, todo, "STATUS:NEEDS-ACTION")
+
+		//Срок задачи позже её начала: задача без продолжительности записывается только сроком,
+		//а повторяющаяся задача - только началом, от которого отсчитываются повторения.
+		assert.Equal(t, []string{"BEGIN:VTODO", "DUE;VALUE=DATE:" + date}, filterLines(todo, "BEGIN:", "DTSTART", "DUE"))
+		assert.Equal(t, []string{"BEGIN:VTODO", "DUE;TZID=Europe/Berlin:" + date + "T180000"},
+			filterLines(calendarComponent(lines, call), "BEGIN:", "DTSTART", "DUE"))
+		assert.Equal(t, []string{"BEGIN:VTODO", "DTSTART;VALUE=DATE:" + date},
+			filterLines(calendarComponent(lines, flowers), "BEGIN:", "DTSTART", "DUE"))
+		m, err = postJSON("api/task?id="+meeting, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"BEGIN:VTODO", "DTSTART;TZID=Europe/Moscow:" + m["date"].(string) + "T093000",
+			"DUE;TZID=Europe/Moscow:" + m["date"].(string) + "T101500"},
+			filterLines(calendarComponent(lines, meeting), "BEGIN:", "DTSTART", "DUE"))
+
+		status, _ = getCalendar(t, feed+"&kind=journal")
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+		//Выполненная разовая задача пропадает из календаря.
+		completeTask(t, bread)
+		_, lines = getCalendar(t, feed)
+		assert.Nil(t, calendarComponent(lines, bread))
+
+		//Новый токен заменяет прежний, отозванный токен не действует.
+		m, err = postJSON("api/user/feed", nil, http.MethodPost)
+		assert.NoError(t, err)
+		status, _ = getCalendar(t, feed)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		status, _ = getCalendar(t, strings.TrimPrefix(fmt.Sprint(m["url"]), "/"))
+		assert.Equal(t, http.StatusOK, status)
+
+		_, err = postJSON("api/user/feed", nil, http.MethodDelete)
+		assert.NoError(t, err)
+		status, _ = getCalendar(t, strings.TrimPrefix(fmt.Sprint(m["url"]), "/"))
+		assert.Equal(t, http.StatusUnauthorized, status)
+	})
+}
@@ -11,11 +11,25 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID          int64  `db:"id"`
+	Date        string `db:"date"`
+	Title       string `db:"title"`
+	Comment     string `db:"comment"`
+	Repeat      string `db:"repeat"`
+	UserID      int64  `db:"user_id"`
+	CreatedAt   string `db:"created_at"`
+	RepeatUntil string `db:"repeat_until"`
+	RepeatCount int    `db:"repeat_count"`
+	Time        string `db:"time"`
+	Duration    int    `db:"duration"`
+	Timezone    string `db:"timezone"`
+	Priority    int    `db:"priority"`
+	ProjectID   int64  `db:"project_id"`
+	CompletedAt string `db:"completed_at"`
+	DeletedAt   string `db:"deleted_at"`
+	Revision    int64  `db:"revision"`
+	ICalUID     string `db:"ical_uid"`
+	CalDAVName  string `db:"caldav_name"`
 }
 
 func count(db *sqlx.DB) (int, error) {
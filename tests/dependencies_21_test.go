@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// addDependency добавляет зависимость задачи id от задачи dependsOn и возвращает ответ сервера.
+func addDependency(t *testing.T, id, dependsOn string) (int, map[string]any) {
+	return requestStatus(t, fmt.Sprintf("api/task/dependency?id=%s&depends_on=%s", id, dependsOn), "", http.MethodPost)
+}
+
+func TestDependencies(t *testing.T) {
+	login := fmt.Sprintf("deps%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		today := time.Now().Format(`20060102`)
+		var ids []string
+		for _, title := range []string{"Собрать данные", "Написать отчёт", "Отправить отчёт"} {
+			ret, err := postJSON("api/task", map[string]any{"title": title, "date": today}, http.MethodPost)
+			assert.NoError(t, err)
+			ids = append(ids, fmt.Sprint(ret["id"]))
+		}
+		collect, write, send := ids[0], ids[1], ids[2]
+
+		status, _ := addDependency(t, write, collect)
+		assert.Equal(t, http.StatusOK, status)
+		status, _ = addDependency(t, send, write)
+		assert.Equal(t, http.StatusOK, status)
+
+		for _, v := range [][2]string{{collect, send}, {collect, collect}} {
+			status, m := addDependency(t, v[0], v[1])
+			assert.Equal(t, http.StatusUnprocessableEntity, status, v)
+			assert.Equal(t, "depends_on", m["field"], v)
+		}
+
+		m, err := postJSON("api/task?id="+send, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, true, m["blocked"])
+		assert.Equal(t, []any{write}, m["depends_on"])
+
+		assert.Equal(t, []string{write, send}, taskIDs(t, "status=blocked&sort=id"))
+		assert.Equal(t, []string{collect}, taskIDs(t, "status=ready"))
+
+		status, m = requestStatus(t, "api/task/done?id="+write, "", http.MethodPost)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, "conflict", m["code"])
+
+		completeTask(t, collect)
+		assert.Equal(t, []string{write}, taskIDs(t, "status=ready"))
+		completeTask(t, write)
+
+		//Отмена выполнения возвращает блокировку.
+		_, err = postJSON("api/task/undo?id="+write, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{send}, taskIDs(t, "status=blocked"))
+
+		_, err = postJSON(fmt.Sprintf("api/task/dependency?id=%s&depends_on=%s", send, write), nil, http.MethodDelete)
+		assert.NoError(t, err)
+		m, err = postJSON("api/task?id="+send, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Nil(t, m["blocked"])
+		assert.Nil(t, m["depends_on"])
+
+		status, _ = requestStatus(t, fmt.Sprintf("api/task/dependency?id=%s&depends_on=%s", send, write), "", http.MethodDelete)
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
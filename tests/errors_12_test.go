@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requestStatus выполняет запрос и возвращает код ответа и разобранное тело.
+func requestStatus(t *testing.T, apipath string, body string, method string) (int, map[string]any) {
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewBufferString(body))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if method != http.MethodGet {
+		req.Header.Set("If-Match", "*")
+	}
+	if len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0, nil
+	}
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	return resp.StatusCode, m
+}
+
+func TestErrors(t *testing.T) {
+	tbl := []struct {
+		path   string
+		body   string
+		method string
+		status int
+		code   string
+		field  string
+	}{
+		{"api/task?id=7645346343", "", http.MethodGet, http.StatusNotFound, "not_found", ""},
+		{"api/task?id=abc", "", http.MethodDelete, http.StatusNotFound, "not_found", ""},
+		{"api/task/done?id=7645346343", "", http.MethodPost, http.StatusNotFound, "not_found", ""},
+		{"api/task", "", http.MethodGet, http.StatusUnprocessableEntity, "validation_failed", "id"},
+		{"api/task", `{"date": "20240129"}`, http.MethodPost, http.StatusUnprocessableEntity, "validation_failed", "title"},
+		{"api/task", `{"date": "28.01.2024", "title": "Заголовок"}`, http.MethodPost, http.StatusUnprocessableEntity, "validation_failed", "date"},
+		{"api/task", `{"title": "Заголовок", "date": "20240101", "repeat": "ooops"}`, http.MethodPost, http.StatusUnprocessableEntity, "validation_failed", "repeat"},
+		{"api/task", `{"title": 1}`, http.MethodPost, http.StatusBadRequest, "bad_request", ""},
+		{"api/tasks?limit=0", "", http.MethodGet, http.StatusUnprocessableEntity, "validation_failed", "limit"},
+		{"api/nextdate?now=20240126&date=20240126&repeat=k", "", http.MethodGet, http.StatusUnprocessableEntity, "validation_failed", "repeat"},
+		{"api/signin", `{"login": "nobody-here", "password": "password123"}`, http.MethodPost, http.StatusUnauthorized, "invalid_credentials", ""},
+	}
+
+	for _, v := range tbl {
+		status, m := requestStatus(t, v.path, v.body, v.method)
+		assert.Equal(t, v.status, status, "%s %s %s", v.method, v.path, v.body)
+		assert.Equal(t, v.code, m["code"], "%s %s %s", v.method, v.path, v.body)
+		assert.NotEmpty(t, m["error"], "%s %s %s", v.method, v.path, v.body)
+		if len(v.field) > 0 {
+			assert.Equal(t, v.field, m["field"], "%s %s %s", v.method, v.path, v.body)
+		}
+	}
+
+	saved := Token
+	Token = "garbage"
+	status, m := requestStatus(t, "api/tasks", "", http.MethodGet)
+	Token = saved
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "unauthorized", m["code"])
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type completion struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Date        string `json:"date"`
+	NextDate    string `json:"next_date"`
+	CompletedAt string `json:"completed_at"`
+	Note        string `json:"note"`
+}
+
+func getHistory(t *testing.T, id string) []completion {
+	body, err := requestJSON("api/task/history?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]completion
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m["completions"]
+}
+
+func TestHistory(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	// Выполнение повторяющейся задачи записывается в историю, отмена возвращает прежнюю дату.
+	id := addTask(t, task{
+		date:   today,
+		title:  "Полить цветы",
+		repeat: "d 2",
+	})
+
+	ret, err := postJSON("api/task/done?id="+id+"&note="+url.QueryEscape("Полил"), nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	history := getHistory(t, id)
+	if assert.Len(t, history, 1) {
+		assert.Equal(t, id, history[0].TaskID)
+		assert.Equal(t, today, history[0].Date)
+		assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), history[0].NextDate)
+		assert.Equal(t, "Полил", history[0].Note)
+		assert.NotEmpty(t, history[0].CompletedAt)
+	}
+
+	ret, err = postJSON("api/task/undo?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var tsk Task
+	assert.NoError(t, db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, today, tsk.Date)
+	assert.Empty(t, getHistory(t, id))
+
+	ret, err = postJSON("api/task/undo?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// Отмена выполнения разовой задачи восстанавливает удалённую задачу.
+	id = addTask(t, task{
+		date:    today,
+		title:   "Купить хлеб",
+		comment: "Бородинский",
+	})
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	history = getHistory(t, id)
+	if assert.Len(t, history, 1) {
+		assert.Empty(t, history[0].NextDate)
+	}
+
+	ret, err = postJSON("api/task/undo?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	assert.NoError(t, db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, "Купить хлеб", tsk.Title)
+	assert.Equal(t, "Бородинский", tsk.Comment)
+	assert.Equal(t, today, tsk.Date)
+}
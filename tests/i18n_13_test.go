@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// requestLang выполняет запрос с заголовком Accept-Language и возвращает разобранное тело ответа
+// и значение заголовка Content-Language.
+func requestLang(t *testing.T, apipath, body, method, lang string) (map[string]any, string) {
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewBufferString(body))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if len(lang) > 0 {
+		req.Header.Set("Accept-Language", lang)
+	}
+	if len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return nil, ""
+	}
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	return m, resp.Header.Get("Content-Language")
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	body := `{"date": "20240129"}`
+
+	tbl := []struct {
+		lang    string
+		want    string
+		message string
+	}{
+		{"", "ru", "Не указан заголовок задачи"},
+		{"en", "en", "Task title is required"},
+		{"en-US,en;q=0.9,ru;q=0.8", "en", "Task title is required"},
+		{"ru;q=0.5,en;q=0.9", "en", "Task title is required"},
+		{"de,ru", "ru", "Не указан заголовок задачи"},
+		{"fr", "ru", "Не указан заголовок задачи"},
+	}
+	for _, v := range tbl {
+		m, lang := requestLang(t, "api/task", body, http.MethodPost, v.lang)
+		assert.Equal(t, v.want, lang, "Accept-Language: %s", v.lang)
+		assert.Equal(t, v.message, m["error"], "Accept-Language: %s", v.lang)
+	}
+
+	m, _ := requestLang(t, "api/nextdate?now=20240126&date=20240126&repeat=d%20401", "", http.MethodGet, "en")
+	assert.Equal(t, "Maximum allowed interval is 400 (got 401)", m["error"])
+
+	m, _ = requestLang(t, "api/signin", `{"login": "nobody-here", "password": "password123"}`, http.MethodPost, "en")
+	assert.Equal(t, "Invalid login or password", m["error"])
+}
+
+func TestUserLang(t *testing.T) {
+	login := fmt.Sprintf("polyglot%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		m, _ := requestLang(t, "api/user", "", http.MethodGet, "")
+		assert.Equal(t, login, m["login"])
+		assert.Equal(t, "", m["lang"])
+
+		m, _ = requestLang(t, "api/user", `{"lang": "de"}`, http.MethodPut, "en")
+		assert.Equal(t, "validation_failed", m["code"])
+		assert.Equal(t, "lang", m["field"])
+		assert.Equal(t, "Unsupported language de", m["error"])
+
+		m, _ = requestLang(t, "api/user", `{"lang": "en"}`, http.MethodPut, "")
+		assert.Equal(t, "en", m["lang"])
+
+		//Язык из профиля важнее заголовка Accept-Language.
+		m, lang := requestLang(t, "api/task", `{"date": "20240129"}`, http.MethodPost, "ru")
+		assert.Equal(t, "en", lang)
+		assert.Equal(t, "Task title is required", m["error"])
+
+		m, _ = requestLang(t, "api/user", `{"lang": ""}`, http.MethodPut, "")
+		assert.Equal(t, "", m["lang"])
+
+		m, lang = requestLang(t, "api/task", `{"date": "20240129"}`, http.MethodPost, "ru")
+		assert.Equal(t, "ru", lang)
+		assert.Equal(t, "Не указан заголовок задачи", m["error"])
+	})
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// importICal отправляет календарь в поле file формы multipart/form-data и возвращает код ответа и результат импорта.
+func importICal(t *testing.T, query, calendar string) (int, map[string]any) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "calendar.ics")
+	assert.NoError(t, err)
+	file.Write([]byte(calendar))
+	assert.NoError(t, form.Close())
+
+	req, err := http.NewRequest(http.MethodPost, getURL("api/import/ics"+query), &body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0, nil
+	}
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	return resp.StatusCode, m
+}
+
+func TestImportICal(t *testing.T) {
+	login := fmt.Sprintf("import%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	date := time.Now().AddDate(0, 0, 3).Format(`20060102`)
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Example//Calendar//EN",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Moscow",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"SUMMARY:Планёрка",
+		"DESCRIPTION:Обсудить план\\, бюджет\\; сроки.\\nВзять отчёт.",
+		"DTSTART;TZID=Europe/Moscow:" + date + "T093000",
+		"DTEND;TZID=Europe/Moscow:" + date + "T101500",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5",
+		"CATEGORIES:work,meeting",
+		"PRIORITY:1",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:Напоминание",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:bread@example.com",
+		"SUMMARY:Купить хлеб и молоко к завтраку для всей семьи\\, не забыть про",
+		"  скидочную карту",
+		"DUE;VALUE=DATE:" + date,
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:hourly@example.com",
+		"SUMMARY:Проверить почту",
+		"RRULE:FREQ=HOURLY",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:done@example.com",
+		"SUMMARY:Сдать отчёт",
+		"STATUS:COMPLETED",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	asUser(token, func() {
+		//В пробном режиме задачи проверяются, но не создаются.
+		status, m := importICal(t, "?dry_run=true", calendar)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, true, m["dry_run"])
+		assert.Equal(t, 2.0, m["created"])
+		assert.Equal(t, 1.0, m["skipped"])
+		assert.Equal(t, 1.0, m["failed"])
+
+		items := m["items"].([]any)
+		assert.Len(t, items, 4)
+		meeting := items[0].(map[string]any)
+		assert.Equal(t, "created", meeting["status"])
+		assert.Equal(t, "standup@example.com", meeting["uid"])
+		task := meeting["task"].(map[string]any)
+		assert.Equal(t, "Планёрка", task["title"])
+		assert.Equal(t, "Обсудить план, бюджет; сроки.\nВзять отчёт.", task["comment"])
+		assert.Equal(t, "09:30", task["time"])
+		assert.Equal(t, 45.0, task["duration"])
+		assert.Equal(t, "Europe/Moscow", task["timezone"])
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5", task["repeat"])
+		assert.Equal(t, 5.0, task["repeat_count"])
+		assert.Equal(t, []any{"work", "meeting"}, task["tags"])
+		assert.Equal(t, 3.0, task["priority"])
+
+		bread := items[1].(map[string]any)
+		assert.Equal(t, "Купить хлеб и молоко к завтраку для всей семьи, не забыть про скидочную карту", bread["title"])
+		assert.Equal(t, date, bread["task"].(map[string]any)["date"])
+
+		//Правило повторения, которое не поддерживается, возвращается ошибкой задачи.
+		hourly := items[2].(map[string]any)
+		assert.Equal(t, "failed", hourly["status"])
+		assert.Equal(t, "repeat", hourly["field"])
+		assert.NotEmpty(t, hourly["error"])
+		assert.Equal(t, "skipped", items[3].(map[string]any)["status"])
+		assert.Empty(t, taskIDs(t, ""))
+
+		//Импорт создаёт задачи без ошибок, задачи с ошибками пропускаются.
+		status, m = importICal(t, "", calendar)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 2.0, m["created"])
+		items = m["items"].([]any)
+		id := fmt.Sprint(items[0].(map[string]any)["id"])
+		assert.NotEmpty(t, id)
+		assert.Nil(t, items[0].(map[string]any)["task"])
+		assert.Len(t, taskIDs(t, ""), 2)
+
+		ret, err := postJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, "Планёрка", ret["title"])
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5", ret["repeat"])
+
+		//UID компонента сохраняется в задаче и выдаётся в календаре.
+		m, err = postJSON("api/user/feed", nil, http.MethodPost)
+		assert.NoError(t, err)
+		_, lines := getCalendar(t, strings.TrimPrefix(fmt.Sprint(m["url"]), "/"))
+		assert.Contains(t, lines, "UID:standup@example.com")
+
+		//Повторный импорт пропускает задачи, UID которых уже есть. Календарь можно передать телом запроса.
+		for _, query := range []string{"?dry_run=1", ""} {
+			status, m = requestStatus(t, "api/import/ics"+query, calendar, http.MethodPost)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, []any{0.0, 3.0, 1.0}, []any{m["created"], m["skipped"], m["failed"]})
+			items = m["items"].([]any)
+			assert.Equal(t, "skipped", items[0].(map[string]any)["status"])
+			assert.Equal(t, id, items[0].(map[string]any)["id"])
+		}
+		assert.Len(t, taskIDs(t, ""), 2)
+
+		//Компонент с тем же UID, что и компонент выше в файле, тоже пропускается.
+		twice := strings.Join([]string{"BEGIN:VCALENDAR", "VERSION:2.0",
+			"BEGIN:VTODO", "UID:milk@example.com", "SUMMARY:Купить молоко", "END:VTODO",
+			"BEGIN:VTODO", "UID:milk@example.com", "SUMMARY:Купить молоко", "END:VTODO",
+			"END:VCALENDAR"}, "\r\n")
+		status, m = requestStatus(t, "api/import/ics?dry_run=true", twice, http.MethodPost)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []any{1.0, 1.0}, []any{m["created"], m["skipped"]})
+
+		status, m = requestStatus(t, "api/import/ics", `{"title": "Не календарь"}`, http.MethodPost)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "file", m["field"])
+		status, _ = requestStatus(t, "api/import/ics?dry_run=maybe", calendar, http.MethodPost)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+	})
+}
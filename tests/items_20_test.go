@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getItems возвращает названия пунктов задачи по порядку.
+func getItems(t *testing.T, id string) []any {
+	m, err := postJSON("api/task/items?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	titles := []any{}
+	items, _ := m["items"].([]any)
+	for _, item := range items {
+		titles = append(titles, item.(map[string]any)["title"])
+	}
+	return titles
+}
+
+// getProgress возвращает прогресс выполнения пунктов задачи.
+func getProgress(t *testing.T, id string) map[string]any {
+	m, err := postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	progress, _ := m["progress"].(map[string]any)
+	return progress
+}
+
+func TestItems(t *testing.T) {
+	login := fmt.Sprintf("items%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		ret, err := postJSON("api/task", map[string]any{
+			"title":  "Подготовить отчёт",
+			"date":   time.Now().Format(`20060102`),
+			"repeat": "d 7",
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		id := fmt.Sprint(ret["id"])
+		assert.Nil(t, getProgress(t, id))
+
+		var items []any
+		for _, v := range []map[string]any{
+			{"task_id": id, "title": "Собрать данные"},
+			{"task_id": id, "title": "Написать текст"},
+			{"task_id": id, "title": "Показать коллеге", "optional": true},
+		} {
+			ret, err := postJSON("api/task/item", v, http.MethodPost)
+			assert.NoError(t, err)
+			items = append(items, ret["id"])
+		}
+		assert.Equal(t, map[string]any{"done": 0.0, "total": 3.0, "open": 2.0}, getProgress(t, id))
+
+		_, err = postJSON("api/task/items", map[string]any{"task_id": id, "ids": []any{items[2], items[0], items[1]}}, http.MethodPut)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"Показать коллеге", "Собрать данные", "Написать текст"}, getItems(t, id))
+
+		m, err := postJSON("api/task/items", map[string]any{"task_id": id, "ids": []any{items[0], items[0], items[1]}}, http.MethodPut)
+		assert.NoError(t, err)
+		assert.Equal(t, "ids", m["field"])
+
+		status, m := requestStatus(t, "api/task/done?id="+id, "", http.MethodPost)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, "conflict", m["code"])
+
+		for _, item := range items[:2] {
+			m, err := postJSON(fmt.Sprintf("api/task/item/toggle?id=%v", item), nil, http.MethodPost)
+			assert.NoError(t, err)
+			assert.Equal(t, true, m["done"])
+		}
+		assert.Equal(t, map[string]any{"done": 2.0, "total": 3.0, "open": 0.0}, getProgress(t, id))
+
+		//У повторяющейся задачи отметки снимаются при выполнении и восстанавливаются при отмене выполнения.
+		completeTask(t, id)
+		assert.Equal(t, map[string]any{"done": 0.0, "total": 3.0, "open": 2.0}, getProgress(t, id))
+		_, err = postJSON("api/task/undo?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"done": 2.0, "total": 3.0, "open": 0.0}, getProgress(t, id))
+
+		_, err = postJSON(fmt.Sprintf("api/task/item?id=%v", items[2]), nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"Собрать данные", "Написать текст"}, getItems(t, id))
+
+		status, _ = requestStatus(t, fmt.Sprintf("api/task/item/toggle?id=%v", items[2]), "", http.MethodPost)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		m, err = postJSON("api/task/item", map[string]any{"task_id": id, "title": " "}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Equal(t, "title", m["field"])
+	})
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// taskIDs возвращает идентификаторы задач, найденных с параметрами запроса query.
+func taskIDs(t *testing.T, query string) []string {
+	m, err := postJSON("api/tasks?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	ids := []string{}
+	tasks, _ := m["tasks"].([]any)
+	for _, task := range tasks {
+		ids = append(ids, fmt.Sprint(task.(map[string]any)["id"]))
+	}
+	return ids
+}
+
+func TestLabels(t *testing.T) {
+	login := fmt.Sprintf("labels%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		ret, err := postJSON("api/project", map[string]any{"name": " Дом "}, http.MethodPost)
+		assert.NoError(t, err)
+		project := fmt.Sprint(ret["id"])
+
+		status, m := requestStatus(t, "api/project", `{"name":"Дом"}`, http.MethodPost)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, "conflict", m["code"])
+
+		ret, err = postJSON("api/task", map[string]any{
+			"title":      "Починить кран",
+			"priority":   3,
+			"project_id": ret["id"],
+			"tags":       []string{"срочно", "дом", "срочно"},
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		urgent := fmt.Sprint(ret["id"])
+
+		ret, err = postJSON("api/task", map[string]any{
+			"title":    "Купить лампочки",
+			"priority": 1,
+			"tags":     []string{"дом"},
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		other := fmt.Sprint(ret["id"])
+
+		m, err = postJSON("api/task?id="+urgent, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 3, m["priority"])
+		assert.Equal(t, project, fmt.Sprint(m["project_id"]))
+		assert.Equal(t, []any{"дом", "срочно"}, m["tags"])
+
+		assert.Equal(t, []string{urgent, other}, taskIDs(t, "tag=дом&sort=id"))
+		assert.Equal(t, []string{urgent}, taskIDs(t, "tag=срочно"))
+		assert.Equal(t, []string{urgent}, taskIDs(t, "project="+project))
+		assert.Equal(t, []string{urgent}, taskIDs(t, "priority=2"))
+		assert.Empty(t, taskIDs(t, "tag=работа"))
+
+		tbl := []struct {
+			values map[string]any
+			field  string
+		}{
+			{map[string]any{"title": "Задача", "priority": 4}, "priority"},
+			{map[string]any{"title": "Задача", "project_id": 1 << 40}, "project_id"},
+			{map[string]any{"title": "Задача", "tags": []string{"два слова"}}, "tags"},
+			{map[string]any{"title": "Задача", "tags": []string{""}}, "tags"},
+		}
+		for _, v := range tbl {
+			m, err := postJSON("api/task", v.values, http.MethodPost)
+			assert.NoError(t, err)
+			assert.Equal(t, "validation_failed", m["code"], v.values)
+			assert.Equal(t, v.field, m["field"], v.values)
+		}
+
+		//Теги задачи восстанавливаются вместе с задачей при отмене выполнения.
+		completeTask(t, urgent)
+		notFoundTask(t, urgent)
+		_, err = postJSON("api/task/undo?id="+urgent, nil, http.MethodPost)
+		assert.NoError(t, err)
+		m, err = postJSON("api/task?id="+urgent, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"дом", "срочно"}, m["tags"])
+
+		m, err = postJSON("api/tags", nil, http.MethodGet)
+		assert.NoError(t, err)
+		tags, _ := m["tags"].([]any)
+		if assert.Len(t, tags, 2) {
+			tag := tags[0].(map[string]any)
+			assert.Equal(t, "дом", tag["name"])
+
+			_, err = postJSON("api/tag", map[string]any{"id": tag["id"], "name": "квартира"}, http.MethodPut)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{urgent}, taskIDs(t, "tag=квартира&priority=3"))
+
+			_, err = postJSON(fmt.Sprintf("api/tag?id=%v", tag["id"]), nil, http.MethodDelete)
+			assert.NoError(t, err)
+			m, err = postJSON("api/task?id="+other, nil, http.MethodGet)
+			assert.NoError(t, err)
+			assert.Nil(t, m["tags"])
+		}
+
+		_, err = postJSON("api/project?id="+project, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		m, err = postJSON("api/task?id="+urgent, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Nil(t, m["project_id"])
+
+		status, _ = requestStatus(t, "api/project?id="+project, "", http.MethodDelete)
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateIntervals(t *testing.T) {
+	tbl := []nextDate{
+		{"20240125", "y 2", "20260125"},
+		{"20220301", "y 3", "20250301"},
+		{"20240126", "y 0", ""},
+		{"20240126", "y 101", ""},
+		{"20240126", "y x", ""},
+		{"20240101", "m /3", "20240401"},
+		{"20231115", "m 15 /3", "20240215"},
+		{"20240131", "m /1", "20240331"},
+		{"20240101", "m 1 /0", ""},
+		{"20240101", "m 1 /61", ""},
+		{"20240101", "m /3 4", ""},
+		{"20240101", "m 31 2", ""},
+		{"20240116", "w 2 /2", "20240130"},
+		{"20240109", "w 2 /2", "20240206"},
+		{"20240101", "w 1,5 /3", "20240212"},
+		{"20240101", "w 2 2", ""},
+		{"20240101", "w 2 /53", ""},
+	}
+	checkNextDates(t, tbl)
+}
+
+func TestNextDateNthWeekday(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "m 1w1", "20240205"},
+		{"20240101", "m -1w5", "20240223"},
+		{"20240101", "m 2w2,4w2", "20240213"},
+		{"20240101", "m 1w1 /3", "20240401"},
+		{"20240101", "m 1w1 3,6", "20240304"},
+		{"20240101", "m 20,1w1", "20240205"},
+		{"20240101", "m 6w1", ""},
+		{"20240101", "m 0w1", ""},
+		{"20240101", "m 1w8", ""},
+		{"20240101", "m 1w", ""},
+	}
+	checkNextDates(t, tbl)
+}
+
+// checkNextDates проверяет ответы /api/nextdate для текущей даты 20240126.
+func checkNextDates(t *testing.T, tbl []nextDate) {
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
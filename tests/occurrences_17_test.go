@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type occurrence struct {
+	Date string `json:"date"`
+	Task struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	} `json:"task"`
+}
+
+// getOccurrences возвращает повторения задач за период в виде списка "дата название".
+func getOccurrences(t *testing.T, from, to string) []string {
+	body, err := requestJSON(fmt.Sprintf("api/occurrences?from=%s&to=%s", from, to), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m struct {
+		Occurrences []occurrence `json:"occurrences"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+
+	var ret []string
+	for _, v := range m.Occurrences {
+		ret = append(ret, v.Date+" "+v.Task.Title)
+	}
+	return ret
+}
+
+func TestOccurrences(t *testing.T) {
+	login := fmt.Sprintf("planner%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		tasks := []map[string]any{
+			{"date": "20300107", "title": "A", "repeat": "d 3"},
+			{"date": "20300108", "title": "B", "repeat": "w 2,4"},
+			{"date": "20300109", "title": "C"},
+			{"date": "20300107", "title": "D", "repeat": "d 1", "repeat_count": 3},
+			{"date": "20300107", "title": "E", "repeat": "d 7", "repeat_until": "20300115"},
+			{"date": "20300201", "title": "F", "repeat": "d 1"},
+		}
+		for _, v := range tasks {
+			ret, err := postJSON("api/task", v, http.MethodPost)
+			assert.NoError(t, err)
+			assert.NotNil(t, ret["id"])
+		}
+
+		assert.Equal(t, []string{
+			"20300107 A", "20300107 D", "20300107 E",
+			"20300108 B", "20300108 D",
+			"20300109 C", "20300109 D",
+			"20300110 A", "20300110 B",
+			"20300113 A",
+			"20300114 E",
+			"20300115 B",
+		}, getOccurrences(t, "20300107", "20300115"))
+
+		assert.Equal(t, []string{
+			"20300110 A", "20300110 B",
+			"20300113 A",
+			"20300114 E",
+			"20300115 B",
+			"20300116 A",
+			"20300117 B",
+			"20300119 A",
+		}, getOccurrences(t, "20300110", "20300120"))
+
+		assert.Empty(t, getOccurrences(t, "20290101", "20290131"))
+
+		tbl := []struct {
+			path  string
+			field string
+		}{
+			{"api/occurrences?from=2030-01-07", "from"},
+			{"api/occurrences?from=20300107&to=20300101", "to"},
+			{"api/occurrences?from=20300107&to=20310108", "to"},
+		}
+		for _, v := range tbl {
+			status, m := requestStatus(t, v.path, "", http.MethodGet)
+			assert.Equal(t, http.StatusUnprocessableEntity, status, v.path)
+			assert.Equal(t, v.field, m["field"], v.path)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tasksPage struct {
+	Tasks []map[string]any `json:"tasks"`
+	Next  string           `json:"next"`
+	Error string           `json:"error"`
+}
+
+func getPage(t *testing.T, params url.Values) tasksPage {
+	body, err := requestJSON("api/tasks?"+params.Encode(), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var page tasksPage
+	assert.NoError(t, json.Unmarshal(body, &page))
+	return page
+}
+
+// walkPages обходит все страницы списка задач и возвращает значения поля field.
+func walkPages(t *testing.T, params url.Values, field string) []string {
+	var values []string
+	for i := 0; i < 10; i++ {
+		page := getPage(t, params)
+		assert.Empty(t, page.Error)
+		for _, task := range page.Tasks {
+			values = append(values, fmt.Sprint(task[field]))
+		}
+		if len(page.Next) == 0 {
+			break
+		}
+		params.Set("cursor", page.Next)
+	}
+	return values
+}
+
+func TestPages(t *testing.T) {
+	login := fmt.Sprintf("pages%d", time.Now().UnixNano())
+	password := "password123"
+	signUp(t, login, password)
+	token, err := signIn(login, password)
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		now := time.Now()
+		titles := []string{"Ж", "Б", "Д", "А", "Е", "В", "Г"}
+		for i, title := range titles {
+			addTask(t, task{
+				date:  now.AddDate(0, 0, len(titles)-i).Format(`20060102`),
+				title: title,
+			})
+		}
+
+		got := walkPages(t, url.Values{"limit": {"3"}, "sort": {"title"}}, "title")
+		want := append([]string{}, titles...)
+		sort.Strings(want)
+		assert.Equal(t, want, got)
+
+		got = walkPages(t, url.Values{"limit": {"2"}, "sort": {"date"}, "order": {"desc"}}, "title")
+		assert.Equal(t, titles, got)
+
+		got = walkPages(t, url.Values{"limit": {"4"}, "sort": {"id"}}, "title")
+		assert.Equal(t, titles, got)
+
+		page := getPage(t, url.Values{"limit": {"7"}})
+		assert.Len(t, page.Tasks, 7)
+		assert.Empty(t, page.Next)
+
+		for _, params := range []url.Values{
+			{"limit": {"0"}},
+			{"limit": {"1000"}},
+			{"sort": {"color"}},
+			{"order": {"up"}},
+			{"cursor": {"!!!"}},
+		} {
+			page := getPage(t, params)
+			assert.NotEmpty(t, page.Error, "Ожидается ошибка для %v", params)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryLanguage(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	login := fmt.Sprintf("query%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		now := time.Now()
+		day := func(n int) time.Time {
+			return time.Date(now.Year(), now.Month(), now.Day()+n, 0, 0, 0, 0, time.UTC)
+		}
+
+		ret, err := postJSON("api/project", map[string]any{"name": "Мой дом"}, http.MethodPost)
+		assert.NoError(t, err)
+		project := ret["id"]
+
+		add := func(values map[string]any) string {
+			ret, err := postJSON("api/task", values, http.MethodPost)
+			assert.NoError(t, err)
+			return fmt.Sprint(ret["id"])
+		}
+		annual := add(map[string]any{"title": "Годовой отчёт", "date": day(10).Format(`20060102`), "tags": []string{"work"}, "priority": 2})
+		bank := add(map[string]any{"title": "Отчёт для банка", "date": day(1).Format(`20060102`), "repeat": "d 7", "tags": []string{"home"}})
+		bread := add(map[string]any{"title": "Купить хлеб", "date": day(0).Format(`20060102`), "project_id": project})
+		old := add(map[string]any{"title": "Старый отчёт", "date": day(0).Format(`20060102`)})
+
+		//Через API просроченную задачу не создать, поэтому переносим её в прошлое напрямую.
+		_, err = db.Exec("UPDATE scheduler SET date = ? WHERE id = ?", day(-3).Format(`20060102`), old)
+		assert.NoError(t, err)
+
+		search := func(query string) []string {
+			return taskIDs(t, "sort=id&search="+url.QueryEscape(query))
+		}
+
+		assert.Equal(t, []string{bread}, search("today"))
+		assert.Equal(t, []string{old}, search("overdue"))
+		assert.Equal(t, []string{annual, bank, bread}, search("-overdue"))
+		assert.Equal(t, []string{annual, bank}, search("due:>today"))
+		assert.Equal(t, []string{annual, bank}, search(fmt.Sprintf("due:%s..%s", day(1).Format("2006-01-02"), day(10).Format("02.01.2006"))))
+		assert.Equal(t, []string{bank, bread, old}, search("due:..tomorrow"))
+		assert.Equal(t, []string{annual}, search("tag:work"))
+		assert.Equal(t, []string{bank}, search("-repeat:none"))
+		assert.Equal(t, []string{annual}, search("priority:>=2"))
+		assert.Equal(t, []string{bread}, search(`project:"Мой дом"`))
+		assert.Equal(t, []string{annual, old}, search("repeat:none отчет"))
+		assert.Equal(t, []string{annual}, search(fmt.Sprintf(`due:<%s tag:work repeat:none "отчёт"`, day(20).Format("2006-01-02"))))
+
+		var week []string
+		start := day(-(int(now.Weekday()) + 6) % 7)
+		for _, v := range []struct {
+			id   string
+			date time.Time
+		}{{annual, day(10)}, {bank, day(1)}, {bread, day(0)}, {old, day(-3)}} {
+			if !v.date.Before(start) && v.date.Before(start.AddDate(0, 0, 7)) {
+				week = append(week, v.id)
+			}
+		}
+		assert.Equal(t, week, search("this-week"))
+
+		//Относительные даты определяются в часовом поясе задачи: в этих часовых поясах текущие даты всегда различаются.
+		var zoned []string
+		for _, zone := range []string{"Pacific/Kiritimati", "Pacific/Pago_Pago"} {
+			loc, err := time.LoadLocation(zone)
+			if !assert.NoError(t, err) {
+				return
+			}
+			zoned = append(zoned, add(map[string]any{"title": "Созвон", "date": time.Now().In(loc).Format(`20060102`),
+				"time": "12:00", "timezone": zone}))
+		}
+		assert.Equal(t, append([]string{bread}, zoned...), search("today"))
+		assert.Equal(t, []string{old}, search("overdue"))
+		assert.Equal(t, zoned, search("due:today Созвон"))
+		assert.Empty(t, search("due:<today Созвон"))
+		assert.Empty(t, search("due:>today Созвон"))
+
+		//Слова с двоеточием после неизвестного названия поля ищутся как текст.
+		link := add(map[string]any{"title": "Прочитать http://example.com/report", "date": day(2).Format(`20060102`)})
+		assert.Equal(t, []string{link}, search("http://example.com/report"))
+		assert.Empty(t, search("note:позвонить"))
+
+		for _, v := range [][2]string{
+			{"due:завтра", "завтра"},
+			{"tag:<work", "<"},
+			{"tag:a..b", ".."},
+			{`"отчёт`, "кавыч"},
+		} {
+			status, m := requestStatus(t, "api/tasks?search="+url.QueryEscape(v[0]), "", http.MethodGet)
+			assert.Equal(t, http.StatusUnprocessableEntity, status, v[0])
+			assert.Equal(t, "search", m["field"], v[0])
+			assert.True(t, strings.Contains(fmt.Sprint(m["error"]), v[1]), m["error"])
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// completeTask отмечает задачу выполненной.
+func completeTask(t *testing.T, id string) {
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
+
+func TestRepeatCount(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":         today,
+		"title":        "Принять таблетку",
+		"repeat":       "d 1",
+		"repeat_count": 2,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	taskID := fmt.Sprint(ret["id"])
+
+	m, err := postJSON("api/task?id="+taskID, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, m["repeat_count"])
+
+	completeTask(t, taskID)
+	var tsk Task
+	assert.NoError(t, db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, taskID))
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), tsk.Date)
+	assert.Equal(t, 1, tsk.RepeatCount)
+
+	//Последнее повторение выполнено - задача удаляется.
+	completeTask(t, taskID)
+	notFoundTask(t, taskID)
+
+	history := getHistory(t, taskID)
+	if assert.Len(t, history, 2) {
+		assert.Empty(t, history[0].NextDate)
+	}
+
+	//Отмена выполнения восстанавливает задачу вместе с оставшимся количеством повторений.
+	ret, err = postJSON("api/task/undo?id="+taskID, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, taskID))
+	assert.Equal(t, 1, tsk.RepeatCount)
+
+	ret, err = postJSON("api/task/undo?id="+taskID, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, taskID))
+	assert.Equal(t, 2, tsk.RepeatCount)
+	assert.Equal(t, today, tsk.Date)
+
+	//Количество повторений из RRULE сохраняется в задаче.
+	ret, err = postJSON("api/task", map[string]any{
+		"date":   today,
+		"title":  "Курс массажа",
+		"repeat": "FREQ=DAILY;INTERVAL=2;COUNT=5",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, fmt.Sprint(ret["id"])))
+	assert.Equal(t, 5, tsk.RepeatCount)
+}
+
+func TestRepeatUntil(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":         today,
+		"title":        "Проветрить дачу",
+		"repeat":       "d 7",
+		"repeat_until": now.AddDate(0, 0, 10).Format(`20060102`),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	completeTask(t, id)
+	var tsk Task
+	assert.NoError(t, db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, now.AddDate(0, 0, 7).Format(`20060102`), tsk.Date)
+
+	//Следующее повторение позже даты окончания - задача удаляется.
+	completeTask(t, id)
+	notFoundTask(t, id)
+
+	tbl := []struct {
+		values map[string]any
+		field  string
+	}{
+		{map[string]any{"title": "Задача", "repeat": "d 1", "repeat_until": "2027-06-30"}, "repeat_until"},
+		{map[string]any{"title": "Задача", "repeat": "d 1", "repeat_count": -1}, "repeat_count"},
+	}
+	for _, v := range tbl {
+		m, err := postJSON("api/task", v.values, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Equal(t, "validation_failed", m["code"])
+		assert.Equal(t, v.field, m["field"])
+	}
+}
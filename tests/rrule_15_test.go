@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateRRule(t *testing.T) {
+	tbl := []nextDate{
+		{"20240101", "FREQ=DAILY;INTERVAL=7", "20240129"},
+		{"20240116", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", "20240130"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=1MO", "20240205"},
+		{"20240101", "FREQ=MONTHLY;BYDAY=-1FR", "20240223"},
+		{"20240101", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "20240131"},
+		{"20240101", "FREQ=MONTHLY;INTERVAL=3", "20240401"},
+		{"20240115", "freq=monthly;bymonthday=-1", "20240131"},
+		{"20230311", "FREQ=YEARLY;BYMONTH=5,6;BYMONTHDAY=7,19", "20240507"},
+		{"20200101", "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "20241128"},
+		{"20240229", "FREQ=YEARLY", "20280229"},
+		{"20240101", "FREQ=DAILY;INTERVAL=10;COUNT=4", "20240131"},
+		{"20240101", "FREQ=WEEKLY;BYDAY=MO;UNTIL=20240205T000000Z", "20240129"},
+		{"20240101", "FREQ=HOURLY", ""},
+		{"20240101", "FREQ=DAILY;BYWEEKNO=1", ""},
+		{"20240101", "FREQ=DAILY;COUNT=2;UNTIL=20240301", ""},
+		{"20240101", "FREQ=WEEKLY;BYDAY=1MO", ""},
+		{"20240101", "FREQ=MONTHLY;BYMONTHDAY=32", ""},
+		{"20240101", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", ""},
+	}
+	checkNextDates(t, tbl)
+
+	//Повторения по правилу закончились: следующей даты нет, но правило корректно.
+	for _, repeat := range []string{"FREQ=DAILY;INTERVAL=10;COUNT=3", "FREQ=WEEKLY;BYDAY=MO;UNTIL=20240128"} {
+		body, err := getBody(fmt.Sprintf("api/nextdate?now=20240126&date=20240101&repeat=%s", url.QueryEscape(repeat)))
+		assert.NoError(t, err)
+		assert.Empty(t, string(body), repeat)
+	}
+}
+
+func TestToRRule(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		want   string
+	}{
+		{"d 7", "FREQ=DAILY;INTERVAL=7"},
+		{"w 7", "FREQ=WEEKLY;BYDAY=SU"},
+		{"w 1,3 /2", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"},
+		{"m 07,19 05,6", "FREQ=MONTHLY;BYMONTHDAY=7,19;BYMONTH=5,6"},
+		{"m -1,-2", "FREQ=MONTHLY;BYMONTHDAY=-1,-2"},
+		{"m -1w5", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"m /3", "FREQ=MONTHLY;INTERVAL=3"},
+		{"m 1 /3", "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1"},
+		{"y", "FREQ=YEARLY"},
+		{"y 2", "FREQ=YEARLY;INTERVAL=2"},
+	}
+
+	for _, v := range tbl {
+		status, m := requestStatus(t, "api/rrule?repeat="+url.QueryEscape(v.repeat), "", http.MethodGet)
+		assert.Equal(t, http.StatusOK, status, v.repeat)
+		assert.Equal(t, v.want, m["rrule"], v.repeat)
+
+		//Правило в формате RRULE должно давать ту же следующую дату, что и исходное.
+		for _, date := range []string{"20240101", "20231115", "20240131"} {
+			legacy, err := getBody(fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s", date, url.QueryEscape(v.repeat)))
+			assert.NoError(t, err)
+			rrule, err := getBody(fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s", date, url.QueryEscape(v.want)))
+			assert.NoError(t, err)
+			assert.Equal(t, string(legacy), string(rrule), "%s %s", date, v.repeat)
+		}
+	}
+
+	for _, repeat := range []string{"m 1,1w1", "d 401", "k 1"} {
+		status, m := requestStatus(t, "api/rrule?repeat="+url.QueryEscape(repeat), "", http.MethodGet)
+		assert.Equal(t, http.StatusUnprocessableEntity, status, repeat)
+		assert.Equal(t, "repeat", m["field"], repeat)
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFullTextSearch(t *testing.T) {
+	login := fmt.Sprintf("search%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		var ids []string
+		for _, v := range [][2]string{
+			{"Позвонить в банк", "Спросить про отчёт"},
+			{"Годовой отчёт", "Собрать цифры за год"},
+			{"Отчёт по проекту", "Черновик"},
+			{"Оплатить <b>налоги</b>", ""},
+		} {
+			ret, err := postJSON("api/task", map[string]any{"title": v[0], "comment": v[1]}, http.MethodPost)
+			assert.NoError(t, err)
+			ids = append(ids, fmt.Sprint(ret["id"]))
+		}
+		bank, annual, project, taxes := ids[0], ids[1], ids[2], ids[3]
+
+		search := func(query string) []string {
+			return taskIDs(t, "search="+url.QueryEscape(query))
+		}
+
+		//Совпадение в названии важнее совпадения в комментарии.
+		found := search("отчет")
+		if assert.Len(t, found, 3) {
+			assert.ElementsMatch(t, []string{annual, project}, found[:2])
+			assert.Equal(t, bank, found[2])
+		}
+		assert.Equal(t, found, search("ОТЧЁТ"))
+		assert.Equal(t, []string{annual}, search(`"годовой отчет"`))
+		assert.Equal(t, []string{annual}, search("год*"))
+		assert.Equal(t, []string{annual}, search("годов"))
+		assert.Empty(t, search("одовой"))
+		assert.Empty(t, search(`"годов отчет"`))
+		assert.ElementsMatch(t, []string{bank, annual}, search("отчет -черновик"))
+		assert.ElementsMatch(t, []string{bank, annual}, search("отчет NOT черновик"))
+		assert.ElementsMatch(t, []string{bank, project}, search("банк OR проекту"))
+
+		m, err := postJSON("api/tasks?search="+url.QueryEscape("банк"), nil, http.MethodGet)
+		assert.NoError(t, err)
+		tasks, _ := m["tasks"].([]any)
+		if assert.Len(t, tasks, 1) {
+			assert.Equal(t, "Позвонить в <mark>банк</mark>", tasks[0].(map[string]any)["snippet"])
+		}
+
+		//Текст задачи во фрагменте экранируется, тегами выделяются только совпадения.
+		m, err = postJSON("api/tasks?search="+url.QueryEscape("налог"), nil, http.MethodGet)
+		assert.NoError(t, err)
+		tasks, _ = m["tasks"].([]any)
+		if assert.Len(t, tasks, 1) {
+			assert.Equal(t, taxes, tasks[0].(map[string]any)["id"])
+			assert.Equal(t, "Оплатить &lt;b&gt;<mark>налоги</mark>&lt;/b&gt;", tasks[0].(map[string]any)["snippet"])
+		}
+
+		params := url.Values{"search": {"отчет"}, "limit": {"1"}}
+		assert.Equal(t, found, walkPages(t, params, "id"))
+
+		for _, query := range []string{`"отчет`, "OR отчет", "-отчет", "отчет OR"} {
+			status, m := requestStatus(t, "api/tasks?search="+url.QueryEscape(query), "", http.MethodGet)
+			assert.Equal(t, http.StatusUnprocessableEntity, status, query)
+			assert.Equal(t, "search", m["field"], query)
+		}
+
+		status, m := requestStatus(t, "api/tasks?sort=rank", "", http.MethodGet)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "sort", m["field"])
+	})
+}
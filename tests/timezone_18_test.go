@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskTime(t *testing.T) {
+	ret, err := postJSON("api/task", map[string]any{
+		"title":    "Планёрка",
+		"repeat":   "w 1,2,3,4,5",
+		"time":     "09:00",
+		"duration": 15,
+		"timezone": "Europe/Moscow",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+
+	m, err := postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, "09:00", m["time"])
+	assert.EqualValues(t, 15, m["duration"])
+	assert.Equal(t, "Europe/Moscow", m["timezone"])
+
+	tbl := []struct {
+		values map[string]any
+		field  string
+	}{
+		{map[string]any{"title": "Задача", "time": "25:00"}, "time"},
+		{map[string]any{"title": "Задача", "time": "9 утра"}, "time"},
+		{map[string]any{"title": "Задача", "duration": -5}, "duration"},
+		{map[string]any{"title": "Задача", "timezone": "Mars/Olympus"}, "timezone"},
+	}
+	for _, v := range tbl {
+		m, err := postJSON("api/task", v.values, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Equal(t, "validation_failed", m["code"], v.values)
+		assert.Equal(t, v.field, m["field"], v.values)
+	}
+}
+
+func TestNextDateTimezone(t *testing.T) {
+	//Между этими часовыми поясами разница 25 часов, поэтому текущие даты в них всегда разные.
+	west, err := time.LoadLocation("Pacific/Pago_Pago")
+	assert.NoError(t, err)
+	east, err := time.LoadLocation("Pacific/Kiritimati")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	date := now.In(west).Format(`20060102`)
+
+	for _, loc := range []*time.Location{west, east} {
+		y, m, d := now.In(loc).Date()
+		want := time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC).Format(`20060102`)
+
+		body, err := getBody(fmt.Sprintf("api/nextdate?date=%s&repeat=%s&tz=%s",
+			date, url.QueryEscape("d 1"), url.QueryEscape(loc.String())))
+		assert.NoError(t, err)
+		assert.Equal(t, want, strings.TrimSpace(string(body)), loc.String())
+	}
+
+	status, m := requestStatus(t, "api/nextdate?date=20240101&repeat=d%201&tz=Nowhere", "", http.MethodGet)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, "timezone", m["field"])
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signInTokens(t *testing.T, login, password string) map[string]any {
+	m, err := postJSON("api/signin", map[string]any{
+		"login":    login,
+		"password": password,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["token"])
+	assert.NotEmpty(t, m["refresh_token"])
+	assert.NotEmpty(t, m["expires_in"])
+	return m
+}
+
+func refresh(t *testing.T, refreshToken string) map[string]any {
+	m, err := postJSON("api/refresh", map[string]any{
+		"refresh_token": refreshToken,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	return m
+}
+
+func authorized(t *testing.T, token string) bool {
+	var ok bool
+	asUser(token, func() {
+		m, err := postJSON("api/tasks", nil, http.MethodGet)
+		assert.NoError(t, err)
+		_, ok = m["tasks"]
+	})
+	return ok
+}
+
+func TestTokens(t *testing.T) {
+	login := fmt.Sprintf("tokens%d", time.Now().UnixNano())
+	password := "password123"
+	signUp(t, login, password)
+
+	assert.False(t, authorized(t, "garbage"))
+
+	tokens := signInTokens(t, login, password)
+	access := fmt.Sprint(tokens["token"])
+	assert.True(t, authorized(t, access))
+
+	// Refresh-токен заменяется при каждом обновлении.
+	refreshed := refresh(t, fmt.Sprint(tokens["refresh_token"]))
+	assert.NotEmpty(t, refreshed["token"])
+	assert.NotEqual(t, tokens["refresh_token"], refreshed["refresh_token"])
+	assert.True(t, authorized(t, fmt.Sprint(refreshed["token"])))
+
+	// Повторное использование старого refresh-токена отзывает сессию.
+	reused := refresh(t, fmt.Sprint(tokens["refresh_token"]))
+	assert.NotEmpty(t, reused["error"])
+	assert.False(t, authorized(t, access))
+	reused = refresh(t, fmt.Sprint(refreshed["refresh_token"]))
+	assert.NotEmpty(t, reused["error"])
+
+	// После выхода токены сессии не действуют.
+	tokens = signInTokens(t, login, password)
+	access = fmt.Sprint(tokens["token"])
+	asUser(access, func() {
+		m, err := postJSON("api/signout", nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, m)
+	})
+	assert.False(t, authorized(t, access))
+	m := refresh(t, fmt.Sprint(tokens["refresh_token"]))
+	assert.NotEmpty(t, m["error"])
+}
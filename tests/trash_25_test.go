@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// trashIDs возвращает идентификаторы задач в корзине.
+func trashIDs(t *testing.T) []string {
+	m, err := postJSON("api/trash", nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var ids []string
+	tasks, _ := m["tasks"].([]any)
+	for _, v := range tasks {
+		task := v.(map[string]any)
+		assert.NotEmpty(t, task["deleted_at"])
+		ids = append(ids, fmt.Sprint(task["id"]))
+	}
+	return ids
+}
+
+func TestTrash(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	login := fmt.Sprintf("trash%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		today := time.Now().Format(`20060102`)
+		var ids []string
+		for _, title := range []string{"Купить краску", "Покрасить забор", "Вымыть кисти"} {
+			ret, err := postJSON("api/task", map[string]any{"title": title, "date": today, "tags": []string{"дача"}},
+				http.MethodPost)
+			assert.NoError(t, err)
+			ids = append(ids, fmt.Sprint(ret["id"]))
+		}
+		buy, paint, wash := ids[0], ids[1], ids[2]
+
+		status, _ := addDependency(t, paint, buy)
+		assert.Equal(t, http.StatusOK, status)
+		_, err = postJSON("api/task/item", map[string]any{"task_id": paint, "title": "Первый слой"}, http.MethodPost)
+		assert.NoError(t, err)
+
+		for _, id := range []string{buy, paint} {
+			_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+			assert.NoError(t, err)
+			notFoundTask(t, id)
+		}
+
+		assert.Equal(t, []string{wash}, taskIDs(t, "tag=дача"))
+		assert.ElementsMatch(t, []string{buy, paint}, trashIDs(t))
+
+		//Задача в корзине недоступна для изменения и повторного удаления.
+		status, _ = requestStatus(t, "api/task/items?id="+paint, "", http.MethodGet)
+		assert.Equal(t, http.StatusNotFound, status)
+		status, _ = requestStatus(t, "api/task?id="+paint, "", http.MethodDelete)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		//Восстановленная задача сохраняет теги, пункты и зависимости, удалённая зависимость её не блокирует.
+		_, err = postJSON("api/trash/restore?id="+paint, nil, http.MethodPost)
+		assert.NoError(t, err)
+		m, err := postJSON("api/task?id="+paint, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"дача"}, m["tags"])
+		assert.Equal(t, float64(1), m["progress"].(map[string]any)["total"])
+		assert.Nil(t, m["blocked"])
+		assert.Nil(t, m["deleted_at"])
+
+		_, err = postJSON("api/trash/restore?id="+buy, nil, http.MethodPost)
+		assert.NoError(t, err)
+		m, err = postJSON("api/task?id="+paint, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, true, m["blocked"])
+		assert.Empty(t, trashIDs(t))
+
+		status, _ = requestStatus(t, "api/trash/restore?id="+buy, "", http.MethodPost)
+		assert.Equal(t, http.StatusNotFound, status)
+		status, _ = requestStatus(t, "api/trash/restore", "", http.MethodPost)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+		//Окончательное удаление задачи из корзины.
+		_, err = postJSON("api/task?id="+buy, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		status, _ = requestStatus(t, "api/trash/task?id="+paint, "", http.MethodDelete)
+		assert.Equal(t, http.StatusNotFound, status)
+		_, err = postJSON("api/trash/task?id="+buy, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, trashIDs(t))
+
+		var deps int
+		err = db.Get(&deps, `SELECT count(*) FROM task_dependencies WHERE depends_on = ?`, buy)
+		assert.NoError(t, err)
+		assert.Zero(t, deps)
+		m, err = postJSON("api/task?id="+paint, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Nil(t, m["depends_on"])
+
+		for _, id := range []string{paint, wash} {
+			_, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+			assert.NoError(t, err)
+		}
+
+		//Задачи старше срока хранения не выдаются из корзины до их удаления фоновой очисткой.
+		_, err = db.Exec(`UPDATE scheduler SET deleted_at = datetime('now', '-31 days') WHERE id = ?`, wash)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{paint}, trashIDs(t))
+		var expired int
+		err = db.Get(&expired, `SELECT count(*) FROM scheduler WHERE id = ?`, wash)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, expired, "Просмотр корзины не удаляет задачи")
+
+		m, err = postJSON("api/trash", nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(2), m["purged"])
+		assert.Empty(t, trashIDs(t))
+
+		var items int
+		err = db.Get(&items, `SELECT count(*) FROM task_items WHERE task_id = ?`, paint)
+		assert.NoError(t, err)
+		assert.Zero(t, items)
+	})
+}
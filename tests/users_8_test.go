@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signIn(login, password string) (string, error) {
+	m, err := postJSON("api/signin", map[string]any{
+		"login":    login,
+		"password": password,
+	}, http.MethodPost)
+	if err != nil {
+		return "", err
+	}
+	if e, ok := m["error"]; ok {
+		return "", fmt.Errorf("%v", e)
+	}
+	return fmt.Sprint(m["token"]), nil
+}
+
+func signUp(t *testing.T, login, password string) {
+	m, err := postJSON("api/signup", map[string]any{
+		"login":    login,
+		"password": password,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, m["id"], "Не возвращён id пользователя %s", login)
+}
+
+// asUser выполняет f с токеном другого пользователя.
+func asUser(token string, f func()) {
+	saved := Token
+	Token = token
+	defer func() { Token = saved }()
+	f()
+}
+
+func TestUsers(t *testing.T) {
+	suffix := time.Now().UnixNano()
+	alice := fmt.Sprintf("alice%d", suffix)
+	bob := fmt.Sprintf("bob%d", suffix)
+	password := "password123"
+
+	signUp(t, alice, password)
+	signUp(t, bob, password)
+
+	m, err := postJSON("api/signup", map[string]any{
+		"login":    alice,
+		"password": password,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"], "Ожидается ошибка при повторной регистрации")
+
+	m, err = postJSON("api/signup", map[string]any{
+		"login":    "short" + alice,
+		"password": "123",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"], "Ожидается ошибка для короткого пароля")
+
+	status, m := requestStatus(t, "api/signup", `{"login": "admin", "password": "password123"}`, http.MethodPost)
+	assert.Equal(t, http.StatusUnprocessableEntity, status, "Логин admin зарезервирован")
+	assert.Equal(t, "login", m["field"])
+
+	_, err = signIn(alice, "wrong"+password)
+	assert.Error(t, err)
+
+	aliceToken, err := signIn(alice, password)
+	assert.NoError(t, err)
+	bobToken, err := signIn(bob, password)
+	assert.NoError(t, err)
+
+	var id string
+	asUser(aliceToken, func() {
+		id = addTask(t, task{title: "Задача Алисы"})
+		assert.Len(t, getTasks(t, ""), 1)
+	})
+
+	asUser(bobToken, func() {
+		assert.Empty(t, getTasks(t, ""))
+		notFoundTask(t, id)
+
+		ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"])
+
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"])
+	})
+
+	asUser(aliceToken, func() {
+		body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.Equal(t, id, m["id"])
+	})
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// versionRequest выполняет запрос с телом body и заголовками headers (имя, значение, ...)
+// и возвращает код ответа, заголовок ETag ответа и разобранное тело.
+func versionRequest(t *testing.T, method, apipath string, body map[string]any, headers ...string) (int, string, map[string]any) {
+	data, err := json.Marshal(body)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewReader(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0, "", nil
+	}
+	defer resp.Body.Close()
+
+	var m map[string]any
+	if resp.StatusCode != http.StatusNotModified {
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	}
+	return resp.StatusCode, resp.Header.Get("ETag"), m
+}
+
+func TestTaskVersion(t *testing.T) {
+	today := time.Now().Format(`20060102`)
+
+	login := fmt.Sprintf("version%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	asUser(token, func() {
+		ret, err := postJSON("api/task", map[string]any{"title": "Написать отчёт", "date": today}, http.MethodPost)
+		assert.NoError(t, err)
+		id := fmt.Sprint(ret["id"])
+
+		//Задача возвращается с версией в поле version и в заголовке ETag.
+		status, etag, first := versionRequest(t, http.MethodGet, "api/task?id="+id, nil)
+		assert.Equal(t, http.StatusOK, status)
+		assert.NotEmpty(t, first["version"])
+		assert.Equal(t, fmt.Sprintf(`"%s-%s"`, id, first["version"]), etag)
+		status, _, _ = versionRequest(t, http.MethodGet, "api/task?id="+id, nil, "If-None-Match", etag)
+		assert.Equal(t, http.StatusNotModified, status)
+
+		//Две вкладки получили одну версию задачи: изменение второй вкладки не затирает изменение первой.
+		second := map[string]any{}
+		for k, v := range first {
+			second[k] = v
+		}
+		first["title"] = "Написать годовой отчёт"
+		status, newETag, _ := versionRequest(t, http.MethodPut, "api/task", first)
+		assert.Equal(t, http.StatusOK, status)
+		assert.NotEqual(t, etag, newETag)
+
+		second["comment"] = "До пятницы"
+		status, _, m := versionRequest(t, http.MethodPut, "api/task", second)
+		assert.Equal(t, http.StatusPreconditionFailed, status)
+		assert.Equal(t, "precondition_failed", m["code"])
+		ret, err = postJSON("api/task?id="+id, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, "Написать годовой отчёт", ret["title"])
+		assert.Empty(t, ret["comment"])
+
+		//Версию можно передать заголовком If-Match, ETag другой задачи не подходит.
+		delete(second, "version")
+		status, _, _ = versionRequest(t, http.MethodPut, "api/task", second, "If-Match", etag)
+		assert.Equal(t, http.StatusPreconditionFailed, status)
+		status, _, _ = versionRequest(t, http.MethodPut, "api/task", second, "If-Match", `"0-1"`)
+		assert.Equal(t, http.StatusPreconditionFailed, status)
+		second["title"] = ret["title"]
+		status, etag, _ = versionRequest(t, http.MethodPut, "api/task", second, "If-Match", newETag)
+		assert.Equal(t, http.StatusOK, status)
+
+		//Версию в теле запроса можно передать и числом.
+		_, _, current := versionRequest(t, http.MethodGet, "api/task?id="+id, nil)
+		version, err := strconv.ParseInt(fmt.Sprint(current["version"]), 10, 64)
+		assert.NoError(t, err)
+		current["version"] = version
+		current["comment"] = "До пятницы"
+		status, etag, _ = versionRequest(t, http.MethodPut, "api/task", current)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, fmt.Sprintf(`"%s-%d"`, id, version+1), etag)
+		status, _, _ = versionRequest(t, http.MethodPut, "api/task", current)
+		assert.Equal(t, http.StatusPreconditionFailed, status)
+		current["version"] = 1.5
+		status, _, m = versionRequest(t, http.MethodPut, "api/task", current)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "version", m["field"])
+		status, _, m = versionRequest(t, http.MethodPut, "api/task", current, "Accept-Language", "en")
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, "Task version must be an integer, got 1.5", m["error"])
+
+		//Выполнение и удаление с устаревшей версией не выполняются.
+		status, _, _ = versionRequest(t, http.MethodPost, "api/task/done?id="+id, nil, "If-Match", newETag)
+		assert.Equal(t, http.StatusPreconditionFailed, status)
+		status, _, _ = versionRequest(t, http.MethodDelete, "api/task?id="+id+"&version=1", nil)
+		assert.Equal(t, http.StatusPreconditionFailed, status)
+		status, _, _ = versionRequest(t, http.MethodDelete, "api/task?id="+id+"&version=last", nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, []string{id}, taskIDs(t, ""))
+
+		status, _, _ = versionRequest(t, http.MethodPost, "api/task/done?id="+id, nil, "If-Match", etag)
+		assert.Equal(t, http.StatusOK, status)
+		got, _ := archiveIDs(t, "")
+		assert.Equal(t, []string{id}, got)
+
+		//Без версии задача не изменяется, не выполняется и не удаляется.
+		ret, err = postJSON("api/task", map[string]any{"title": "Позвонить", "date": today}, http.MethodPost)
+		assert.NoError(t, err)
+		id = fmt.Sprint(ret["id"])
+		status, _, m = versionRequest(t, http.MethodPut, "api/task", map[string]any{"id": id, "title": "Перезвонить",
+			"date": today})
+		assert.Equal(t, http.StatusPreconditionRequired, status)
+		assert.Equal(t, "precondition_required", m["code"])
+		status, _, _ = versionRequest(t, http.MethodPost, "api/task/done?id="+id, nil)
+		assert.Equal(t, http.StatusPreconditionRequired, status)
+		status, _, _ = versionRequest(t, http.MethodDelete, "api/task?id="+id, nil)
+		assert.Equal(t, http.StatusPreconditionRequired, status)
+		assert.Empty(t, trashIDs(t))
+
+		//С заголовком "If-Match: *" задача изменяется и удаляется без проверки версии.
+		status, _, _ = versionRequest(t, http.MethodPut, "api/task", map[string]any{"id": id, "title": "Перезвонить",
+			"date": today}, "If-Match", "*")
+		assert.Equal(t, http.StatusOK, status)
+		status, _, _ = versionRequest(t, http.MethodDelete, "api/task?id="+id, nil, "If-Match", "*")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []string{id}, trashIDs(t))
+	})
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// viewTitles возвращает названия задач страницы списка задач представления и курсор следующей страницы.
+func viewTitles(t *testing.T, apipath string) ([]any, any) {
+	m, err := postJSON(apipath, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	titles := []any{}
+	tasks, _ := m["tasks"].([]any)
+	for _, task := range tasks {
+		titles = append(titles, task.(map[string]any)["title"])
+	}
+	return titles, m["next"]
+}
+
+func TestViews(t *testing.T) {
+	login := fmt.Sprintf("views%d", time.Now().UnixNano())
+	signUp(t, login, "password123")
+	token, err := signIn(login, "password123")
+	assert.NoError(t, err)
+
+	var view string
+	asUser(token, func() {
+		for _, v := range []map[string]any{
+			{"title": "Отчёт", "tags": []string{"work"}},
+			{"title": "Встреча", "tags": []string{"work"}, "priority": 3},
+			{"title": "Поход в кино"},
+		} {
+			_, err := postJSON("api/task", v, http.MethodPost)
+			assert.NoError(t, err)
+		}
+
+		ret, err := postJSON("api/view", map[string]any{
+			"name":   "Работа",
+			"search": "tag:work",
+			"sort":   "title",
+			"order":  "desc",
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		view = fmt.Sprint(ret["id"])
+
+		titles, next := viewTitles(t, "api/views/"+view+"/tasks")
+		assert.Equal(t, []any{"Отчёт", "Встреча"}, titles)
+		assert.Nil(t, next)
+
+		titles, next = viewTitles(t, "api/views/"+view+"/tasks?limit=1")
+		assert.Equal(t, []any{"Отчёт"}, titles)
+		if assert.NotNil(t, next) {
+			titles, next = viewTitles(t, fmt.Sprintf("api/views/%s/tasks?limit=1&cursor=%v", view, next))
+			assert.Equal(t, []any{"Встреча"}, titles)
+			assert.Nil(t, next)
+		}
+
+		_, err = postJSON("api/view", map[string]any{"id": ret["id"], "name": "Срочное", "priority": 3}, http.MethodPut)
+		assert.NoError(t, err)
+		titles, _ = viewTitles(t, "api/views/"+view+"/tasks")
+		assert.Equal(t, []any{"Встреча"}, titles)
+
+		m, err := postJSON("api/view?id="+view, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, "Срочное", m["name"])
+		assert.EqualValues(t, 3, m["priority"])
+
+		status, _ := requestStatus(t, "api/view", `{"name":"Срочное"}`, http.MethodPost)
+		assert.Equal(t, http.StatusConflict, status)
+
+		for _, v := range [][2]string{
+			{`{"name":" "}`, "name"},
+			{`{"name":"Ошибка","search":"due:завтра"}`, "search"},
+			{`{"name":"Ошибка","sort":"size"}`, "sort"},
+			{`{"name":"Ошибка","priority":7}`, "priority"},
+			{`{"name":"Ошибка","archived":"all"}`, "archived"},
+		} {
+			status, m := requestStatus(t, "api/view", v[0], http.MethodPost)
+			assert.Equal(t, http.StatusUnprocessableEntity, status, v[0])
+			assert.Equal(t, v[1], m["field"], v[0])
+		}
+
+		m, err = postJSON("api/views", nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Len(t, m["views"], 1)
+
+		//Представление может выбирать задачи в архиве.
+		ret, err = postJSON("api/task", map[string]any{"title": "Сдать отчёт", "tags": []string{"work"}}, http.MethodPost)
+		assert.NoError(t, err)
+		completeTask(t, fmt.Sprint(ret["id"]))
+		ret, err = postJSON("api/view", map[string]any{"name": "Архив", "search": "tag:work", "archived": "only"},
+			http.MethodPost)
+		assert.NoError(t, err)
+		m, err = postJSON(fmt.Sprintf("api/view?id=%v", ret["id"]), nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Equal(t, "only", m["archived"])
+		titles, _ = viewTitles(t, fmt.Sprintf("api/views/%v/tasks", ret["id"]))
+		assert.Equal(t, []any{"Сдать отчёт"}, titles)
+	})
+
+	status, _ := requestStatus(t, "api/views/"+view+"/tasks", "", http.MethodGet)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	asUser(token, func() {
+		_, err := postJSON("api/view?id="+view, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		status, _ := requestStatus(t, "api/views/"+view+"/tasks", "", http.MethodGet)
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
@@ -0,0 +1,64 @@
+// Продление сессии веб-интерфейса: refresh-токен, полученный при входе, сохраняется в localStorage,
+// а запрос, отклонённый с кодом 401 из-за истёкшего access-токена, повторяется один раз после обновления токенов.
+(function () {
+    var storageKey = "refresh_token";
+    var refreshing = null; //Выполняемое обновление токенов, общее для всех запросов, получивших 401.
+
+    //tokenURL проверяет, что запрос выдаёт токены: такие запросы не повторяются.
+    function tokenURL(url) {
+        return /(^|\/)api\/(signin|refresh)$/.test(url || "");
+    }
+
+    //saveTokens сохраняет access-токен в куке token и refresh-токен в localStorage.
+    function saveTokens(data) {
+        var expires = new Date();
+        expires.setTime(expires.getTime() + 288e5);
+        document.cookie = "token=" + data.token + ";expires=" + expires.toUTCString() + ";path=/";
+        localStorage.setItem(storageKey, data.refresh_token);
+    }
+
+    //refresh обновляет токены. Refresh-токен одноразовый, поэтому одновременные запросы ждут одного обновления.
+    function refresh() {
+        if (!refreshing) {
+            var token = localStorage.getItem(storageKey);
+            refreshing = (token ? axios.post("/api/refresh", { refresh_token: token }) : Promise.reject())
+                .then(function (resp) {
+                    saveTokens(resp.data);
+                })
+                .catch(function (err) {
+                    localStorage.removeItem(storageKey);
+                    throw err;
+                })
+                .finally(function () {
+                    refreshing = null;
+                });
+        }
+        return refreshing;
+    }
+
+    axios.interceptors.response.use(
+        function (resp) {
+            if (/(^|\/)api\/signin$/.test(resp.config.url) && resp.data && resp.data.refresh_token) {
+                localStorage.setItem(storageKey, resp.data.refresh_token);
+            }
+            return resp;
+        },
+        function (err) {
+            var config = err.config;
+            if (!err.response || err.response.status !== 401 || !config || config.retried || tokenURL(config.url)) {
+                return Promise.reject(err);
+            }
+
+            config.retried = true;
+            //Если токены обновить не удалось, возвращается исходная ошибка 401, и интерфейс открывает страницу входа.
+            return refresh().then(
+                function () {
+                    return axios(config);
+                },
+                function () {
+                    return Promise.reject(err);
+                }
+            );
+        }
+    );
+})();